@@ -7,7 +7,9 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/cyberspacesec/go-snir/pkg/api"
+	"github.com/cyberspacesec/go-snir/pkg/database"
 	"github.com/cyberspacesec/go-snir/pkg/log"
+	"github.com/cyberspacesec/go-snir/pkg/runner"
 )
 
 // 生成随机API密钥
@@ -31,11 +33,25 @@ var apiCmd = &cobra.Command{
 			log.Success("已生成随机API密钥", "api_key", log.Cyan(opts.API.APIKey))
 		}
 
+		// 启用数据库时记录扫描结果和会话
+		var dbOptions *database.Options
+		if opts.DB.Enable {
+			options := runner.DatabaseOptions(opts)
+			dbOptions = &options
+		}
+
+		// 保留策略
+		policy, err := prunePolicy()
+		if err != nil {
+			return err
+		}
+
 		// 创建API服务配置
 		apiOptions := api.Options{
 			Port:                  opts.API.Port,
 			Host:                  opts.API.Host,
 			ScreenshotPath:        opts.Scan.ScreenshotPath,
+			Storage:               opts.Scan.Storage,
 			APIKey:                opts.API.APIKey,
 			EnableBlacklist:       opts.Scan.EnableBlacklist,
 			DefaultBlacklist:      opts.Scan.DefaultBlacklist,
@@ -43,6 +59,9 @@ var apiCmd = &cobra.Command{
 			BlacklistFile:         opts.Scan.BlacklistFile,
 			MaxConcurrentRequests: opts.API.MaxConcurrent,
 			RequestQueueSize:      opts.API.QueueSize,
+			Database:              dbOptions,
+			Prune:                 policy,
+			PruneInterval:         pruneFlags.interval,
 		}
 
 		// 创建API服务
@@ -76,5 +95,13 @@ func init() {
 	apiCmd.Flags().IntVar(&opts.API.MaxConcurrent, "max-concurrent", 10, log.Cyan("最大并发请求数"))
 	apiCmd.Flags().IntVar(&opts.API.QueueSize, "queue-size", 100, log.Cyan("请求队列大小"))
 
+	// 添加数据库相关选项
+	apiCmd.Flags().BoolVar(&opts.DB.Enable, "db", false, log.Cyan("启用数据库存储，记录扫描结果和会话"))
+	addDatabaseFlags(apiCmd.Flags())
+
+	// 添加截图目录和保留策略相关选项
+	addStorageFlags(apiCmd.Flags())
+	addBackgroundPruneFlags(apiCmd.Flags())
+
 	log.Debug(log.Green("已注册api命令"))
 }
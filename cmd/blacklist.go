@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cyberspacesec/go-snir/pkg/log"
+	"github.com/cyberspacesec/go-snir/pkg/runner"
+)
+
+var blacklistCmdFlags = struct {
+	json bool
+}{}
+
+var blacklistCmd = &cobra.Command{
+	Use:   "blacklist",
+	Short: log.Yellow("URL黑名单相关命令"),
+	Long:  log.Yellow("查看和调试URL黑名单规则"),
+}
+
+var blacklistTestCmd = &cobra.Command{
+	Use:   "test [url...]",
+	Short: log.Yellow("检查URL命中了哪些黑名单规则"),
+	Long:  log.Yellow("检查URL是否在黑名单中，列出所有命中的规则、规则类型、规则来源以及解析到的IP地址。未提供参数时从标准输入读取URL"),
+	Example: `  # 检查单个URL
+  ./snir blacklist test http://10.0.0.1
+
+  # 检查多个URL并使用自定义规则文件
+  ./snir blacklist test example.com internal.corp --blacklist-file blacklist.txt
+
+  # 从标准输入读取URL并输出JSON
+  cat urls.txt | ./snir blacklist test --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targets := args
+		if len(targets) == 0 {
+			var err error
+			targets, err = readTargetLines(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("读取标准输入失败: %v", err)
+			}
+		}
+
+		if len(targets) == 0 {
+			return fmt.Errorf("请提供要检查的URL，或通过标准输入传入")
+		}
+
+		// 检查时总是启用黑名单
+		opts.Scan.EnableBlacklist = true
+		blacklist, err := runner.NewURLBlacklist(opts)
+		if err != nil {
+			return fmt.Errorf("创建URL黑名单失败: %v", err)
+		}
+
+		explanations := make([]*runner.BlacklistExplanation, 0, len(targets))
+		for _, target := range targets {
+			explanations = append(explanations, blacklist.Explain(normalizeBlacklistTarget(target)))
+		}
+
+		if blacklistCmdFlags.json {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(explanations)
+		}
+
+		for _, exp := range explanations {
+			printBlacklistExplanation(exp)
+		}
+
+		return nil
+	},
+}
+
+// readTargetLines 从输入中逐行读取目标，跳过空行和注释
+func readTargetLines(r io.Reader) ([]string, error) {
+	var targets []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			targets = append(targets, line)
+		}
+	}
+	return targets, scanner.Err()
+}
+
+// normalizeBlacklistTarget 为没有协议的目标补全协议，与扫描时的处理保持一致
+func normalizeBlacklistTarget(target string) string {
+	if strings.Contains(target, "://") {
+		return target
+	}
+	if !opts.Scan.HTTPS && opts.Scan.HTTP {
+		return "http://" + target
+	}
+	return "https://" + target
+}
+
+// printBlacklistExplanation 打印单个URL的黑名单检查结果
+func printBlacklistExplanation(exp *runner.BlacklistExplanation) {
+	status := log.Green("允许")
+	if exp.Blacklisted {
+		status = log.Red("已拦截")
+	}
+	fmt.Printf("%s %s\n", log.Bold(exp.URL), status)
+
+	if exp.Host != "" {
+		fmt.Printf("  %s %s\n", log.Cyan("主机:"), exp.Host)
+	}
+	if len(exp.ResolvedIPs) > 0 {
+		fmt.Printf("  %s %s\n", log.Cyan("解析IP:"), strings.Join(exp.ResolvedIPs, ", "))
+	} else if exp.ResolveErr != "" {
+		fmt.Printf("  %s %s\n", log.Cyan("解析失败:"), exp.ResolveErr)
+	}
+
+	if len(exp.Matches) == 0 {
+		fmt.Printf("  %s\n\n", log.Green("没有命中任何规则"))
+		return
+	}
+
+	fmt.Printf("  %s\n", log.Cyan("命中规则:"))
+	for _, match := range exp.Matches {
+		if match.Rule == nil {
+			fmt.Printf("    - %s\n", match.Reason)
+			continue
+		}
+		fmt.Printf("    - [%s] %s %s %s\n",
+			log.Yellow(match.Rule.Type),
+			match.Rule.Pattern,
+			log.Cyan("来源:"), match.Rule.Origin())
+		fmt.Printf("      %s %s\n", log.Cyan("匹配值:"), match.Value)
+	}
+	fmt.Println()
+}
+
+func init() {
+	rootCmd.AddCommand(blacklistCmd)
+	blacklistCmd.AddCommand(blacklistTestCmd)
+
+	// 黑名单规则选项
+	blacklistCmd.PersistentFlags().BoolVar(&opts.Scan.DefaultBlacklist, "default-blacklist", true, log.Cyan("使用默认黑名单规则"))
+	blacklistCmd.PersistentFlags().StringSliceVar(&opts.Scan.BlacklistPatterns, "blacklist-pattern", []string{}, log.Cyan("添加自定义黑名单规则 (可多次使用)"))
+	blacklistCmd.PersistentFlags().StringVar(&opts.Scan.BlacklistFile, "blacklist-file", "", log.Cyan("黑名单规则文件路径"))
+
+	blacklistTestCmd.Flags().BoolVar(&blacklistCmdFlags.json, "json", false, log.Cyan("以JSON格式输出结果"))
+
+	log.Debug(log.Green("已注册blacklist命令"))
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/cyberspacesec/go-snir/pkg/database"
+	"github.com/cyberspacesec/go-snir/pkg/islazy"
+	"github.com/cyberspacesec/go-snir/pkg/log"
+	"github.com/cyberspacesec/go-snir/pkg/runner"
+)
+
+// addDatabaseFlags 添加数据库连接相关的选项
+func addDatabaseFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&opts.DB.Path, "db-path", "go-web-screenshot.db", log.Cyan("SQLite数据库文件路径"))
+	flags.StringVar(&opts.Writer.DbURI, "db-uri", "", log.Cyan("数据库连接URI，支持 sqlite://, postgres://, mysql:// (设置后忽略 --db-path)"))
+	flags.IntVar(&opts.DB.MaxOpenConns, "db-max-open-conns", 0, log.Cyan("数据库最大打开连接数 (0表示不限制)"))
+	flags.IntVar(&opts.DB.MaxIdleConns, "db-max-idle-conns", 0, log.Cyan("数据库最大空闲连接数 (0表示使用默认值)"))
+	flags.DurationVar(&opts.DB.ConnMaxLifetime, "db-conn-max-lifetime", 0, log.Cyan("数据库连接最大存活时间，如 30m (0表示不限制)"))
+	flags.BoolVar(&opts.Writer.DbDebug, "db-debug", false, log.Cyan("输出数据库SQL日志"))
+}
+
+// openDatabase 根据命令行选项打开已有的数据库
+func openDatabase() (*database.DB, error) {
+	if opts.Writer.DbURI == "" && !islazy.FileExists(opts.DB.Path) {
+		return nil, fmt.Errorf("数据库文件不存在: %s", opts.DB.Path)
+	}
+
+	db, err := database.NewDB(runner.DatabaseOptions(opts))
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %v", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/cyberspacesec/go-snir/pkg/database"
+	"github.com/cyberspacesec/go-snir/pkg/islazy"
+	"github.com/cyberspacesec/go-snir/pkg/log"
+	"github.com/cyberspacesec/go-snir/pkg/prune"
+	"github.com/cyberspacesec/go-snir/pkg/storage"
+)
+
+// pruneFlags 保留策略相关的选项，prune、api和serve命令共用
+var pruneFlags = struct {
+	maxAge      string
+	keepPerURL  int
+	maxSize     string
+	orphans     bool
+	orphanGrace string
+	interval    time.Duration
+}{}
+
+var pruneCmdFlags = struct {
+	dryRun bool
+	json   bool
+}{}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: log.Yellow("按保留策略清理旧的扫描结果和截图文件"),
+	Long: log.Yellow("按保留时间、每个URL保留的数量和截图目录总大小清理数据库中的截图记录及对应的截图文件。" +
+		"先删除数据库记录，再删除不再被任何记录引用的文件；未找到数据库时只按文件修改时间和大小清理截图存储"),
+	Example: `  # 预览将删除30天前的结果
+  ./snir prune --max-age 30d --dry-run
+
+  # 每个URL只保留最近5次结果，截图目录不超过10GB
+  ./snir prune --keep-per-url 5 --max-size 10GB
+
+  # 同时清理未被数据库引用的截图文件
+  ./snir prune --max-age 90d --orphans --screenshot-path screenshots`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		policy, err := prunePolicy()
+		if err != nil {
+			return err
+		}
+		if !policy.Enabled() {
+			return fmt.Errorf("请至少指定 --max-age、--keep-per-url、--max-size 或 --orphans 中的一个")
+		}
+		policy.DryRun = pruneCmdFlags.dryRun
+
+		var db *database.DB
+		if opts.Writer.DbURI != "" || islazy.FileExists(opts.DB.Path) {
+			if db, err = openDatabase(); err != nil {
+				return err
+			}
+			defer db.Close()
+		} else {
+			log.Warn("数据库文件不存在，只清理截图存储", "db_path", opts.DB.Path)
+		}
+
+		store, err := storage.OpenBlobStore(opts.Scan.Storage, opts.Scan.ScreenshotPath)
+		if err != nil {
+			return fmt.Errorf("打开截图存储失败: %v", err)
+		}
+
+		report, err := prune.Run(context.Background(), db, store, policy, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if pruneCmdFlags.json {
+			return writeJSON(report)
+		}
+		printPruneReport(report)
+		return nil
+	},
+}
+
+// addPruneFlags 添加保留策略相关的选项
+func addPruneFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&pruneFlags.maxAge, "max-age", "", log.Cyan("结果最长保留时间，如 72h、30d (为空表示不限制)"))
+	flags.IntVar(&pruneFlags.keepPerURL, "keep-per-url", 0, log.Cyan("每个URL保留的最新结果数量 (0表示不限制)"))
+	flags.StringVar(&pruneFlags.maxSize, "max-size", "", log.Cyan("截图文件总大小上限，如 500MB、10GB，超过时从最旧的结果开始清理"))
+	flags.BoolVar(&pruneFlags.orphans, "orphans", false, log.Cyan("清理截图目录中未被数据库记录引用的文件，修改时间在 --orphan-grace 之内的文件不清理"))
+	flags.StringVar(&pruneFlags.orphanGrace, "orphan-grace", "1h", log.Cyan("孤立文件的保留时间，正在进行的扫描已保存截图但尚未写入数据库，应大于扫描超时时间"))
+}
+
+// addBackgroundPruneFlags 添加api和serve命令中定期清理相关的选项
+func addBackgroundPruneFlags(flags *pflag.FlagSet) {
+	addPruneFlags(flags)
+	flags.DurationVar(&pruneFlags.interval, "prune-interval", 0, log.Cyan("按保留策略定期清理的间隔，如 1h (0表示不启用)"))
+}
+
+// prunePolicy 根据命令行选项创建保留策略
+func prunePolicy() (prune.Policy, error) {
+	maxAge, err := prune.ParseDuration(pruneFlags.maxAge)
+	if err != nil {
+		return prune.Policy{}, err
+	}
+	maxSize, err := prune.ParseSize(pruneFlags.maxSize)
+	if err != nil {
+		return prune.Policy{}, err
+	}
+	orphanGrace, err := prune.ParseDuration(pruneFlags.orphanGrace)
+	if err != nil {
+		return prune.Policy{}, err
+	}
+	if pruneFlags.keepPerURL < 0 {
+		return prune.Policy{}, fmt.Errorf("--keep-per-url 不能为负数")
+	}
+
+	return prune.Policy{
+		MaxAge:       maxAge,
+		KeepPerURL:   pruneFlags.keepPerURL,
+		MaxTotalSize: maxSize,
+		Orphans:      pruneFlags.orphans,
+		OrphanGrace:  orphanGrace,
+	}, nil
+}
+
+// printPruneReport 输出清理结果
+func printPruneReport(report *prune.Report) {
+	if len(report.Items) == 0 {
+		log.Info("没有需要清理的内容")
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\t探测时间\t原因\t大小\tURL\t文件")
+	for _, item := range report.Items {
+		id := "-"
+		if item.ID != 0 {
+			id = fmt.Sprintf("%d", item.ID)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			id, item.ProbedAt.Format("2006-01-02 15:04:05"), item.Reason,
+			prune.FormatSize(item.Size), item.URL, strings.Join(item.Files, ","))
+	}
+	tw.Flush()
+
+	if report.DryRun {
+		log.Info("预览模式，未删除任何内容",
+			"items", len(report.Items),
+			"freed", prune.FormatSize(report.FreedBytes))
+		return
+	}
+
+	for _, e := range report.Errors {
+		log.Warn(e)
+	}
+	log.Success("清理完成",
+		"rows", report.DeletedRows,
+		"files", report.DeletedFiles,
+		"freed", prune.FormatSize(report.FreedBytes))
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	addPruneFlags(pruneCmd.Flags())
+	addDatabaseFlags(pruneCmd.Flags())
+	addStorageFlags(pruneCmd.Flags())
+	pruneCmd.Flags().BoolVar(&pruneCmdFlags.dryRun, "dry-run", false, log.Cyan("只列出将被清理的结果和文件，不实际删除"))
+	pruneCmd.Flags().BoolVar(&pruneCmdFlags.json, "json", false, log.Cyan("以JSON格式输出结果"))
+
+	log.Debug(log.Green("已注册prune命令"))
+}
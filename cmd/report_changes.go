@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cyberspacesec/go-snir/pkg/changes"
+	"github.com/cyberspacesec/go-snir/pkg/log"
+)
+
+var changesCmdFlags = struct {
+	since          string
+	phashThreshold int
+	json           bool
+}{}
+
+var changesCmd = &cobra.Command{
+	Use:   "changes",
+	Short: "列出发生变化的主机",
+	Long:  "比较数据库中同一URL相邻两次探测的结果，列出指定时间之后状态码、标题、TLS证书、技术栈、HTML内容或截图发生变化的URL",
+	Example: `  # 列出最近24小时内发生变化的主机
+  ./snir report changes --since 24h
+
+  # 列出某个日期之后的变化，输出JSON
+  ./snir report changes --since 2024-06-01 --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := changes.ParseSince(changesCmdFlags.since, time.Now())
+		if err != nil {
+			return err
+		}
+
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		events, err := changes.Since(db, since, changes.Options{PHashThreshold: changesCmdFlags.phashThreshold})
+		if err != nil {
+			return fmt.Errorf("检测变化失败: %v", err)
+		}
+
+		if changesCmdFlags.json {
+			return writeJSON(events)
+		}
+
+		if len(events) == 0 {
+			log.Info("没有发现变化", "since", since.Format("2006-01-02 15:04:05"))
+			return nil
+		}
+
+		for _, event := range events {
+			printChangeEvent(event)
+		}
+		log.Info("发现变化", "count", len(events), "since", since.Format("2006-01-02 15:04:05"))
+		return nil
+	},
+}
+
+var historyCmd = &cobra.Command{
+	Use:     "history [url]",
+	Short:   "查看URL的探测历史",
+	Long:    "按探测时间列出数据库中同一URL的所有探测结果，以及与上一次探测相比发生变化的字段",
+	Example: `  ./snir report history https://example.com`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		results, events, err := changes.URLHistory(db, args[0], changes.Options{PHashThreshold: changesCmdFlags.phashThreshold})
+		if err != nil {
+			return fmt.Errorf("获取探测历史失败: %v", err)
+		}
+
+		if changesCmdFlags.json {
+			return writeJSON(map[string]interface{}{
+				"results": results,
+				"changes": events,
+			})
+		}
+
+		if len(results) == 0 {
+			log.Info("数据库中没有该URL的探测记录", "url", args[0])
+			return nil
+		}
+
+		changed := make(map[uint][]string)
+		for _, event := range events {
+			for _, change := range event.Changes {
+				changed[event.CurrentID] = append(changed[event.CurrentID], change.Field)
+			}
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\t探测时间\t状态码\t标题\t感知哈希\t变化")
+		for _, result := range results {
+			status := fmt.Sprintf("%d", result.ResponseCode)
+			if result.Failed {
+				status = "失败"
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n",
+				result.ID, result.ProbedAt.Format("2006-01-02 15:04:05"), status,
+				result.Title, result.PerceptionHash, strings.Join(changed[result.ID], ","))
+		}
+		return tw.Flush()
+	},
+}
+
+// printChangeEvent 打印一次变化
+func printChangeEvent(event changes.Event) {
+	fmt.Printf("%s %s\n", log.Bold(event.URL), log.Cyan(fmt.Sprintf("#%d -> #%d  %s",
+		event.PreviousID, event.CurrentID, event.ProbedAt.Format("2006-01-02 15:04:05"))))
+	for _, change := range event.Changes {
+		fmt.Printf("  %-13s %s -> %s", log.Yellow(change.Field), change.Old, change.New)
+		if change.Detail != "" {
+			fmt.Printf(" (%s)", change.Detail)
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+}
+
+// writeJSON 以缩进格式将数据输出到标准输出
+func writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(v)
+}
+
+func init() {
+	reportCmd.AddCommand(changesCmd)
+	reportCmd.AddCommand(historyCmd)
+
+	for _, c := range []*cobra.Command{changesCmd, historyCmd} {
+		addDatabaseFlags(c.Flags())
+		c.Flags().IntVar(&changesCmdFlags.phashThreshold, "phash-threshold", 10, "截图感知哈希的汉明距离超过该值时认为截图发生变化")
+		c.Flags().BoolVar(&changesCmdFlags.json, "json", false, "以JSON格式输出结果")
+	}
+	changesCmd.Flags().StringVar(&changesCmdFlags.since, "since", "24h", "只列出该时间之后的变化，支持 24h、7d、2006-01-02 或 RFC3339 格式")
+
+	log.Debug("已注册changes和history命令")
+}
@@ -10,14 +10,16 @@ import (
 )
 
 var convertCmdFlags = struct {
-	fromFile string
-	toFile   string
+	fromFile       string
+	toFile         string
+	screenshotPath string
+	storage        string
 }{}
 
 var convertCmd = &cobra.Command{
 	Use:   "convert",
 	Short: "转换报告格式",
-	Long:  "将报告从一种格式转换为另一种格式",
+	Long:  "将报告从一种格式转换为另一种格式，输出为 .har 文件时合并所有结果的网络活动记录",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// 检查必要的参数
 		if convertCmdFlags.fromFile == "" {
@@ -29,8 +31,10 @@ var convertCmd = &cobra.Command{
 
 		// 创建转换选项
 		options := report.ConvertOptions{
-			FromFile: convertCmdFlags.fromFile,
-			ToFile:   convertCmdFlags.toFile,
+			FromFile:       convertCmdFlags.fromFile,
+			ToFile:         convertCmdFlags.toFile,
+			ScreenshotPath: convertCmdFlags.screenshotPath,
+			Storage:        convertCmdFlags.storage,
 		}
 
 		// 执行转换
@@ -50,6 +54,8 @@ func init() {
 	// 添加转换相关选项
 	convertCmd.Flags().StringVar(&convertCmdFlags.fromFile, "from", "", "源文件路径")
 	convertCmd.Flags().StringVar(&convertCmdFlags.toFile, "to", "", "目标文件路径")
+	convertCmd.Flags().StringVar(&convertCmdFlags.screenshotPath, "screenshot-path", "screenshots", "截图目录，输出HAR文件时从中读取HAR文件")
+	convertCmd.Flags().StringVar(&convertCmdFlags.storage, "storage", "", storageFlagUsage)
 	convertCmd.MarkFlagRequired("from")
 	convertCmd.MarkFlagRequired("to")
 
@@ -16,13 +16,15 @@ var htmlCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// 检查输入文件
 		if opts.Report.InputFile == "" {
-			return fmt.Errorf("请使用 --input 参数指定JSONL结果文件")
+			return fmt.Errorf("请使用 --input 参数指定结果文件")
 		}
 
 		// 创建HTML选项
 		htmlOptions := report.HTMLOptions{
-			InputFile:  opts.Report.InputFile,
-			OutputPath: opts.Report.OutputPath,
+			InputFile:      opts.Report.InputFile,
+			OutputPath:     opts.Report.OutputPath,
+			ScreenshotPath: opts.Scan.ScreenshotPath,
+			Storage:        opts.Scan.Storage,
 		}
 
 		// 生成HTML报告
@@ -40,8 +42,10 @@ func init() {
 	reportCmd.AddCommand(htmlCmd)
 
 	// 添加HTML报告相关选项
-	htmlCmd.Flags().StringVar(&opts.Report.InputFile, "input", "", "JSONL格式的结果文件路径")
+	htmlCmd.Flags().StringVar(&opts.Report.InputFile, "input", "", "结果文件路径 (支持 .jsonl, .csv, .db)")
 	htmlCmd.Flags().StringVar(&opts.Report.OutputPath, "output", "report.html", "HTML报告输出路径")
+	htmlCmd.Flags().StringVar(&opts.Scan.ScreenshotPath, "screenshot-path", "screenshots", "截图目录，用于按内容哈希查找截图文件")
+	htmlCmd.Flags().StringVar(&opts.Scan.Storage, "storage", "", storageFlagUsage)
 	htmlCmd.MarkFlagRequired("input")
 
 	log.Debug("已注册html报告命令")
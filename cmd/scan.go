@@ -2,11 +2,14 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/cyberspacesec/go-snir/pkg/log"
+	"github.com/cyberspacesec/go-snir/pkg/runner"
 	"github.com/cyberspacesec/go-snir/pkg/scan"
 )
 
@@ -25,6 +28,9 @@ var scanCmd = &cobra.Command{
   
   # 扫描网段
   ./snir scan cidr 192.168.1.0/24
+
+  # 从管道读取目标
+  cat hosts.txt | ./snir scan -
   
   # 保存HTML内容和HTTP头
   ./snir scan example.com --save-html --save-headers
@@ -37,6 +43,11 @@ var scanCmd = &cobra.Command{
   
   # 更多示例请查看 docs/usage_examples.md`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// 参数为 - 时从标准输入流式读取目标
+		if len(args) == 1 && args[0] == "-" {
+			return scanStream(os.Stdin, "标准输入")
+		}
+
 		// 如果直接提供了URL参数，则视为单URL扫描模式
 		if len(args) == 1 {
 			target := args[0]
@@ -57,7 +68,7 @@ var scanCmd = &cobra.Command{
 			// 执行扫描
 			log.CommandTitle("扫描URL")
 			log.Info("开始扫描", "url", log.Cyan(target))
-			result, err := scanner.ScanSingle(target)
+			results, err := scanner.ScanSingle(target)
 			if err != nil {
 				// 美化错误消息
 				errMsg := err.Error()
@@ -85,7 +96,7 @@ var scanCmd = &cobra.Command{
 			}
 
 			// 打印结果
-			printResult(result)
+			printResult(results)
 
 			return nil
 		}
@@ -129,9 +140,24 @@ func init() {
 
 	// 添加通用的截图选项
 	scanCmd.PersistentFlags().StringVar(&opts.Scan.ScreenshotPath, "screenshot-path", "screenshots", log.Cyan("截图保存路径"))
+	scanCmd.PersistentFlags().StringVar(&opts.Scan.Storage, "storage", "", log.Cyan(storageFlagUsage))
 	scanCmd.PersistentFlags().StringVar(&opts.Scan.ScreenshotFormat, "screenshot-format", "png", log.Cyan("截图格式 (png或jpeg)"))
 	scanCmd.PersistentFlags().IntVar(&opts.Scan.ScreenshotQuality, "screenshot-quality", 90, log.Cyan("截图质量 (仅对jpeg格式有效)"))
 	scanCmd.PersistentFlags().BoolVar(&opts.Scan.ScreenshotSkipSave, "skip-screenshot", false, log.Cyan("跳过保存截图"))
+	scanCmd.PersistentFlags().StringVar(&opts.Scan.Capture, "capture", "screenshot", log.Cyan("采集模式 (screenshot、pdf或both)"))
+	scanCmd.PersistentFlags().StringVar(&opts.Scan.PDF.Paper, "pdf-paper", "A4", log.Cyan("PDF纸张大小 (A3、A4、A5、Letter、Legal、Tabloid)"))
+	scanCmd.PersistentFlags().BoolVar(&opts.Scan.PDF.Landscape, "pdf-landscape", false, log.Cyan("PDF横向打印"))
+	scanCmd.PersistentFlags().StringVar(&opts.Scan.PDF.Margin, "pdf-margin", "", log.Cyan("PDF页边距，如 1cm 或 10mm,5mm,10mm,5mm (上,右,下,左)"))
+	scanCmd.PersistentFlags().BoolVar(&opts.Scan.PDF.PrintBackground, "pdf-background", false, log.Cyan("PDF中打印背景图形"))
+	scanCmd.PersistentFlags().StringVar(&opts.Scan.PDF.HeaderTemplate, "pdf-header", "", log.Cyan("PDF页眉HTML模板，可使用 pageNumber、totalPages、url、title、date 等类名插入内容"))
+	scanCmd.PersistentFlags().StringVar(&opts.Scan.PDF.FooterTemplate, "pdf-footer", "", log.Cyan("PDF页脚HTML模板"))
+	scanCmd.PersistentFlags().BoolVar(&opts.Scan.Archive, "archive", false, log.Cyan("保存MHTML页面存档，记录存档的SHA-256哈希和采集时间"))
+	scanCmd.PersistentFlags().BoolVar(&opts.Scan.SaveHAR, "save-har", false, log.Cyan("保存HAR 1.2格式的网络活动记录，包括请求头、响应头、时间、重定向和请求发起者"))
+	scanCmd.PersistentFlags().Int64Var(&opts.Scan.HARBodyLimit, "har-body-limit", 0, log.Cyan("HAR中保存的单个响应体大小上限（字节），为0时不保存响应体"))
+	scanCmd.PersistentFlags().BoolVar(&opts.Scan.SaveNetwork, "save-network", false, log.Cyan("保存网络请求日志"))
+	scanCmd.PersistentFlags().StringSliceVar(&opts.Scan.Devices, "devices", nil, log.Cyan("模拟的设备，多个设备用逗号分隔，每个设备截图一次 (可选 "+strings.Join(runner.DeviceNames(), "、")+")"))
+	scanCmd.PersistentFlags().StringSliceVar(&opts.Scan.Viewports, "viewports", nil, log.Cyan("页面加载一次后依次调整视口大小并截图，多个视口用逗号分隔，如 1920x1080,1366x768,390x844"))
+	scanCmd.PersistentFlags().StringVar(&opts.Scan.DeviceFile, "device-file", "", log.Cyan("自定义设备配置文件 (JSON数组)，未指定 --devices 时使用文件中的所有设备"))
 	scanCmd.PersistentFlags().BoolVar(&opts.Scan.SaveHTML, "save-html", false, log.Cyan("保存网页HTML内容"))
 	scanCmd.PersistentFlags().BoolVar(&opts.Scan.SaveHeaders, "save-headers", false, log.Cyan("保存HTTP响应头"))
 	scanCmd.PersistentFlags().BoolVar(&opts.Scan.SaveConsole, "save-console", false, log.Cyan("保存控制台日志"))
@@ -140,24 +166,40 @@ func init() {
 	// Chrome相关选项
 	scanCmd.PersistentFlags().StringVar(&opts.Chrome.Path, "chrome-path", "", log.Cyan("Chrome可执行文件路径"))
 	scanCmd.PersistentFlags().StringVar(&opts.Chrome.UserAgent, "user-agent", "", log.Cyan("自定义User-Agent"))
-	scanCmd.PersistentFlags().StringVar(&opts.Chrome.Proxy, "proxy", "", log.Cyan("代理服务器地址"))
+	scanCmd.PersistentFlags().StringArrayVarP(&opts.Chrome.Headers, "header", "H", nil, log.Cyan("自定义HTTP请求头，格式为 \"名称: 值\"，加上 \"主机模式=\" 前缀时只发送给匹配的主机，如 \"*.example.com=X-Token: abc\"，可多次指定"))
+	scanCmd.PersistentFlags().StringVar(&opts.Chrome.AcceptLanguage, "accept-language", "", log.Cyan("Accept-Language请求头，同时设置navigator.languages，如 zh-CN,zh;q=0.9,en;q=0.8"))
+	scanCmd.PersistentFlags().StringVar(&opts.Chrome.Proxy, "proxy", "", log.Cyan("代理服务器地址，需要身份验证时使用 http://用户名:密码@主机:端口 (Chrome不支持SOCKS代理身份验证，socks4/socks5代理不能带用户名和密码)"))
+	scanCmd.PersistentFlags().StringVar(&opts.Chrome.ProxyFile, "proxy-file", "", log.Cyan("代理列表文件路径，每行一个代理(http/https/socks4/socks5)，每次截图按 --proxy-strategy 选择，只有http/https代理可以带用户名和密码"))
+	scanCmd.PersistentFlags().StringVar(&opts.Chrome.ProxyStrategy, "proxy-strategy", runner.ProxyRoundRobin, log.Cyan("代理选择策略: round-robin、random、sticky(同一主机使用同一代理)或failover(按顺序使用第一个可用代理)"))
+	scanCmd.PersistentFlags().StringArrayVar(&opts.Chrome.Auth, "auth", nil, log.Cyan("HTTP身份验证凭据(Basic/Digest/NTLM)，格式为 \"用户名:密码\"，加上 \"主机模式=\" 前缀时只用于匹配的主机，可多次指定"))
+	scanCmd.PersistentFlags().StringVar(&opts.Chrome.AuthFile, "auth-file", "", log.Cyan("凭据文件路径，每行一条，格式与 --auth 相同"))
 	scanCmd.PersistentFlags().IntVar(&opts.Chrome.Timeout, "timeout", 30, log.Cyan("页面加载超时时间(秒)"))
 	scanCmd.PersistentFlags().IntVar(&opts.Chrome.Delay, "delay", 0, log.Cyan("截图前等待时间(秒)"))
 	scanCmd.PersistentFlags().IntVar(&opts.Chrome.WindowX, "resolution-x", 1280, log.Cyan("窗口宽度"))
 	scanCmd.PersistentFlags().IntVar(&opts.Chrome.WindowY, "resolution-y", 800, log.Cyan("窗口高度"))
 	scanCmd.PersistentFlags().BoolVar(&opts.Chrome.Headless, "headless", true, log.Cyan("使用无头模式"))
+	scanCmd.PersistentFlags().BoolVar(&opts.Chrome.Stealth, "stealth", false, log.Cyan("隐藏navigator.webdriver、通知权限和User-Agent中的HeadlessChrome等自动化痕迹"))
 
 	// 扫描相关选项
 	scanCmd.PersistentFlags().IntVar(&opts.Scan.Threads, "threads", 2, log.Cyan("并发线程数"))
 	scanCmd.PersistentFlags().BoolVar(&opts.Scan.HTTP, "http", true, log.Cyan("使用HTTP协议"))
 	scanCmd.PersistentFlags().BoolVar(&opts.Scan.HTTPS, "https", true, log.Cyan("使用HTTPS协议"))
 	scanCmd.PersistentFlags().IntVar(&opts.Scan.MaxRetries, "max-retries", 1, log.Cyan("最大重试次数"))
+	scanCmd.PersistentFlags().DurationVar(&opts.Scan.RetryBackoff, "retry-backoff", 2*time.Second, log.Cyan("第一次重试前的等待时间，之后每次加倍，最长1分钟"))
+	scanCmd.PersistentFlags().StringSliceVar(&opts.Scan.RetryOn, "retry-on", runner.DefaultRetryOn, log.Cyan("需要重试的错误分类: dns、connection_refused、tls、timeout、network、proxy、http_5xx、browser_crash、unknown"))
+	scanCmd.PersistentFlags().Float64Var(&opts.Scan.RateLimit, "rate-limit", 0, log.Cyan("全局每秒最大请求数 (0表示不限制)"))
+	scanCmd.PersistentFlags().Float64Var(&opts.Scan.HostRateLimit, "host-rate-limit", 0, log.Cyan("同一主机每秒最大请求数，如 0.5 表示每2秒一次 (0表示不限制)"))
+	scanCmd.PersistentFlags().IntVar(&opts.Scan.HostConcurrency, "host-concurrency", 0, log.Cyan("同一主机的最大并发数 (0表示不限制)"))
+	scanCmd.PersistentFlags().StringVar(&opts.Scan.RateLimitBy, "rate-limit-by", runner.RateLimitByHost, log.Cyan("按主机(host)或注册域名(domain)计算同一主机的限制"))
+	scanCmd.PersistentFlags().DurationVar(&opts.Scan.Jitter, "jitter", 0, log.Cyan("每次请求前额外等待的随机时间上限，如 500ms"))
 	scanCmd.PersistentFlags().StringVar(&opts.Scan.JavaScript, "js", "", log.Cyan("要在页面上执行的JavaScript代码"))
 	scanCmd.PersistentFlags().StringVar(&opts.Scan.JavaScriptFile, "js-file", "", log.Cyan("包含JavaScript代码的文件路径"))
 
 	// 数据库相关选项
 	scanCmd.PersistentFlags().BoolVar(&opts.DB.Enable, "db", false, log.Cyan("启用数据库存储"))
-	scanCmd.PersistentFlags().StringVar(&opts.DB.Path, "db-path", "go-web-screenshot.db", log.Cyan("数据库文件路径"))
+	addDatabaseFlags(scanCmd.PersistentFlags())
+	scanCmd.PersistentFlags().StringVar(&opts.DB.SessionName, "session-name", "", log.Cyan("扫描会话名称 (默认使用开始时间)"))
+	scanCmd.PersistentFlags().StringVar(&opts.DB.Operator, "operator", "", log.Cyan("扫描操作者 (默认使用当前系统用户)"))
 
 	// 输出相关选项
 	scanCmd.PersistentFlags().BoolVar(&opts.Writer.Jsonl, "write-jsonl", false, log.Cyan("写入JSONL格式结果"))
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cyberspacesec/go-snir/pkg/log"
+	"github.com/cyberspacesec/go-snir/pkg/scan"
+	"github.com/cyberspacesec/go-snir/pkg/targets"
+)
+
+var domainCmd = &cobra.Command{
+	Use:   "domain [domain...]",
+	Short: log.Yellow("扩展子域名并截图"),
+	Long: log.Yellow("从证书透明度记录和子域名字典中扩展根域名的子域名，用系统解析器解析后对能解析的主机截图。" +
+		"结果中的 source 字段记录产生主机的扩展来源: input(指定的域名)、ct(证书透明度记录)、wordlist(字典)或permutation(组合)"),
+	Example: `  # 从crt.sh导出的JSON中扩展子域名
+  curl -s 'https://crt.sh/?q=%25.example.com&output=json' -o crt.json
+  ./snir scan domain example.com --ct-file crt.json
+
+  # 同时使用字典，并把字典与证书中的子域名组合 (如 api-dev.example.com)
+  ./snir scan domain example.com --ct-file crt.json --wordlist subdomains.txt --permute
+
+  # 提高并发解析数并保存结果为JSONL
+  ./snir scan domain example.com example.org --wordlist subdomains.txt --resolve-threads 50 --write-jsonl`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		expander, err := newExpander(args)
+		if err != nil {
+			return err
+		}
+		log.Info("开始解析候选主机", "domains", log.Cyan(strings.Join(args, " ")),
+			"candidates", log.Cyan(fmt.Sprintf("%d", expander.Candidates())))
+
+		return runScan(strings.Join(args, " "), func(scanner *scan.Scanner) (int, error) {
+			return scanner.ScanExpansion(expander)
+		})
+	},
+}
+
+// newExpander 根据扩展选项创建子域名扩展器，读取证书透明度记录和字典
+func newExpander(domains []string) (*targets.Expander, error) {
+	expander, err := targets.NewExpander(domains)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Scan.ResolveThreads > 0 {
+		expander.Threads = opts.Scan.ResolveThreads
+	}
+	if opts.Scan.ResolveTimeout > 0 {
+		expander.Timeout = opts.Scan.ResolveTimeout
+	}
+	expander.Permute = opts.Scan.Permute
+
+	read := func(path string, add func(f *os.File) error) error {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("无法打开文件: %v", err)
+		}
+		defer file.Close()
+		if err := add(file); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		return nil
+	}
+	for _, path := range opts.Scan.CTFiles {
+		if err := read(path, func(f *os.File) error { return expander.AddCT(f) }); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range opts.Scan.Wordlists {
+		if err := read(path, func(f *os.File) error { return expander.AddWordlist(f) }); err != nil {
+			return nil, err
+		}
+	}
+	return expander, nil
+}
+
+func init() {
+	scanCmd.AddCommand(domainCmd)
+
+	// 添加子域名扩展相关选项
+	domainCmd.Flags().StringArrayVar(&opts.Scan.CTFiles, "ct-file", nil, log.Cyan("证书透明度记录文件，支持crt.sh导出的JSON、每行一条JSON记录的日志(crt.sh或certstream格式)和每行一个名称的列表，可多次指定"))
+	domainCmd.Flags().StringArrayVar(&opts.Scan.Wordlists, "wordlist", nil, log.Cyan("子域名字典文件，每行一个子域名标签，可多次指定"))
+	domainCmd.Flags().BoolVar(&opts.Scan.Permute, "permute", false, log.Cyan("把字典中的标签与证书透明度记录中的子域名组合，如 api-dev、dev-api"))
+	domainCmd.Flags().IntVar(&opts.Scan.ResolveThreads, "resolve-threads", 20, log.Cyan("并发解析数"))
+	domainCmd.Flags().DurationVar(&opts.Scan.ResolveTimeout, "resolve-timeout", 5*time.Second, log.Cyan("单次解析的超时时间"))
+
+	// 自定义帮助输出，为示例部分添加颜色
+	defaultHelpFunc := domainCmd.HelpFunc()
+	domainCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		// 保存原始示例
+		originalExample := cmd.Example
+
+		// 为示例添加颜色
+		coloredExample := ""
+		lines := strings.Split(originalExample, "\n")
+		for _, line := range lines {
+			// 为示例添加颜色
+			if strings.HasPrefix(line, "  #") {
+				coloredExample += log.Cyan(line) + "\n"
+			} else if strings.HasPrefix(line, "  ./snir") {
+				coloredExample += log.Yellow(line) + "\n"
+			} else {
+				coloredExample += line + "\n"
+			}
+		}
+		cmd.Example = coloredExample
+
+		// 调用默认帮助函数
+		defaultHelpFunc(cmd, args)
+
+		// 恢复原始示例
+		cmd.Example = originalExample
+	})
+
+	log.Debug(log.Green("已注册domain命令"))
+}
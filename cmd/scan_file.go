@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"strings"
@@ -9,7 +8,6 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/cyberspacesec/go-snir/pkg/log"
-	"github.com/cyberspacesec/go-snir/pkg/scan"
 )
 
 var fileCmd = &cobra.Command{
@@ -36,56 +34,19 @@ var fileCmd = &cobra.Command{
 			return fmt.Errorf("请使用 -f 或 --file 参数指定URL文件路径")
 		}
 
-		// 打开文件
+		// 文件路径为 - 时从标准输入读取
+		if opts.Scan.FilePath == "-" {
+			return scanStream(os.Stdin, "标准输入")
+		}
+
+		// 打开文件，边读取边扫描，不会把全部URL读入内存
 		file, err := os.Open(opts.Scan.FilePath)
 		if err != nil {
 			return fmt.Errorf("无法打开文件: %v", err)
 		}
 		defer file.Close()
 
-		// 读取URL列表
-		var urls []string
-		fileScanner := bufio.NewScanner(file)
-		for fileScanner.Scan() {
-			url := strings.TrimSpace(fileScanner.Text())
-			if url != "" && !strings.HasPrefix(url, "#") {
-				urls = append(urls, url)
-			}
-		}
-
-		if err := fileScanner.Err(); err != nil {
-			return fmt.Errorf("读取文件时出错: %v", err)
-		}
-
-		if len(urls) == 0 {
-			return fmt.Errorf("文件中没有有效的URL")
-		}
-
-		log.Info("从文件中读取URL", "count", log.Cyan(fmt.Sprintf("%d", len(urls))), "file", log.Cyan(opts.Scan.FilePath))
-
-		// 创建扫描配置
-		config := &scan.Config{
-			Targets: urls,
-			Options: opts,
-		}
-
-		// 创建扫描器
-		urlScanner, err := scan.NewScanner(config)
-		if err != nil {
-			return fmt.Errorf("创建扫描器失败: %v", err)
-		}
-		defer urlScanner.Close()
-
-		// 执行扫描
-		log.CommandTitle("批量扫描")
-		log.Info("开始批量扫描", "url_count", log.Cyan(fmt.Sprintf("%d", len(urls))))
-		err = urlScanner.ScanMulti(urls)
-		if err != nil {
-			return fmt.Errorf("批量扫描失败: %v", err)
-		}
-
-		log.Success("批量扫描完成", "file", log.Cyan(opts.Scan.FilePath))
-		return nil
+		return scanStream(file, opts.Scan.FilePath)
 	},
 }
 
@@ -93,7 +54,7 @@ func init() {
 	scanCmd.AddCommand(fileCmd)
 
 	// 添加文件扫描相关选项
-	fileCmd.Flags().StringVarP(&opts.Scan.FilePath, "file", "f", "", log.Cyan("包含URL列表的文件路径"))
+	fileCmd.Flags().StringVarP(&opts.Scan.FilePath, "file", "f", "", log.Cyan("包含URL列表的文件路径，每行可以包含多个URL、主机名或CIDR网段，- 表示标准输入"))
 	fileCmd.MarkFlagRequired("file")
 
 	// 自定义帮助输出，为示例部分添加颜色
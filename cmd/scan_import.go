@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cyberspacesec/go-snir/pkg/log"
+	"github.com/cyberspacesec/go-snir/pkg/scan"
+	"github.com/cyberspacesec/go-snir/pkg/targets"
+)
+
+// importLong 是导入命令共用的说明
+const importLong = "。识别为 http、https 或 ssl 的服务总会被扫描，TLS 服务使用 https；" +
+	"指定 --ports 时同时扫描列表中的其他端口，否则只扫描没有服务信息的端口，" +
+	"没有服务信息时 443、8443 等端口使用 https，其他端口使用 http"
+
+var nmapCmd = &cobra.Command{
+	Use:   "nmap",
+	Short: log.Yellow("导入nmap扫描结果并截图"),
+	Long:  log.Yellow("从nmap的XML输出 (-oX) 中读取开放端口并截图" + importLong),
+	Example: `  # 导入nmap的服务识别结果
+  nmap -sV -p- -oX scan.xml 10.0.0.0/24
+  ./snir scan nmap -f scan.xml
+
+  # 同时扫描未识别为Web服务的3000和5000端口
+  ./snir scan nmap -f scan.xml --ports 3000,5000`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return scanImport(targets.FormatNmap)
+	},
+}
+
+var masscanCmd = &cobra.Command{
+	Use:   "masscan",
+	Short: log.Yellow("导入masscan扫描结果并截图"),
+	Long:  log.Yellow("从masscan的JSON (-oJ) 或列表 (-oL) 输出中读取开放端口并截图" + importLong),
+	Example: `  # 导入masscan的JSON输出
+  masscan -p80,443,8000-9000 10.0.0.0/16 -oJ scan.json
+  ./snir scan masscan -f scan.json
+
+  # 只扫描部分端口
+  ./snir scan masscan -f scan.txt --ports 80,443,8080`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return scanImport(targets.FormatMasscan)
+	},
+}
+
+var naabuCmd = &cobra.Command{
+	Use:   "naabu",
+	Short: log.Yellow("导入naabu扫描结果并截图"),
+	Long:  log.Yellow("从naabu的默认输出 (主机:端口) 或JSON输出 (-json) 中读取开放端口并截图" + importLong),
+	Example: `  # 通过管道导入naabu的结果
+  naabu -host example.com -silent | ./snir scan naabu -f -
+
+  # 导入naabu的JSON输出
+  ./snir scan naabu -f naabu.json --ports 80,443,8080,8443`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return scanImport(targets.FormatNaabu)
+	},
+}
+
+var hostPortCmd = &cobra.Command{
+	Use:   "hostport",
+	Short: log.Yellow("扫描 主机:端口 列表"),
+	Long:  log.Yellow("从每行一个 主机:端口 的列表中读取开放端口并截图，# 之后为注释" + importLong),
+	Example: `  # 扫描 主机:端口 列表
+  ./snir scan hostport -f open-ports.txt
+
+  # 只扫描列表中的Web端口
+  ./snir scan hostport -f open-ports.txt --ports 80,443,8080,8443`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return scanImport(targets.FormatHostPort)
+	},
+}
+
+// requestLong 是代理记录导入命令共用的说明
+const requestLong = "。按规范化的URL去重（协议和主机名小写、去掉默认端口和片段、查询参数排序），" +
+	"请求中的Cookie和请求头（如 Authorization）只发送给请求所在的主机，以便截取需要登录的页面"
+
+var burpCmd = &cobra.Command{
+	Use:   "burp",
+	Short: log.Yellow("导入Burp Suite记录的请求并截图"),
+	Long:  log.Yellow("从Burp Suite导出的XML (Proxy history 或 Site map 中选择 Save items) 中读取请求并截图" + requestLong),
+	Example: `  # 导入Burp导出的请求，请求内容可以经过base64编码
+  ./snir scan burp -f burp-items.xml
+
+  # 只导入GET请求
+  ./snir scan burp -f burp-items.xml --get-only`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return scanRequestImport(targets.FormatBurp)
+	},
+}
+
+var zapCmd = &cobra.Command{
+	Use:   "zap",
+	Short: log.Yellow("导入ZAP记录的请求并截图"),
+	Long:  log.Yellow("从ZAP导出的消息文件 (Export Messages to File) 中读取请求并截图，ZAP导出的HAR文件请使用 scan har" + requestLong),
+	Example: `  # 导入ZAP导出的消息
+  ./snir scan zap -f zap-messages.txt --get-only`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return scanRequestImport(targets.FormatZAP)
+	},
+}
+
+var harCmd = &cobra.Command{
+	Use:   "har",
+	Short: log.Yellow("导入HAR文件中的请求并截图"),
+	Long:  log.Yellow("从HAR文件中读取请求并截图，浏览器开发者工具、Burp和ZAP导出的HAR文件都可以导入" + requestLong),
+	Example: `  # 导入浏览器导出的HAR文件
+  ./snir scan har -f session.har --get-only`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return scanRequestImport(targets.FormatHAR)
+	},
+}
+
+// scanImport 导入 -f 指定的端口扫描结果并扫描，- 表示标准输入
+func scanImport(format string) error {
+	file, source, err := openImport(format)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return runScan(source, func(scanner *scan.Scanner) (int, error) {
+		return scanner.ScanImport(file, format)
+	})
+}
+
+// scanRequestImport 导入 -f 指定的代理记录并扫描，- 表示标准输入
+func scanRequestImport(format string) error {
+	file, source, err := openImport(format)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return runScan(source, func(scanner *scan.Scanner) (int, error) {
+		return scanner.ScanRequests(file, format)
+	})
+}
+
+// openImport 打开 -f 指定的导入文件，- 表示标准输入
+func openImport(format string) (*os.File, string, error) {
+	if opts.Scan.FilePath == "" {
+		return nil, "", fmt.Errorf("请使用 -f 或 --file 参数指定%s文件路径", format)
+	}
+	if opts.Scan.FilePath == "-" {
+		return os.Stdin, "标准输入", nil
+	}
+	file, err := os.Open(opts.Scan.FilePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("无法打开文件: %v", err)
+	}
+	return file, opts.Scan.FilePath, nil
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{nmapCmd, masscanCmd, naabuCmd, hostPortCmd, burpCmd, zapCmd, harCmd} {
+		scanCmd.AddCommand(cmd)
+
+		// 添加导入相关选项
+		cmd.Flags().StringVarP(&opts.Scan.FilePath, "file", "f", "", log.Cyan("导入的文件路径，- 表示标准输入"))
+		cmd.MarkFlagRequired("file")
+		switch cmd {
+		case burpCmd, zapCmd, harCmd:
+			cmd.Flags().BoolVar(&opts.Scan.GetOnly, "get-only", false, log.Cyan("只导入GET请求"))
+		default:
+			cmd.Flags().IntSliceVar(&opts.Scan.Ports, "ports", nil, log.Cyan("除识别为Web服务的端口外，还要扫描的端口列表，如 80,443,8080"))
+		}
+
+		// 自定义帮助输出，为示例部分添加颜色
+		defaultHelpFunc := cmd.HelpFunc()
+		cmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+			// 保存原始示例
+			originalExample := cmd.Example
+
+			// 为示例添加颜色
+			coloredExample := ""
+			lines := strings.Split(originalExample, "\n")
+			for _, line := range lines {
+				// 为示例添加颜色
+				if strings.HasPrefix(line, "  #") {
+					coloredExample += log.Cyan(line) + "\n"
+				} else if strings.HasPrefix(line, "  ./snir") || strings.Contains(line, "| ./snir") {
+					coloredExample += log.Yellow(line) + "\n"
+				} else {
+					coloredExample += line + "\n"
+				}
+			}
+			cmd.Example = coloredExample
+
+			// 调用默认帮助函数
+			defaultHelpFunc(cmd, args)
+
+			// 恢复原始示例
+			cmd.Example = originalExample
+		})
+	}
+
+	log.Debug(log.Green("已注册nmap、masscan、naabu、hostport、burp、zap和har命令"))
+}
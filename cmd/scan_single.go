@@ -48,7 +48,7 @@ var singleCmd = &cobra.Command{
 		// 执行扫描
 		log.CommandTitle("扫描URL")
 		log.Info("开始扫描", "url", log.Cyan(target))
-		result, err := scanner.ScanSingle(target)
+		results, err := scanner.ScanSingle(target)
 		if err != nil {
 			// 美化错误消息
 			errMsg := err.Error()
@@ -76,7 +76,7 @@ var singleCmd = &cobra.Command{
 		}
 
 		// 打印结果
-		printResult(result)
+		printResult(results)
 
 		return nil
 	},
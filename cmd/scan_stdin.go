@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cyberspacesec/go-snir/pkg/log"
+	"github.com/cyberspacesec/go-snir/pkg/scan"
+)
+
+var stdinCmd = &cobra.Command{
+	Use:   "stdin",
+	Short: log.Yellow("从标准输入流式扫描目标"),
+	Long: log.Yellow("从标准输入逐行读取目标并扫描，可以通过管道接收其他工具的输出。" +
+		"每行可以包含多个用空白分隔的URL、主机名或CIDR网段，行首或空白之后的 # 开始注释。" +
+		"目标边读取边扫描，不会全部读入内存"),
+	Example: `  # 从其他工具的输出中读取目标
+  subfinder -d example.com -silent | ./snir scan stdin
+
+  # 等价的简写
+  cat hosts.txt | ./snir scan -
+
+  # 混合输入URL、主机名和网段
+  echo "https://example.com 10.0.0.0/24 intranet.local" | ./snir scan -`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return scanStream(os.Stdin, "标准输入")
+	},
+}
+
+// scanStream 从r中流式读取目标并扫描，source 为日志中显示的目标来源
+func scanStream(r io.Reader, source string) error {
+	return runScan(source, func(scanner *scan.Scanner) (int, error) {
+		return scanner.ScanReader(r)
+	})
+}
+
+// runScan 创建扫描器并执行scanFn，scanFn 返回扫描的目标数量
+func runScan(source string, scanFn func(scanner *scan.Scanner) (int, error)) error {
+	// 创建扫描配置
+	config := &scan.Config{
+		Options: opts,
+	}
+
+	// 创建扫描器
+	scanner, err := scan.NewScanner(config)
+	if err != nil {
+		return fmt.Errorf("创建扫描器失败: %v", err)
+	}
+	defer scanner.Close()
+
+	// 执行扫描
+	log.CommandTitle("批量扫描")
+	log.Info("开始流式扫描", "source", log.Cyan(source))
+	count, err := scanFn(scanner)
+	if err != nil {
+		return fmt.Errorf("批量扫描失败: %v", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%s中没有有效的目标", source)
+	}
+
+	log.Success("批量扫描完成", "source", log.Cyan(source), "count", log.Cyan(fmt.Sprintf("%d", count)))
+	return nil
+}
+
+func init() {
+	scanCmd.AddCommand(stdinCmd)
+
+	// 自定义帮助输出，为示例部分添加颜色
+	defaultHelpFunc := stdinCmd.HelpFunc()
+	stdinCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		// 保存原始示例
+		originalExample := cmd.Example
+
+		// 为示例添加颜色
+		coloredExample := ""
+		lines := strings.Split(originalExample, "\n")
+		for _, line := range lines {
+			// 为示例添加颜色
+			if strings.HasPrefix(line, "  #") {
+				coloredExample += log.Cyan(line) + "\n"
+			} else if strings.Contains(line, "./snir") {
+				coloredExample += log.Yellow(line) + "\n"
+			} else {
+				coloredExample += line + "\n"
+			}
+		}
+		cmd.Example = coloredExample
+
+		// 调用默认帮助函数
+		defaultHelpFunc(cmd, args)
+
+		// 恢复原始示例
+		cmd.Example = originalExample
+	})
+}
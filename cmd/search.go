@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cyberspacesec/go-snir/pkg/log"
+)
+
+var searchCmdFlags = struct {
+	limit int
+	json  bool
+}{}
+
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: log.Yellow("在数据库中全文搜索截图结果"),
+	Long: log.Yellow("在标题、URL、响应头、HTML内容和技术栈中搜索截图结果，按相关度排序。" +
+		"支持字段前缀 title:, url:, header:名称=值, body:, tech:，多个条件需要同时匹配，以*结尾表示前缀匹配"),
+	Example: `  # 在所有字段中搜索
+  ./snir search login
+
+  # 搜索标题中包含短语的页面
+  ./snir search 'title:"sign in"'
+
+  # 搜索使用nginx且页面中包含密码输入框的结果
+  ./snir search 'header:server=nginx body:password'
+
+  # 按技术栈搜索，输出JSON
+  ./snir search tech:WordPress --json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		results, err := db.Search(strings.Join(args, " "), searchCmdFlags.limit)
+		if err != nil {
+			return err
+		}
+
+		if searchCmdFlags.json {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(results)
+		}
+
+		if len(results) == 0 {
+			log.Info("没有找到匹配的结果")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "相关度\tID\t状态码\t标题\tURL\t截图")
+		for _, result := range results {
+			fmt.Fprintf(tw, "%.2f\t%d\t%d\t%s\t%s\t%s\n",
+				result.Rank, result.ID, result.ResponseCode, result.Title, result.URL, result.Filename)
+		}
+		return tw.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	addDatabaseFlags(searchCmd.Flags())
+	searchCmd.Flags().IntVar(&searchCmdFlags.limit, "limit", 50, log.Cyan("最多返回的结果数量"))
+	searchCmd.Flags().BoolVar(&searchCmdFlags.json, "json", false, log.Cyan("以JSON格式输出结果"))
+
+	log.Debug(log.Green("已注册search命令"))
+}
@@ -7,17 +7,31 @@ import (
 	"github.com/cyberspacesec/go-snir/pkg/report"
 )
 
+var serveCmdFlags = struct {
+	dbPath string
+}{}
+
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: log.Yellow("启动Web服务器查看结果"),
 	Long:  log.Yellow("启动一个Web服务器，用于查看截图和扫描结果"),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// 保留策略
+		policy, err := prunePolicy()
+		if err != nil {
+			return err
+		}
+
 		// 创建服务器配置
 		serverOptions := report.ServerOptions{
 			Host:           opts.Report.Host,
 			Port:           opts.Report.Port,
 			ScreenshotPath: opts.Scan.ScreenshotPath,
+			Storage:        opts.Scan.Storage,
 			ReportPath:     opts.Report.OutputPath,
+			DBPath:         serveCmdFlags.dbPath,
+			Prune:          policy,
+			PruneInterval:  pruneFlags.interval,
 		}
 
 		// 创建服务器
@@ -36,6 +50,9 @@ func init() {
 	// 添加服务器选项
 	serveCmd.Flags().StringVar(&opts.Report.Host, "host", "0.0.0.0", log.Cyan("Web服务器监听地址"))
 	serveCmd.Flags().IntVar(&opts.Report.Port, "port", 8080, log.Cyan("Web服务器监听端口"))
+	serveCmd.Flags().StringVar(&serveCmdFlags.dbPath, "db-path", "", log.Cyan("从该数据库读取截图结果，并支持按标签筛选"))
+	addStorageFlags(serveCmd.Flags())
+	addBackgroundPruneFlags(serveCmd.Flags())
 
 	log.Debug(log.Green("已注册serve命令"))
 }
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cyberspacesec/go-snir/pkg/database"
+	"github.com/cyberspacesec/go-snir/pkg/log"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: log.Yellow("管理扫描会话"),
+	Long:  log.Yellow("查看、比较和删除数据库中记录的扫描会话"),
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: log.Yellow("列出所有扫描会话"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		sessions, err := db.GetScanSessions()
+		if err != nil {
+			return fmt.Errorf("获取扫描会话失败: %v", err)
+		}
+
+		if len(sessions) == 0 {
+			log.Info("数据库中没有扫描会话")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\t名称\t操作者\t目标数\t结果数\t失败数\t开始时间\t耗时")
+		for _, session := range sessions {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\t%d\t%s\t%s\n",
+				session.ID, session.Name, session.Operator,
+				session.TargetCount, session.ResultCount, session.FailedCount,
+				session.StartedAt.Format("2006-01-02 15:04:05"), sessionDuration(session))
+		}
+		return tw.Flush()
+	},
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show [id]",
+	Short: log.Yellow("显示扫描会话详情及其结果"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		session, err := db.GetScanSession(id)
+		if err != nil {
+			return fmt.Errorf("获取扫描会话失败: %v", err)
+		}
+
+		screenshots, err := db.GetSessionScreenshots(id)
+		if err != nil {
+			return fmt.Errorf("获取会话结果失败: %v", err)
+		}
+
+		fmt.Printf("%s %d\n", log.Cyan("ID:"), session.ID)
+		fmt.Printf("%s %s\n", log.Cyan("名称:"), session.Name)
+		fmt.Printf("%s %s\n", log.Cyan("操作者:"), session.Operator)
+		fmt.Printf("%s %s\n", log.Cyan("命令:"), session.CommandLine)
+		fmt.Printf("%s %s\n", log.Cyan("开始时间:"), session.StartedAt.Format("2006-01-02 15:04:05"))
+		fmt.Printf("%s %s\n", log.Cyan("耗时:"), sessionDuration(session))
+		fmt.Printf("%s %d / %s %d / %s %d\n",
+			log.Cyan("目标数:"), session.TargetCount,
+			log.Cyan("结果数:"), session.ResultCount,
+			log.Cyan("失败数:"), session.FailedCount)
+		fmt.Printf("%s %s\n\n", log.Cyan("选项:"), session.Options)
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\t状态码\t标题\tURL\t状态")
+		for _, screenshot := range screenshots {
+			status := "成功"
+			if screenshot.Failed {
+				status = "失败: " + screenshot.FailedReason
+			}
+			fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\n",
+				screenshot.ID, screenshot.ResponseCode, screenshot.Title, screenshot.URL, status)
+		}
+		return tw.Flush()
+	},
+}
+
+var sessionsDeleteCmd = &cobra.Command{
+	Use:   "delete [id]",
+	Short: log.Yellow("删除扫描会话及其所有结果"),
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if err := db.DeleteScanSession(id); err != nil {
+			return fmt.Errorf("删除扫描会话失败: %v", err)
+		}
+
+		log.Success("已删除扫描会话", "id", id)
+		return nil
+	},
+}
+
+// parseID 解析会话或截图ID参数
+func parseID(arg string) (uint, error) {
+	id, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("无效的ID: %s", arg)
+	}
+	return uint(id), nil
+}
+
+// sessionDuration 返回会话耗时的可读描述
+func sessionDuration(session *database.ScanSession) string {
+	if session.EndedAt.IsZero() {
+		return "进行中"
+	}
+	return session.EndedAt.Sub(session.StartedAt).Round(time.Second).String()
+}
+
+func init() {
+	rootCmd.AddCommand(sessionsCmd)
+	sessionsCmd.AddCommand(sessionsListCmd)
+	sessionsCmd.AddCommand(sessionsShowCmd)
+	sessionsCmd.AddCommand(sessionsDeleteCmd)
+
+	addDatabaseFlags(sessionsCmd.PersistentFlags())
+
+	log.Debug(log.Green("已注册sessions命令"))
+}
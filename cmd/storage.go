@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/cyberspacesec/go-snir/pkg/log"
+)
+
+// storageFlagUsage 是 --storage 选项的说明
+const storageFlagUsage = "截图存储URI，如 s3://bucket/prefix?endpoint=http://127.0.0.1:9000 (为空时保存到 --screenshot-path)"
+
+// addStorageFlags 添加截图目录和截图存储相关的选项
+func addStorageFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&opts.Scan.ScreenshotPath, "screenshot-path", "screenshots", log.Cyan("截图保存路径"))
+	flags.StringVar(&opts.Scan.Storage, "storage", "", log.Cyan(storageFlagUsage))
+}
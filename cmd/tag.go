@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cyberspacesec/go-snir/pkg/database"
+	"github.com/cyberspacesec/go-snir/pkg/log"
+)
+
+var tagCmdFlags = struct {
+	url       string
+	tag       string
+	field     string
+	pattern   string
+	rulesFile string
+}{}
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: log.Yellow("管理截图标签"),
+	Long:  log.Yellow("为数据库中的截图添加、移除和查看标签，或按规则批量打标签"),
+	Example: `  # 给ID为3和5的截图添加标签
+  ./snir tag add login 3 5
+
+  # 给某个URL的所有截图添加标签
+  ./snir tag add interesting --url https://example.com
+
+  # 移除标签
+  ./snir tag remove false-positive 5
+
+  # 查看所有标签及数量，或查看某个标签下的截图
+  ./snir tag list
+  ./snir tag list --tag login
+
+  # 按标题正则批量打标签
+  ./snir tag apply --tag login --field title --pattern "(?i)(login|sign in)"
+
+  # 从规则文件批量打标签（每行: 标签 字段 正则表达式）
+  ./snir tag apply --rules-file tag-rules.txt`,
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add [tag] [id...]",
+	Short: log.Yellow("给截图添加标签"),
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		ids, err := tagTargetIDs(db, args[1:])
+		if err != nil {
+			return err
+		}
+
+		if err := db.TagScreenshots(ids, args[0]); err != nil {
+			return fmt.Errorf("添加标签失败: %v", err)
+		}
+
+		log.Success("已添加标签", "tag", args[0], "count", len(ids))
+		return nil
+	},
+}
+
+var tagRemoveCmd = &cobra.Command{
+	Use:   "remove [tag] [id...]",
+	Short: log.Yellow("移除截图上的标签"),
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		ids, err := tagTargetIDs(db, args[1:])
+		if err != nil {
+			return err
+		}
+
+		removed, err := db.UntagScreenshots(ids, args[0])
+		if err != nil {
+			return fmt.Errorf("移除标签失败: %v", err)
+		}
+
+		log.Success("已移除标签", "tag", args[0], "count", removed)
+		return nil
+	},
+}
+
+var tagListCmd = &cobra.Command{
+	Use:   "list",
+	Short: log.Yellow("列出标签，或列出某个标签下的截图"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		if tagCmdFlags.tag != "" {
+			screenshots, err := db.GetScreenshotsByTag(tagCmdFlags.tag)
+			if err != nil {
+				return fmt.Errorf("获取截图失败: %v", err)
+			}
+
+			fmt.Fprintln(tw, "ID\t状态码\t标题\tURL\t标签")
+			for _, screenshot := range screenshots {
+				result := screenshot.ToResult()
+				fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\n",
+					result.ID, result.ResponseCode, result.Title, result.URL, strings.Join(result.Tags, ","))
+			}
+			return tw.Flush()
+		}
+
+		counts, err := db.GetTagCounts()
+		if err != nil {
+			return fmt.Errorf("获取标签失败: %v", err)
+		}
+
+		fmt.Fprintln(tw, "标签\t截图数")
+		for _, count := range counts {
+			fmt.Fprintf(tw, "%s\t%d\n", count.Name, count.Count)
+		}
+		return tw.Flush()
+	},
+}
+
+var tagApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: log.Yellow("按规则批量给截图打标签"),
+	Long: log.Yellow("对数据库中的所有截图应用标签规则。规则由标签、字段和正则表达式组成，" +
+		"支持的字段: title, url, final_url, html, header, tech, status"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var rules []database.TagRule
+
+		if tagCmdFlags.rulesFile != "" {
+			file, err := os.Open(tagCmdFlags.rulesFile)
+			if err != nil {
+				return fmt.Errorf("无法打开规则文件: %v", err)
+			}
+			defer file.Close()
+
+			rules, err = database.ParseTagRules(file)
+			if err != nil {
+				return fmt.Errorf("解析规则文件失败: %v", err)
+			}
+		}
+
+		if tagCmdFlags.tag != "" || tagCmdFlags.pattern != "" {
+			rules = append(rules, database.TagRule{
+				Tag:     tagCmdFlags.tag,
+				Field:   tagCmdFlags.field,
+				Pattern: tagCmdFlags.pattern,
+			})
+		}
+
+		if len(rules) == 0 {
+			return fmt.Errorf("请使用 --rules-file 或 --tag/--field/--pattern 指定标签规则")
+		}
+
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		counts, err := db.ApplyTagRules(rules)
+		if err != nil {
+			return fmt.Errorf("应用标签规则失败: %v", err)
+		}
+
+		names := make([]string, 0, len(counts))
+		for name := range counts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			log.Info("已应用标签规则", "tag", log.Cyan(name), "matched", counts[name])
+		}
+		return nil
+	},
+}
+
+// tagTargetIDs 根据参数中的ID或 --url 选项确定要操作的截图
+func tagTargetIDs(db *database.DB, args []string) ([]uint, error) {
+	var ids []uint
+	for _, arg := range args {
+		id, err := parseID(arg)
+		if err != nil {
+			return nil, fmt.Errorf("无效的截图ID: %s", arg)
+		}
+		ids = append(ids, id)
+	}
+
+	if tagCmdFlags.url != "" {
+		urlIDs, err := db.GetScreenshotIDsByURL(tagCmdFlags.url)
+		if err != nil {
+			return nil, fmt.Errorf("查询URL失败: %v", err)
+		}
+		ids = append(ids, urlIDs...)
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("请指定截图ID或使用 --url 指定URL")
+	}
+	return ids, nil
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRemoveCmd)
+	tagCmd.AddCommand(tagListCmd)
+	tagCmd.AddCommand(tagApplyCmd)
+
+	addDatabaseFlags(tagCmd.PersistentFlags())
+
+	tagAddCmd.Flags().StringVar(&tagCmdFlags.url, "url", "", log.Cyan("给该URL的所有截图添加标签"))
+	tagRemoveCmd.Flags().StringVar(&tagCmdFlags.url, "url", "", log.Cyan("移除该URL的所有截图上的标签"))
+	tagListCmd.Flags().StringVar(&tagCmdFlags.tag, "tag", "", log.Cyan("列出带有该标签的截图"))
+
+	tagApplyCmd.Flags().StringVar(&tagCmdFlags.rulesFile, "rules-file", "", log.Cyan("标签规则文件，每行格式为\"标签 字段 正则表达式\""))
+	tagApplyCmd.Flags().StringVar(&tagCmdFlags.tag, "tag", "", log.Cyan("规则的标签名称"))
+	tagApplyCmd.Flags().StringVar(&tagCmdFlags.field, "field", database.TagFieldTitle, log.Cyan("规则匹配的字段"))
+	tagApplyCmd.Flags().StringVar(&tagCmdFlags.pattern, "pattern", "", log.Cyan("规则的正则表达式"))
+
+	log.Debug(log.Green("已注册tag命令"))
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cyberspacesec/go-snir/pkg/storage"
+)
+
+// HandleGetArchive 处理获取结果的MHTML页面存档请求
+// 返回前校验存档内容的SHA-256哈希，并在响应头中返回哈希和采集时间
+func (s *Server) HandleGetArchive(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDB(w) {
+		return
+	}
+	id, ok := resultIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	screenshot, err := s.db.GetScreenshot(id)
+	if err != nil {
+		sendSessionError(w, "获取结果失败", err)
+		return
+	}
+	if screenshot.Archive == "" {
+		SendJSONResponse(w, http.StatusNotFound, APIResponse{
+			Success: false,
+			Error:   "结果没有页面存档",
+		})
+		return
+	}
+
+	data, err := s.store.Get(r.Context(), s.store.Key(screenshot.Archive))
+	if errors.Is(err, storage.ErrNotFound) {
+		SendJSONResponse(w, http.StatusNotFound, APIResponse{
+			Success: false,
+			Error:   "页面存档文件不存在",
+		})
+		return
+	}
+	if err != nil {
+		SendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+			Success: false,
+			Error:   "读取页面存档失败: " + err.Error(),
+		})
+		return
+	}
+
+	// 存档内容被修改时不返回，避免作为证据使用
+	if screenshot.ArchiveHash != "" && storage.Hash(data) != screenshot.ArchiveHash {
+		SendJSONResponse(w, http.StatusConflict, APIResponse{
+			Success: false,
+			Error:   "页面存档内容与记录的SHA-256哈希不一致",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", storage.ContentType(screenshot.Archive))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"result-%d.mhtml\"", screenshot.ID))
+	w.Header().Set("X-Archive-SHA256", screenshot.ArchiveHash)
+	if screenshot.ArchivedAt != nil {
+		w.Header().Set("X-Archived-At", screenshot.ArchivedAt.UTC().Format(time.RFC3339))
+	}
+	w.Write(data)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cyberspacesec/go-snir/pkg/storage"
+)
+
+// BlobResult 表示引用截图文件的扫描结果
+type BlobResult struct {
+	ID       uint      `json:"id"`
+	URL      string    `json:"url"`
+	Title    string    `json:"title"`
+	ProbedAt time.Time `json:"probed_at"`
+}
+
+// BlobInfo 表示按内容哈希保存的截图文件及引用它的结果
+type BlobInfo struct {
+	Hash          string       `json:"hash"`
+	Key           string       `json:"key"`
+	ScreenshotURL string       `json:"screenshot_url"`
+	CreatedAt     time.Time    `json:"created_at"`
+	Results       []BlobResult `json:"results"`
+}
+
+// HandleGetBlob 处理获取截图文件信息请求
+func (s *Server) HandleGetBlob(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDB(w) {
+		return
+	}
+
+	hash := mux.Vars(r)["hash"]
+	blob, err := s.db.GetBlob(hash)
+	if err != nil {
+		sendSessionError(w, "获取截图文件失败", err)
+		return
+	}
+
+	screenshots, err := s.db.GetBlobScreenshots(hash)
+	if err != nil {
+		sendSessionError(w, "获取截图结果失败", err)
+		return
+	}
+
+	key := storage.BlobKey(blob.Hash, blob.Ext)
+	info := BlobInfo{
+		Hash:          blob.Hash,
+		Key:           key,
+		ScreenshotURL: "/screenshots/" + key,
+		CreatedAt:     blob.CreatedAt,
+		Results:       make([]BlobResult, 0, len(screenshots)),
+	}
+	for _, screenshot := range screenshots {
+		info.Results = append(info.Results, BlobResult{
+			ID:       screenshot.ID,
+			URL:      screenshot.URL,
+			Title:    screenshot.Title,
+			ProbedAt: screenshot.ProbedAt,
+		})
+	}
+
+	SendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    info,
+	})
+}
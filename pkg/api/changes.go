@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cyberspacesec/go-snir/pkg/changes"
+)
+
+// changesOptions 从请求参数中读取变化检测选项
+func changesOptions(r *http.Request) changes.Options {
+	threshold, _ := strconv.Atoi(r.URL.Query().Get("phash_threshold"))
+	return changes.Options{PHashThreshold: threshold}
+}
+
+// HandleURLHistory 处理获取URL探测历史请求
+func (s *Server) HandleURLHistory(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDB(w) {
+		return
+	}
+
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   "缺少url参数",
+		})
+		return
+	}
+
+	results, events, err := changes.URLHistory(s.db, url, changesOptions(r))
+	if err != nil {
+		SendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+			Success: false,
+			Error:   "获取探测历史失败: " + err.Error(),
+		})
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"results": results,
+			"changes": events,
+		},
+	})
+}
+
+// HandleChanges 处理列出发生变化的主机请求
+func (s *Server) HandleChanges(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDB(w) {
+		return
+	}
+
+	value := r.URL.Query().Get("since")
+	if value == "" {
+		value = "24h"
+	}
+	since, err := changes.ParseSince(value, time.Now())
+	if err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	events, err := changes.Since(s.db, since, changesOptions(r))
+	if err != nil {
+		SendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+			Success: false,
+			Error:   "检测变化失败: " + err.Error(),
+		})
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    events,
+	})
+}
@@ -2,18 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
 	"strings"
 	"time"
 
-	"github.com/cyberspacesec/go-snir/pkg/islazy"
 	"github.com/cyberspacesec/go-snir/pkg/log"
 	"github.com/cyberspacesec/go-snir/pkg/models"
 	"github.com/cyberspacesec/go-snir/pkg/runner"
+	"github.com/cyberspacesec/go-snir/pkg/storage"
 	"github.com/gorilla/mux"
 )
 
@@ -28,7 +27,16 @@ func (s *Server) HandleRoot(w http.ResponseWriter, r *http.Request) {
 				"/screenshot - 截图单个URL (需要API密钥)",
 				"/batch - 批量截图多个URL (需要API密钥)",
 				"/screenshots_list - 列出所有截图 (需要API密钥)",
-				"/get_screenshot/{filename} - 获取指定截图 (需要API密钥)",
+				"/get_screenshot/{filename} - 获取指定截图，支持 <sha256>.png 形式的文件名 (需要API密钥)",
+				"/sessions - 列出扫描会话 (需要API密钥，需启用数据库)",
+				"/sessions/{id} - 获取或删除扫描会话 (需要API密钥，需启用数据库)",
+				"/tags - 列出标签，/tags/{name} 获取带有标签的结果，/tags/apply 批量应用标签规则 (需要API密钥，需启用数据库)",
+				"/results/{id}/tags - 给结果添加或移除标签 (需要API密钥，需启用数据库)",
+				"/results/{id}/archive - 下载结果的MHTML页面存档，响应头中包含SHA-256哈希和采集时间 (需要API密钥，需启用数据库)",
+				"/search?q= - 全文搜索标题、URL、响应头、HTML和技术栈，支持 title:, url:, header:name=, body:, tech: 前缀 (需要API密钥，需启用数据库)",
+				"/history?url= - 获取URL的探测历史及每次探测的变化 (需要API密钥，需启用数据库)",
+				"/changes?since=24h - 列出指定时间之后发生变化的主机 (需要API密钥，需启用数据库)",
+				"/blobs/{sha256} - 获取截图文件信息及引用该文件的结果 (需要API密钥，需启用数据库)",
 				"/screenshots/ - 直接访问截图文件（无需认证）",
 			},
 			"auth_required": true,
@@ -76,6 +84,8 @@ func (s *Server) HandleScreenshot(w http.ResponseWriter, r *http.Request) {
 	opts.Chrome.Path = ""
 	opts.Chrome.UserAgent = req.UserAgent
 	opts.Chrome.Proxy = req.Proxy
+	opts.Chrome.Proxies = req.Proxies
+	opts.Chrome.ProxyStrategy = req.ProxyStrategy
 	opts.Chrome.Timeout = req.Timeout
 	opts.Chrome.Delay = req.Delay
 	opts.Chrome.WindowX = 1280
@@ -85,6 +95,7 @@ func (s *Server) HandleScreenshot(w http.ResponseWriter, r *http.Request) {
 
 	// 截图选项
 	opts.Scan.ScreenshotPath = s.Options.ScreenshotPath
+	opts.Scan.Storage = s.Options.Storage
 	opts.Scan.ScreenshotFormat = "png"
 	opts.Scan.ScreenshotQuality = 90
 	opts.Scan.ScreenshotSkipSave = false
@@ -108,8 +119,10 @@ func (s *Server) HandleScreenshot(w http.ResponseWriter, r *http.Request) {
 	opts.Chrome.WebGLVendor = req.Fingerprint.WebGLVendor
 	opts.Chrome.WebGLRenderer = req.Fingerprint.WebGLRenderer
 	opts.Chrome.CustomHeaders = req.Fingerprint.CustomHeaders
+	opts.Chrome.Headers = req.Fingerprint.Headers
 	opts.Chrome.DisableWebRTC = req.Fingerprint.DisableWebRTC
 	opts.Chrome.SpoofScreenSize = req.Fingerprint.SpoofScreenSize
+	opts.Chrome.Stealth = req.Fingerprint.Stealth
 
 	// 如果请求指定了屏幕尺寸，则使用请求中的值
 	if req.Fingerprint.ScreenWidth > 0 {
@@ -138,11 +151,27 @@ func (s *Server) HandleScreenshot(w http.ResponseWriter, r *http.Request) {
 			})
 		}
 	}
+	for _, credential := range req.Auth {
+		opts.Chrome.Credentials = append(opts.Chrome.Credentials, runner.Credential{
+			Host:     credential.Host,
+			Username: credential.Username,
+			Password: credential.Password,
+		})
+	}
 
 	// 高级元素选择和交互
 	opts.Scan.Selector = req.Selector
 	opts.Scan.XPath = req.XPath
 	opts.Scan.CaptureFullPage = req.CaptureFullPage
+	opts.Scan.Capture = req.Capture
+	opts.Scan.PDF = runner.PDFOptions(req.PDF)
+	opts.Scan.Archive = req.Archive
+	opts.Scan.SaveHAR = req.SaveHAR
+	opts.Scan.HARBodyLimit = req.HARBodyLimit
+	opts.Scan.Viewports = req.Viewports
+	if req.Device != "" {
+		opts.Scan.Devices = []string{req.Device}
+	}
 
 	// 交互操作
 	if len(req.Actions) > 0 {
@@ -177,6 +206,52 @@ func (s *Server) HandleScreenshot(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// 检查采集模式和PDF选项
+	if err := runner.ValidateCapture(&opts); err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// 检查设备名称、视口大小、请求头、身份验证凭据和代理
+	if _, err := runner.LoadDevices(opts.Scan.Devices, ""); err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if _, err := runner.ParseViewports(opts.Scan.Viewports); err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if _, err := runner.ParseHeaderRules(opts.Chrome.Headers); err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := runner.ValidateCredentials(opts.Chrome.Credentials); err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if _, err := runner.LoadProxyPool(&opts); err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
 	// 首先创建黑名单实例并检查URL是否在黑名单中
 	blacklist, err := runner.NewURLBlacklist(&opts)
 	if err != nil {
@@ -218,11 +293,24 @@ func (s *Server) HandleScreenshot(w http.ResponseWriter, r *http.Request) {
 	}
 	defer runnerInstance.Close()
 
-	result, err := driver.Witness(req.URL, runnerInstance)
+	result, err := runnerInstance.Witness(runnerInstance.DeviceTargets(runner.Target{URL: req.URL})[0])
+
+	// 记录到扫描会话
+	if sessionWriter, serr := s.newSessionWriter(r, req.SessionName, req.Operator, &opts); serr != nil {
+		log.Error("创建扫描会话失败", "error", serr)
+	} else if sessionWriter != nil {
+		sessionWriter.SetTargetCount(1)
+		if result != nil {
+			sessionWriter.Write(result)
+		}
+		sessionWriter.Close()
+	}
+
 	if err != nil {
 		SendJSONResponse(w, http.StatusInternalServerError, APIResponse{
 			Success: false,
 			Error:   "截图失败: " + err.Error(),
+			Data:    result,
 		})
 		return
 	}
@@ -275,6 +363,8 @@ func (s *Server) HandleBatchScreenshot(w http.ResponseWriter, r *http.Request) {
 	opts.Chrome.Path = ""
 	opts.Chrome.UserAgent = req.UserAgent
 	opts.Chrome.Proxy = req.Proxy
+	opts.Chrome.Proxies = req.Proxies
+	opts.Chrome.ProxyStrategy = req.ProxyStrategy
 	opts.Chrome.Timeout = req.Timeout
 	opts.Chrome.Delay = req.Delay
 	opts.Chrome.WindowX = 1280
@@ -284,12 +374,18 @@ func (s *Server) HandleBatchScreenshot(w http.ResponseWriter, r *http.Request) {
 
 	// 截图选项
 	opts.Scan.ScreenshotPath = s.Options.ScreenshotPath
+	opts.Scan.Storage = s.Options.Storage
 	opts.Scan.ScreenshotFormat = "png"
 	opts.Scan.ScreenshotQuality = 90
 	opts.Scan.ScreenshotSkipSave = false
 	opts.Scan.HTTP = req.HTTP
 	opts.Scan.HTTPS = req.HTTPS
 	opts.Scan.Threads = req.Threads
+	opts.Scan.RateLimit = req.RateLimit
+	opts.Scan.HostRateLimit = req.HostRateLimit
+	opts.Scan.HostConcurrency = req.HostConcurrency
+	opts.Scan.RateLimitBy = req.RateLimitBy
+	opts.Scan.Jitter = time.Duration(req.JitterMs) * time.Millisecond
 
 	// 添加服务器级别的黑名单配置
 	opts.Scan.EnableBlacklist = s.Options.EnableBlacklist
@@ -308,8 +404,10 @@ func (s *Server) HandleBatchScreenshot(w http.ResponseWriter, r *http.Request) {
 	opts.Chrome.WebGLVendor = req.Fingerprint.WebGLVendor
 	opts.Chrome.WebGLRenderer = req.Fingerprint.WebGLRenderer
 	opts.Chrome.CustomHeaders = req.Fingerprint.CustomHeaders
+	opts.Chrome.Headers = req.Fingerprint.Headers
 	opts.Chrome.DisableWebRTC = req.Fingerprint.DisableWebRTC
 	opts.Chrome.SpoofScreenSize = req.Fingerprint.SpoofScreenSize
+	opts.Chrome.Stealth = req.Fingerprint.Stealth
 
 	// 如果请求指定了屏幕尺寸，则使用请求中的值
 	if req.Fingerprint.ScreenWidth > 0 {
@@ -338,11 +436,25 @@ func (s *Server) HandleBatchScreenshot(w http.ResponseWriter, r *http.Request) {
 			})
 		}
 	}
+	for _, credential := range req.Auth {
+		opts.Chrome.Credentials = append(opts.Chrome.Credentials, runner.Credential{
+			Host:     credential.Host,
+			Username: credential.Username,
+			Password: credential.Password,
+		})
+	}
 
 	// 高级元素选择和交互
 	opts.Scan.Selector = req.Selector
 	opts.Scan.XPath = req.XPath
 	opts.Scan.CaptureFullPage = req.CaptureFullPage
+	opts.Scan.Capture = req.Capture
+	opts.Scan.PDF = runner.PDFOptions(req.PDF)
+	opts.Scan.Archive = req.Archive
+	opts.Scan.SaveHAR = req.SaveHAR
+	opts.Scan.HARBodyLimit = req.HARBodyLimit
+	opts.Scan.Viewports = req.Viewports
+	opts.Scan.Devices = req.Devices
 
 	// 交互操作
 	if len(req.Actions) > 0 {
@@ -377,6 +489,59 @@ func (s *Server) HandleBatchScreenshot(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// 检查采集模式和PDF选项
+	if err := runner.ValidateCapture(&opts); err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// 检查设备名称、视口大小、请求头、身份验证凭据和代理
+	if _, err := runner.LoadDevices(opts.Scan.Devices, ""); err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if _, err := runner.ParseViewports(opts.Scan.Viewports); err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if _, err := runner.ParseHeaderRules(opts.Chrome.Headers); err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := runner.ValidateCredentials(opts.Chrome.Credentials); err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if _, err := runner.LoadProxyPool(&opts); err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if _, err := runner.NewScheduler(&opts); err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
 	// 创建黑名单检查器
 	blacklist, err := runner.NewURLBlacklist(&opts)
 	if err != nil {
@@ -442,7 +607,24 @@ func (s *Server) HandleBatchScreenshot(w http.ResponseWriter, r *http.Request) {
 		Results: []*models.Result{},
 	}
 
-	runnerInstance, err := runner.NewRunner(log.GetLogger(), driver, opts, []runner.Writer{memWriter})
+	writers := []runner.Writer{memWriter}
+
+	// 为批量任务打开扫描会话
+	sessionWriter, err := s.newSessionWriter(r, req.SessionName, req.Operator, &opts)
+	if err != nil {
+		SendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	var sessionID uint
+	if sessionWriter != nil {
+		writers = append(writers, sessionWriter)
+		sessionID = sessionWriter.Session().ID
+	}
+
+	runnerInstance, err := runner.NewRunner(log.GetLogger(), driver, opts, writers)
 	if err != nil {
 		SendJSONResponse(w, http.StatusInternalServerError, APIResponse{
 			Success: false,
@@ -462,7 +644,7 @@ func (s *Server) HandleBatchScreenshot(w http.ResponseWriter, r *http.Request) {
 
 	// 添加URL到处理队列
 	for _, urlStr := range filteredURLs {
-		runnerInstance.Targets <- urlStr
+		runnerInstance.Targets <- runner.Target{URL: urlStr}
 	}
 	close(runnerInstance.Targets)
 
@@ -475,6 +657,7 @@ func (s *Server) HandleBatchScreenshot(w http.ResponseWriter, r *http.Request) {
 		Message: fmt.Sprintf("已提交%d个URL进行截图", len(filteredURLs)),
 		Data: map[string]interface{}{
 			"task_id":          time.Now().Unix(),
+			"session_id":       sessionID,
 			"filtered_urls":    len(filteredURLs),
 			"blacklisted_urls": blacklistedURLs,
 		},
@@ -495,8 +678,7 @@ func (s *Server) HandleGetScreenshot(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 防止目录遍历攻击
-	cleanFilename := filepath.Clean(filename)
-	if strings.Contains(cleanFilename, "..") {
+	if strings.Contains(filename, "..") {
 		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
 			Success: false,
 			Error:   "无效的文件名",
@@ -504,23 +686,24 @@ func (s *Server) HandleGetScreenshot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 构建文件路径
-	filePath := filepath.Join(s.Options.ScreenshotPath, cleanFilename)
+	// 通过截图存储读取文件，支持分片路径和按哈希命名的文件
+	key, err := s.store.ResolveKey(filename)
+	if err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   "无效的文件名",
+		})
+		return
+	}
 
-	// 检查文件是否存在
-	if !islazy.FileExists(filePath) {
+	data, err := s.store.Get(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
 		SendJSONResponse(w, http.StatusNotFound, APIResponse{
 			Success: false,
 			Error:   "文件不存在",
 		})
 		return
 	}
-
-	// 获取文件内容类型
-	contentType := GetImageContentType(filePath)
-
-	// 读取文件
-	data, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		SendJSONResponse(w, http.StatusInternalServerError, APIResponse{
 			Success: false,
@@ -530,54 +713,41 @@ func (s *Server) HandleGetScreenshot(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 设置响应头
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", cleanFilename))
+	w.Header().Set("Content-Type", storage.ContentType(key))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", path.Base(key)))
 	w.Write(data)
 }
 
 // HandleListScreenshots 处理列出截图请求
 func (s *Server) HandleListScreenshots(w http.ResponseWriter, r *http.Request) {
-	// 检查截图目录是否存在
-	if !islazy.DirExists(s.Options.ScreenshotPath) {
-		SendJSONResponse(w, http.StatusOK, APIResponse{
-			Success: true,
-			Data:    []string{},
+	// 列出截图存储中的所有文件
+	objects, err := s.store.Backend().List(r.Context(), "")
+	if err != nil {
+		SendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+			Success: false,
+			Error:   "读取截图目录失败: " + err.Error(),
 		})
 		return
 	}
 
-	var screenshots []map[string]interface{}
-
-	// 遍历截图目录
-	err := filepath.Walk(s.Options.ScreenshotPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	screenshots := []map[string]interface{}{}
+	for _, object := range objects {
+		name := path.Base(object.Key)
+		if !IsImageFile(name) {
+			continue
 		}
 
-		// 只处理文件
-		if !info.IsDir() && IsImageFile(info.Name()) {
-			relPath, err := filepath.Rel(s.Options.ScreenshotPath, path)
-			if err != nil {
-				relPath = path
-			}
-
-			screenshots = append(screenshots, map[string]interface{}{
-				"filename": info.Name(),
-				"path":     relPath,
-				"size":     info.Size(),
-				"time":     info.ModTime().Format(time.RFC3339),
-				"url":      fmt.Sprintf("/screenshots/%s", relPath),
-			})
+		screenshot := map[string]interface{}{
+			"filename": name,
+			"path":     object.Key,
+			"size":     object.Size,
+			"time":     object.ModTime.Format(time.RFC3339),
+			"url":      fmt.Sprintf("/screenshots/%s", object.Key),
 		}
-		return nil
-	})
-
-	if err != nil {
-		SendJSONResponse(w, http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "读取截图目录失败: " + err.Error(),
-		})
-		return
+		if hash := strings.TrimSuffix(name, path.Ext(name)); storage.IsHash(hash) {
+			screenshot["hash"] = hash
+		}
+		screenshots = append(screenshots, screenshot)
 	}
 
 	SendJSONResponse(w, http.StatusOK, APIResponse{
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cyberspacesec/go-snir/pkg/database"
+)
+
+// SearchResult 表示带有截图链接的搜索结果
+type SearchResult struct {
+	database.SearchResult
+	ScreenshotURL string `json:"screenshot_url,omitempty"`
+}
+
+// screenshotURL 返回截图文件的访问链接
+func (s *Server) screenshotURL(filename string) string {
+	if filename == "" {
+		return ""
+	}
+	return fmt.Sprintf("/screenshots/%s", s.store.Key(filename))
+}
+
+// HandleSearch 处理全文搜索请求
+func (s *Server) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDB(w) {
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   "缺少搜索参数q",
+		})
+		return
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "无效的limit参数",
+			})
+			return
+		}
+	}
+
+	matches, err := s.db.Search(query, limit)
+	if err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	results := make([]SearchResult, 0, len(matches))
+	for _, match := range matches {
+		results = append(results, SearchResult{
+			SearchResult:  match,
+			ScreenshotURL: s.screenshotURL(match.Filename),
+		})
+	}
+
+	SendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    results,
+	})
+}
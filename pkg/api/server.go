@@ -8,7 +8,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/cyberspacesec/go-snir/pkg/database"
 	"github.com/cyberspacesec/go-snir/pkg/log"
+	"github.com/cyberspacesec/go-snir/pkg/prune"
+	"github.com/cyberspacesec/go-snir/pkg/storage"
 	"github.com/gorilla/mux"
 )
 
@@ -188,6 +191,7 @@ func NewServer(options Options) *Server {
 	return &Server{
 		Options: options,
 		Router:  router,
+		store:   storage.NewBlobStore(storage.NewLocal(options.ScreenshotPath)),
 	}
 }
 
@@ -206,10 +210,37 @@ func (s *Server) SetupRoutes() {
 	s.Router.HandleFunc("/screenshot", s.HandleScreenshot).Methods("POST")
 	s.Router.HandleFunc("/batch", s.HandleBatchScreenshot).Methods("POST")
 	s.Router.HandleFunc("/screenshots_list", s.HandleListScreenshots).Methods("GET")
-	s.Router.HandleFunc("/get_screenshot/{filename}", s.HandleGetScreenshot).Methods("GET")
+	s.Router.HandleFunc("/get_screenshot/{filename:.+}", s.HandleGetScreenshot).Methods("GET")
+
+	// 扫描会话
+	s.Router.HandleFunc("/sessions", s.HandleListSessions).Methods("GET")
+	s.Router.HandleFunc("/sessions/{id:[0-9]+}", s.HandleGetSession).Methods("GET")
+	s.Router.HandleFunc("/sessions/{id:[0-9]+}", s.HandleDeleteSession).Methods("DELETE")
+
+	// 标签
+	s.Router.HandleFunc("/tags", s.HandleListTags).Methods("GET")
+	s.Router.HandleFunc("/tags/apply", s.HandleApplyTagRules).Methods("POST")
+	s.Router.HandleFunc("/tags/{name}", s.HandleGetTagResults).Methods("GET")
+	s.Router.HandleFunc("/results/{id:[0-9]+}/tags", s.HandleAddResultTags).Methods("POST")
+	s.Router.HandleFunc("/results/{id:[0-9]+}/tags/{name}", s.HandleRemoveResultTag).Methods("DELETE")
+
+	// 页面存档
+	s.Router.HandleFunc("/results/{id:[0-9]+}/archive", s.HandleGetArchive).Methods("GET")
+
+	// 全文搜索
+	s.Router.HandleFunc("/search", s.HandleSearch).Methods("GET")
+
+	// 探测历史和变化检测
+	s.Router.HandleFunc("/history", s.HandleURLHistory).Methods("GET")
+	s.Router.HandleFunc("/changes", s.HandleChanges).Methods("GET")
+
+	// 按内容哈希保存的截图文件
+	s.Router.HandleFunc("/blobs/{hash:[0-9a-f]{64}}", s.HandleGetBlob).Methods("GET")
 
 	// 设置静态文件服务
-	s.Router.PathPrefix("/screenshots/").Handler(http.StripPrefix("/screenshots/", http.FileServer(http.Dir(s.Options.ScreenshotPath))))
+	s.Router.PathPrefix("/screenshots/").Handler(http.StripPrefix("/screenshots/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.store.ServeHTTP(w, r)
+	})))
 
 	// 添加一个不需要认证的路由，显示API信息
 	s.Router.HandleFunc("/", s.HandleRoot).Methods("GET")
@@ -224,6 +255,32 @@ func (s *Server) Run() error {
 	addr := fmt.Sprintf("%s:%d", s.Options.Host, s.Options.Port)
 	log.Info("启动API服务器", "address", addr)
 
+	// 打开截图存储
+	if s.Options.Storage != "" {
+		store, err := storage.OpenBlobStore(s.Options.Storage, s.Options.ScreenshotPath)
+		if err != nil {
+			return fmt.Errorf("打开截图存储失败: %v", err)
+		}
+		s.store = store
+		log.Info("已启用截图存储", "storage", storage.RedactURI(s.Options.Storage))
+	}
+
+	// 打开数据库，用于记录扫描结果和会话
+	if s.Options.Database != nil {
+		db, err := database.NewDB(*s.Options.Database)
+		if err != nil {
+			return fmt.Errorf("打开数据库失败: %v", err)
+		}
+		defer db.Close()
+		s.db = db
+		log.Info("已启用数据库", "dialect", db.Dialect())
+	}
+
+	// 按保留策略定期清理旧的结果和截图文件
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	prune.StartBackground(ctx, s.db, s.store, s.Options.Prune, s.Options.PruneInterval)
+
 	// 输出配置信息
 	active, waiting, max, queue, _ := getConcurrencyStats()
 	log.Info("服务器并发设置",
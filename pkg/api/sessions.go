@@ -0,0 +1,159 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+
+	"github.com/cyberspacesec/go-snir/pkg/database"
+	"github.com/cyberspacesec/go-snir/pkg/log"
+	"github.com/cyberspacesec/go-snir/pkg/runner"
+)
+
+// newSessionWriter 为一次API任务创建数据库写入器并打开扫描会话
+// 未启用数据库时返回nil
+func (s *Server) newSessionWriter(r *http.Request, name, operator string, opts *runner.Options) (*database.DBWriter, error) {
+	if s.db == nil {
+		return nil, nil
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("api %s", time.Now().Format("2006-01-02 15:04:05"))
+	}
+	if operator == "" {
+		operator = r.RemoteAddr
+	}
+
+	writer := database.NewDBWriter(s.db)
+	err := writer.StartSession(&database.ScanSession{
+		Name:        name,
+		CommandLine: fmt.Sprintf("%s %s", r.Method, r.URL.Path),
+		Options:     runner.SessionOptions(opts),
+		Operator:    operator,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// requireDB 检查数据库是否启用，未启用时返回错误响应
+func (s *Server) requireDB(w http.ResponseWriter) bool {
+	if s.db == nil {
+		SendJSONResponse(w, http.StatusServiceUnavailable, APIResponse{
+			Success: false,
+			Error:   "数据库未启用，请使用 --db 参数启动API服务",
+		})
+		return false
+	}
+	return true
+}
+
+// sessionIDFromRequest 从路由参数中解析会话ID
+func sessionIDFromRequest(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id == 0 {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   "无效的会话ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// HandleListSessions 处理列出扫描会话请求
+func (s *Server) HandleListSessions(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDB(w) {
+		return
+	}
+
+	sessions, err := s.db.GetScanSessions()
+	if err != nil {
+		SendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+			Success: false,
+			Error:   "获取扫描会话失败: " + err.Error(),
+		})
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    sessions,
+	})
+}
+
+// HandleGetSession 处理获取扫描会话详情请求
+func (s *Server) HandleGetSession(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDB(w) {
+		return
+	}
+	id, ok := sessionIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	session, err := s.db.GetScanSession(id)
+	if err != nil {
+		sendSessionError(w, "获取扫描会话失败", err)
+		return
+	}
+
+	screenshots, err := s.db.GetSessionScreenshots(id)
+	if err != nil {
+		sendSessionError(w, "获取会话结果失败", err)
+		return
+	}
+
+	results := make([]interface{}, 0, len(screenshots))
+	for _, screenshot := range screenshots {
+		results = append(results, screenshot.ToResult())
+	}
+
+	SendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"session": session,
+			"results": results,
+		},
+	})
+}
+
+// HandleDeleteSession 处理删除扫描会话请求
+func (s *Server) HandleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDB(w) {
+		return
+	}
+	id, ok := sessionIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.db.DeleteScanSession(id); err != nil {
+		sendSessionError(w, "删除扫描会话失败", err)
+		return
+	}
+
+	log.Info("已删除扫描会话", "id", id)
+	SendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "扫描会话已删除",
+	})
+}
+
+// sendSessionError 根据错误类型返回404或500响应
+func sendSessionError(w http.ResponseWriter, msg string, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		status = http.StatusNotFound
+	}
+	SendJSONResponse(w, status, APIResponse{
+		Success: false,
+		Error:   msg + ": " + err.Error(),
+	})
+}
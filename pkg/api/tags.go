@@ -0,0 +1,173 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cyberspacesec/go-snir/pkg/database"
+	"github.com/cyberspacesec/go-snir/pkg/models"
+)
+
+// TagRequest 表示给截图添加标签的请求
+type TagRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// TagRulesRequest 表示批量应用标签规则的请求
+type TagRulesRequest struct {
+	Rules []database.TagRule `json:"rules"`
+}
+
+// resultIDFromRequest 从路由参数中解析结果ID
+func resultIDFromRequest(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id == 0 {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   "无效的结果ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// HandleListTags 处理列出标签请求，返回每个标签关联的截图数量
+func (s *Server) HandleListTags(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDB(w) {
+		return
+	}
+
+	counts, err := s.db.GetTagCounts()
+	if err != nil {
+		SendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+			Success: false,
+			Error:   "获取标签失败: " + err.Error(),
+		})
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    counts,
+	})
+}
+
+// HandleGetTagResults 处理获取带有指定标签的结果请求
+func (s *Server) HandleGetTagResults(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDB(w) {
+		return
+	}
+
+	screenshots, err := s.db.GetScreenshotsByTag(mux.Vars(r)["name"])
+	if err != nil {
+		SendJSONResponse(w, http.StatusInternalServerError, APIResponse{
+			Success: false,
+			Error:   "获取截图失败: " + err.Error(),
+		})
+		return
+	}
+
+	results := make([]*models.Result, 0, len(screenshots))
+	for _, screenshot := range screenshots {
+		results = append(results, screenshot.ToResult())
+	}
+
+	SendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    results,
+	})
+}
+
+// HandleAddResultTags 处理给结果添加标签请求
+func (s *Server) HandleAddResultTags(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDB(w) {
+		return
+	}
+	id, ok := resultIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req TagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Tags) == 0 {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   "请求体中需要包含非空的tags列表",
+		})
+		return
+	}
+
+	for _, tag := range req.Tags {
+		if err := s.db.TagScreenshots([]uint{id}, tag); err != nil {
+			SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+				Success: false,
+				Error:   "添加标签失败: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	SendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "标签已添加",
+	})
+}
+
+// HandleRemoveResultTag 处理移除结果上的标签请求
+func (s *Server) HandleRemoveResultTag(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDB(w) {
+		return
+	}
+	id, ok := resultIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	removed, err := s.db.UntagScreenshots([]uint{id}, mux.Vars(r)["name"])
+	if err != nil || removed == 0 {
+		SendJSONResponse(w, http.StatusNotFound, APIResponse{
+			Success: false,
+			Error:   "结果上没有该标签",
+		})
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "标签已移除",
+	})
+}
+
+// HandleApplyTagRules 处理批量应用标签规则请求
+func (s *Server) HandleApplyTagRules(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDB(w) {
+		return
+	}
+
+	var req TagRulesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Rules) == 0 {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   "请求体中需要包含非空的rules列表",
+		})
+		return
+	}
+
+	counts, err := s.db.ApplyTagRules(req.Rules)
+	if err != nil {
+		SendJSONResponse(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   "应用标签规则失败: " + err.Error(),
+		})
+		return
+	}
+
+	SendJSONResponse(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "标签规则已应用",
+		Data:    counts,
+	})
+}
@@ -4,7 +4,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/cyberspacesec/go-snir/pkg/database"
 	"github.com/cyberspacesec/go-snir/pkg/models"
+	"github.com/cyberspacesec/go-snir/pkg/prune"
+	"github.com/cyberspacesec/go-snir/pkg/storage"
 	"github.com/gorilla/mux"
 )
 
@@ -52,6 +55,23 @@ type CustomCookie struct {
 	HttpOnly bool   `json:"http_only,omitempty"`
 }
 
+// AuthCredential 表示HTTP身份验证凭据，支持Basic、Digest和NTLM
+type AuthCredential struct {
+	Host     string `json:"host,omitempty"` // 主机模式，如 *.corp.example.com，为空时对所有主机生效
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// PDFOptions 表示PDF采集选项
+type PDFOptions struct {
+	Paper           string `json:"paper,omitempty"`            // 纸张大小，如 A4、Letter
+	Landscape       bool   `json:"landscape,omitempty"`        // 是否横向打印
+	Margin          string `json:"margin,omitempty"`           // 页边距，如 1cm 或 10mm,5mm,10mm,5mm
+	PrintBackground bool   `json:"print_background,omitempty"` // 是否打印背景图形
+	HeaderTemplate  string `json:"header_template,omitempty"`  // 页眉HTML模板
+	FooterTemplate  string `json:"footer_template,omitempty"`  // 页脚HTML模板
+}
+
 // BrowserFingerprint 表示浏览器指纹
 type BrowserFingerprint struct {
 	UserAgent       string            `json:"user_agent,omitempty"`
@@ -62,22 +82,26 @@ type BrowserFingerprint struct {
 	WebGLVendor     string            `json:"webgl_vendor,omitempty"`
 	WebGLRenderer   string            `json:"webgl_renderer,omitempty"`
 	CustomHeaders   map[string]string `json:"custom_headers,omitempty"`
+	Headers         []string          `json:"headers,omitempty"` // 格式为 "名称: 值" 或 "主机模式=名称: 值"
 	DisableWebRTC   bool              `json:"disable_webrtc,omitempty"`
 	SpoofScreenSize bool              `json:"spoof_screen_size,omitempty"`
 	ScreenWidth     int               `json:"screen_width,omitempty"`
 	ScreenHeight    int               `json:"screen_height,omitempty"`
+	Stealth         bool              `json:"stealth,omitempty"` // 隐藏无头浏览器的自动化痕迹
 }
 
 // ScreenshotRequest 表示截图请求结构
 type ScreenshotRequest struct {
-	URL              string `json:"url"`
-	HTTPS            bool   `json:"https,omitempty"`
-	HTTP             bool   `json:"http,omitempty"`
-	UserAgent        string `json:"user_agent,omitempty"`
-	Proxy            string `json:"proxy,omitempty"`
-	Timeout          int    `json:"timeout,omitempty"`
-	Delay            int    `json:"delay,omitempty"`
-	IgnoreCertErrors bool   `json:"ignore_cert_errors,omitempty"`
+	URL              string   `json:"url"`
+	HTTPS            bool     `json:"https,omitempty"`
+	HTTP             bool     `json:"http,omitempty"`
+	UserAgent        string   `json:"user_agent,omitempty"`
+	Proxy            string   `json:"proxy,omitempty"`
+	Proxies          []string `json:"proxies,omitempty"`        // 代理池，每次截图按策略选择一个代理，SOCKS代理不能带用户名和密码
+	ProxyStrategy    string   `json:"proxy_strategy,omitempty"` // 代理选择策略：round-robin、random、sticky或failover
+	Timeout          int      `json:"timeout,omitempty"`
+	Delay            int      `json:"delay,omitempty"`
+	IgnoreCertErrors bool     `json:"ignore_cert_errors,omitempty"`
 
 	// 高级浏览器控制
 	JavaScript     string             `json:"javascript,omitempty"`      // 注入的JS代码
@@ -86,13 +110,25 @@ type ScreenshotRequest struct {
 	RunJSAfter     bool               `json:"run_js_after,omitempty"`    // 在页面加载后执行
 	Fingerprint    BrowserFingerprint `json:"fingerprint,omitempty"`     // 浏览器指纹配置
 	Cookies        []CustomCookie     `json:"cookies,omitempty"`         // 自定义Cookie
+	Auth           []AuthCredential   `json:"auth,omitempty"`            // HTTP身份验证凭据
 
 	// 高级元素选择和交互
 	Selector        string              `json:"selector,omitempty"`          // CSS选择器
 	XPath           string              `json:"xpath,omitempty"`             // XPath
 	CaptureFullPage bool                `json:"capture_full_page,omitempty"` // 是否捕获整个页面
+	Capture         string              `json:"capture,omitempty"`           // 采集模式: screenshot、pdf或both
+	PDF             PDFOptions          `json:"pdf,omitempty"`               // PDF选项
+	Archive         bool                `json:"archive,omitempty"`           // 是否保存MHTML页面存档
+	SaveHAR         bool                `json:"save_har,omitempty"`          // 是否保存HAR文件
+	HARBodyLimit    int64               `json:"har_body_limit,omitempty"`    // HAR中保存的单个响应体大小上限（字节）
+	Device          string              `json:"device,omitempty"`            // 模拟的设备名称，如 iphone、pixel、ipad
+	Viewports       []string            `json:"viewports,omitempty"`         // 页面加载后依次截图的视口大小，如 1920x1080
 	Actions         []InteractionAction `json:"actions,omitempty"`           // 交互操作列表
 	Form            Form                `json:"form,omitempty"`              // 表单配置
+
+	// 扫描会话
+	SessionName string `json:"session_name,omitempty"` // 会话名称
+	Operator    string `json:"operator,omitempty"`     // 操作者
 }
 
 // BatchScreenshotRequest 表示批量截图请求结构
@@ -102,11 +138,20 @@ type BatchScreenshotRequest struct {
 	HTTP             bool     `json:"http,omitempty"`
 	UserAgent        string   `json:"user_agent,omitempty"`
 	Proxy            string   `json:"proxy,omitempty"`
+	Proxies          []string `json:"proxies,omitempty"`        // 代理池，每次截图按策略选择一个代理，SOCKS代理不能带用户名和密码
+	ProxyStrategy    string   `json:"proxy_strategy,omitempty"` // 代理选择策略：round-robin、random、sticky或failover
 	Timeout          int      `json:"timeout,omitempty"`
 	Delay            int      `json:"delay,omitempty"`
 	Threads          int      `json:"threads,omitempty"`
 	IgnoreCertErrors bool     `json:"ignore_cert_errors,omitempty"`
 
+	// 速率限制
+	RateLimit       float64 `json:"rate_limit,omitempty"`       // 全局每秒最大请求数
+	HostRateLimit   float64 `json:"host_rate_limit,omitempty"`  // 同一主机每秒最大请求数
+	HostConcurrency int     `json:"host_concurrency,omitempty"` // 同一主机的最大并发数
+	RateLimitBy     string  `json:"rate_limit_by,omitempty"`    // 按主机（host）或注册域名（domain）限速
+	JitterMs        int     `json:"jitter_ms,omitempty"`        // 每次请求前额外等待的随机时间上限（毫秒）
+
 	// 高级浏览器控制
 	JavaScript     string             `json:"javascript,omitempty"`      // 注入的JS代码
 	JavaScriptFile string             `json:"javascript_file,omitempty"` // JS文件路径
@@ -114,13 +159,25 @@ type BatchScreenshotRequest struct {
 	RunJSAfter     bool               `json:"run_js_after,omitempty"`    // 在页面加载后执行
 	Fingerprint    BrowserFingerprint `json:"fingerprint,omitempty"`     // 浏览器指纹配置
 	Cookies        []CustomCookie     `json:"cookies,omitempty"`         // 自定义Cookie
+	Auth           []AuthCredential   `json:"auth,omitempty"`            // HTTP身份验证凭据
 
 	// 高级元素选择和交互
 	Selector        string              `json:"selector,omitempty"`          // CSS选择器
 	XPath           string              `json:"xpath,omitempty"`             // XPath
 	CaptureFullPage bool                `json:"capture_full_page,omitempty"` // 是否捕获整个页面
+	Capture         string              `json:"capture,omitempty"`           // 采集模式: screenshot、pdf或both
+	PDF             PDFOptions          `json:"pdf,omitempty"`               // PDF选项
+	Archive         bool                `json:"archive,omitempty"`           // 是否保存MHTML页面存档
+	SaveHAR         bool                `json:"save_har,omitempty"`          // 是否保存HAR文件
+	HARBodyLimit    int64               `json:"har_body_limit,omitempty"`    // HAR中保存的单个响应体大小上限（字节）
+	Devices         []string            `json:"devices,omitempty"`           // 模拟的设备名称，每个设备截图一次
+	Viewports       []string            `json:"viewports,omitempty"`         // 页面加载后依次截图的视口大小，如 1920x1080
 	Actions         []InteractionAction `json:"actions,omitempty"`           // 交互操作列表
 	Form            Form                `json:"form,omitempty"`              // 表单配置
+
+	// 扫描会话
+	SessionName string `json:"session_name,omitempty"` // 会话名称
+	Operator    string `json:"operator,omitempty"`     // 操作者
 }
 
 // Options 包含API服务的配置选项
@@ -128,22 +185,28 @@ type Options struct {
 	Port                  int
 	Host                  string
 	ScreenshotPath        string
-	APIKey                string   // API密钥，用于鉴权
-	EnableBlacklist       bool     // 是否启用URL黑名单
-	DefaultBlacklist      bool     // 是否使用默认黑名单
-	BlacklistPatterns     []string // 自定义黑名单规则
-	BlacklistFile         string   // 黑名单文件路径
-	MaxConcurrentRequests int      // 最大并发请求数
-	RequestQueueSize      int      // 请求队列大小
+	Storage               string            // 截图存储URI，为空时使用本地截图目录
+	APIKey                string            // API密钥，用于鉴权
+	EnableBlacklist       bool              // 是否启用URL黑名单
+	DefaultBlacklist      bool              // 是否使用默认黑名单
+	BlacklistPatterns     []string          // 自定义黑名单规则
+	BlacklistFile         string            // 黑名单文件路径
+	MaxConcurrentRequests int               // 最大并发请求数
+	RequestQueueSize      int               // 请求队列大小
+	Database              *database.Options // 数据库选项，为nil时不记录结果和会话
+	Prune                 prune.Policy      // 保留策略
+	PruneInterval         time.Duration     // 定期清理的间隔，为0时不启用
 }
 
 // Server 表示API服务器
 type Server struct {
 	Options          Options
 	Router           *mux.Router
-	concurrencyLimit interface{}   // 并发限制器
-	shutdownCh       chan struct{} // 关闭通道
-	serverStartTime  time.Time     // 服务器启动时间
+	concurrencyLimit interface{}        // 并发限制器
+	shutdownCh       chan struct{}      // 关闭通道
+	serverStartTime  time.Time          // 服务器启动时间
+	db               *database.DB       // 数据库，未启用时为nil
+	store            *storage.BlobStore // 截图存储
 }
 
 // MemoryWriter 内存写入器实现 runner.Writer 接口
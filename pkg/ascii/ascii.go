@@ -42,6 +42,11 @@ func Logo() string {
 	return coloredLogo + info
 }
 
+// Version 返回版本号
+func Version() string {
+	return version
+}
+
 // VersionInfo 返回详细的版本信息
 func VersionInfo() string {
 	cyan := color.New(color.FgCyan).SprintFunc()
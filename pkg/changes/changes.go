@@ -0,0 +1,249 @@
+// Package changes 比较同一URL在不同扫描中的结果，检测状态码、标题、证书、技术栈、HTML和截图的变化
+package changes
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyberspacesec/go-snir/pkg/database"
+	"github.com/cyberspacesec/go-snir/pkg/models"
+	"github.com/cyberspacesec/go-snir/pkg/phash"
+)
+
+// 变化的字段
+const (
+	FieldAvailability = "availability" // 探测成功或失败
+	FieldStatus       = "status"       // 响应状态码
+	FieldTitle        = "title"        // 页面标题
+	FieldTLS          = "tls"          // TLS证书
+	FieldTechnologies = "technologies" // 技术栈
+	FieldHTML         = "html"         // HTML内容
+	FieldScreenshot   = "screenshot"   // 截图感知哈希
+)
+
+// Options 变化检测选项
+type Options struct {
+	PHashThreshold int // 感知哈希汉明距离超过该值时认为截图发生变化
+}
+
+// Change 表示一个字段的变化
+type Change struct {
+	Field  string `json:"field"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Event 表示同一URL相邻两次探测之间的变化
+type Event struct {
+	URL              string    `json:"url"`
+	PreviousID       uint      `json:"previous_id"`
+	CurrentID        uint      `json:"current_id"`
+	PreviousProbedAt time.Time `json:"previous_probed_at"`
+	ProbedAt         time.Time `json:"probed_at"`
+	Changes          []Change  `json:"changes"`
+}
+
+// Compare 比较同一URL的两次探测结果
+func Compare(prev, curr *models.Result, opts Options) []Change {
+	var changes []Change
+
+	if prev.Failed != curr.Failed {
+		changes = append(changes, Change{
+			Field: FieldAvailability,
+			Old:   availability(prev),
+			New:   availability(curr),
+		})
+	}
+	// 失败的探测没有可比较的内容
+	if prev.Failed || curr.Failed {
+		return changes
+	}
+
+	if prev.ResponseCode != curr.ResponseCode {
+		changes = append(changes, Change{
+			Field: FieldStatus,
+			Old:   strconv.Itoa(prev.ResponseCode),
+			New:   strconv.Itoa(curr.ResponseCode),
+		})
+	}
+
+	if prev.Title != curr.Title {
+		changes = append(changes, Change{Field: FieldTitle, Old: prev.Title, New: curr.Title})
+	}
+
+	if oldTLS, newTLS := tlsIdentity(prev.TLS), tlsIdentity(curr.TLS); oldTLS != newTLS {
+		changes = append(changes, Change{
+			Field:  FieldTLS,
+			Old:    oldTLS,
+			New:    newTLS,
+			Detail: tlsDetail(prev.TLS, curr.TLS),
+		})
+	}
+
+	if oldTech, newTech := technologies(prev), technologies(curr); oldTech != newTech {
+		changes = append(changes, Change{Field: FieldTechnologies, Old: oldTech, New: newTech})
+	}
+
+	// 未保存HTML的结果不比较HTML
+	if prev.HTML != "" && curr.HTML != "" {
+		if oldHash, newHash := contentHash(prev.HTML), contentHash(curr.HTML); oldHash != newHash {
+			changes = append(changes, Change{
+				Field:  FieldHTML,
+				Old:    oldHash,
+				New:    newHash,
+				Detail: fmt.Sprintf("%d -> %d bytes", len(prev.HTML), len(curr.HTML)),
+			})
+		}
+	}
+
+	if prev.PerceptionHash != "" && curr.PerceptionHash != "" {
+		threshold := opts.PHashThreshold
+		if threshold <= 0 {
+			threshold = phash.DefaultThreshold
+		}
+		if distance, err := phash.Distance(prev.PerceptionHash, curr.PerceptionHash); err == nil && distance > threshold {
+			changes = append(changes, Change{
+				Field:  FieldScreenshot,
+				Old:    prev.PerceptionHash,
+				New:    curr.PerceptionHash,
+				Detail: fmt.Sprintf("distance=%d", distance),
+			})
+		}
+	}
+
+	return changes
+}
+
+// History 比较按探测时间排序的同一URL的结果，返回探测时间不早于since的变化
+func History(results []*models.Result, since time.Time, opts Options) []Event {
+	var events []Event
+	for i := 1; i < len(results); i++ {
+		prev, curr := results[i-1], results[i]
+		if curr.ProbedAt.Before(since) {
+			continue
+		}
+		if changes := Compare(prev, curr, opts); len(changes) > 0 {
+			events = append(events, Event{
+				URL:              curr.URL,
+				PreviousID:       prev.ID,
+				CurrentID:        curr.ID,
+				PreviousProbedAt: prev.ProbedAt,
+				ProbedAt:         curr.ProbedAt,
+				Changes:          changes,
+			})
+		}
+	}
+	return events
+}
+
+// URLHistory 从数据库读取URL的所有探测结果，以及相邻两次探测之间的变化
+func URLHistory(db *database.DB, url string, opts Options) ([]*models.Result, []Event, error) {
+	screenshots, err := db.GetURLHistory(url)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]*models.Result, 0, len(screenshots))
+	for _, screenshot := range screenshots {
+		results = append(results, screenshot.ToResult())
+	}
+	return results, History(results, time.Time{}, opts), nil
+}
+
+// Since 检测所有在since之后被探测过的URL的变化，按探测时间排序
+func Since(db *database.DB, since time.Time, opts Options) ([]Event, error) {
+	urls, err := db.GetURLsProbedSince(since)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, url := range urls {
+		_, history, err := URLHistory(db, url, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range history {
+			if !event.ProbedAt.Before(since) {
+				events = append(events, event)
+			}
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].ProbedAt.Before(events[j].ProbedAt)
+	})
+	return events, nil
+}
+
+// ParseSince 解析时间范围，支持时长（如 24h、30m、7d）、日期（2006-01-02）和RFC3339时间
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("时间范围不能为空")
+	}
+
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("无效的时间范围: %s (支持 24h、7d、2006-01-02 或 RFC3339 格式)", value)
+}
+
+// availability 返回探测结果的可用状态描述
+func availability(r *models.Result) string {
+	if r.Failed {
+		return "failed: " + r.FailedReason
+	}
+	return "ok"
+}
+
+// tlsIdentity 返回用于比较的证书标识，优先使用SHA1指纹
+func tlsIdentity(tls models.TLS) string {
+	if tls.FingerprintSHA1 != "" {
+		return tls.FingerprintSHA1
+	}
+	if tls.Subject == "" && tls.Issuer == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s / %s / %s", tls.Subject, tls.Issuer, tls.NotAfter.Format("2006-01-02"))
+}
+
+// tlsDetail 描述证书主题和颁发者的变化
+func tlsDetail(prev, curr models.TLS) string {
+	return fmt.Sprintf("%s (%s) -> %s (%s)", prev.Subject, prev.Issuer, curr.Subject, curr.Issuer)
+}
+
+// technologies 返回排序后的技术栈列表
+func technologies(r *models.Result) string {
+	names := make([]string, 0, len(r.Technologies))
+	for _, t := range r.Technologies {
+		name := t.Name
+		if t.Version != "" {
+			name += " " + t.Version
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// contentHash 返回内容的SHA256哈希前16位
+func contentHash(content string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(content)))[:16]
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/cyberspacesec/go-snir/pkg/models"
+)
+
+// createBlobs 创建截图文件表，并为截图记录添加内容哈希列
+func createBlobs(tx *gorm.DB) error {
+	type blob struct {
+		Hash      string `gorm:"primaryKey;size:64"`
+		Ext       string `gorm:"size:16"`
+		CreatedAt time.Time
+	}
+	type screenshot struct {
+		BlobHash string `gorm:"index;size:64"`
+	}
+	if err := createTables(tx, &blob{}); err != nil {
+		return err
+	}
+	return addColumns(tx, &screenshot{})
+}
+
+// addPDFColumns 为截图记录添加PDF文件位置和内容哈希列
+func addPDFColumns(tx *gorm.DB) error {
+	type screenshot struct {
+		PDF     string
+		PDFHash string `gorm:"index;size:64"`
+	}
+	return addColumns(tx, &screenshot{})
+}
+
+// addArchiveColumns 为截图记录添加页面存档位置、内容哈希和采集时间列
+func addArchiveColumns(tx *gorm.DB) error {
+	type screenshot struct {
+		Archive     string
+		ArchiveHash string `gorm:"index;size:64"`
+		ArchivedAt  *time.Time
+	}
+	return addColumns(tx, &screenshot{})
+}
+
+// addHARColumns 为截图记录添加HAR文件位置和内容哈希列
+func addHARColumns(tx *gorm.DB) error {
+	type screenshot struct {
+		HAR     string
+		HARHash string `gorm:"index;size:64"`
+	}
+	return addColumns(tx, &screenshot{})
+}
+
+// blobHashColumns 是截图记录中引用文件内容哈希的列
+var blobHashColumns = []string{"blob_hash", "pdf_hash", "archive_hash", "har_hash"}
+
+// saveBlobs 记录截图引用的文件，包括截图、PDF、页面存档、HAR文件和多视口截图，已存在的文件不重复记录
+func saveBlobs(tx *gorm.DB, screenshots []*Screenshot) error {
+	seen := make(map[string]bool)
+	var blobs []Blob
+	add := func(hash, location string) {
+		if hash == "" || seen[hash] {
+			return
+		}
+		seen[hash] = true
+		blobs = append(blobs, Blob{
+			Hash: hash,
+			Ext:  strings.ToLower(filepath.Ext(location)),
+		})
+	}
+	for _, s := range screenshots {
+		add(s.BlobHash, s.Filename)
+		add(s.PDFHash, s.PDF)
+		add(s.ArchiveHash, s.Archive)
+		add(s.HARHash, s.HAR)
+		for _, v := range s.Viewports {
+			add(v.Hash, v.Screenshot)
+		}
+	}
+	if len(blobs) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&blobs).Error
+}
+
+// removeUnusedBlobs 删除不再被任何截图记录引用的文件记录
+func removeUnusedBlobs(tx *gorm.DB) error {
+	session := tx.Session(&gorm.Session{NewDB: true})
+	query := tx
+	for _, column := range blobHashColumns {
+		used := session.Model(&Screenshot{}).Select(column).Where(column + " <> ''")
+		query = query.Where("hash NOT IN (?)", used)
+	}
+	query = query.Where("hash NOT IN (?)", session.Model(&models.Viewport{}).Select("hash").Where("hash <> ''"))
+	return query.Delete(&Blob{}).Error
+}
+
+// GetBlob 获取截图文件记录
+func (d *DB) GetBlob(hash string) (*Blob, error) {
+	var blob Blob
+	if err := d.db.Where("hash = ?", hash).First(&blob).Error; err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// GetBlobScreenshots 获取以截图、PDF、页面存档、HAR文件或多视口截图引用指定文件的所有截图记录，按探测时间从新到旧排序
+func (d *DB) GetBlobScreenshots(hash string) ([]*Screenshot, error) {
+	var screenshots []*Screenshot
+	viewports := d.db.Model(&models.Viewport{}).Select("result_id").Where("hash = ?", hash)
+	err := d.db.Where("blob_hash = ? OR pdf_hash = ? OR archive_hash = ? OR har_hash = ? OR id IN (?)", hash, hash, hash, hash, viewports).Order("probed_at DESC, id DESC").Find(&screenshots).Error
+	return screenshots, err
+}
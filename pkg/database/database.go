@@ -3,9 +3,10 @@ package database
 import (
 	"fmt"
 	"path/filepath"
+	"time"
 
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 
 	"github.com/cyberspacesec/go-snir/pkg/islazy"
@@ -15,57 +16,104 @@ import (
 
 // Options 数据库选项
 type Options struct {
-	Path string // 数据库文件路径
+	Path            string        // SQLite数据库文件路径，URI为空时使用
+	URI             string        // 数据库连接URI，根据协议选择sqlite、postgres或mysql
+	MaxOpenConns    int           // 最大打开连接数，0表示不限制
+	MaxIdleConns    int           // 最大空闲连接数，0表示使用默认值
+	ConnMaxLifetime time.Duration // 连接最大存活时间，0表示不限制
+	ConnMaxIdleTime time.Duration // 连接最大空闲时间，0表示不限制
+	Debug           bool          // 是否输出SQL日志
 }
 
 // DB 数据库管理器
 type DB struct {
 	db      *gorm.DB
 	options Options
+	dialect string
 }
 
 // NewDB 创建新的数据库管理器
 func NewDB(options Options) (*DB, error) {
-	// 确保目录存在
-	dir := filepath.Dir(options.Path)
-	if _, err := islazy.CreateDir(dir); err != nil {
-		return nil, fmt.Errorf("创建数据库目录失败: %v", err)
+	dialect, dsn := DialectSQLite, options.Path
+	if options.URI != "" {
+		var err error
+		if dialect, dsn, err = ParseURI(options.URI); err != nil {
+			return nil, err
+		}
+	}
+
+	// SQLite需要确保数据库文件所在目录存在
+	if dialect == DialectSQLite {
+		dir := filepath.Dir(dsn)
+		if _, err := islazy.CreateDir(dir); err != nil {
+			return nil, fmt.Errorf("创建数据库目录失败: %v", err)
+		}
 	}
 
 	// 设置日志级别
 	logLevel := logger.Silent
-	if log.IsDebugEnabled() {
+	if options.Debug || log.IsDebugEnabled() {
 		logLevel = logger.Info
 	}
 
+	dialector, err := openDialector(dialect, dsn)
+	if err != nil {
+		return nil, err
+	}
+
 	// 连接数据库
-	db, err := gorm.Open(sqlite.Open(options.Path), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logLevel),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("连接数据库失败: %v", err)
 	}
 
+	// 设置连接池
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接池失败: %v", err)
+	}
+	if options.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(options.MaxOpenConns)
+	}
+	if options.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(options.MaxIdleConns)
+	}
+	if options.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(options.ConnMaxLifetime)
+	}
+	if options.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(options.ConnMaxIdleTime)
+	}
+
 	// 初始化数据库
 	if err := initDB(db); err != nil {
+		sqlDB.Close()
 		return nil, fmt.Errorf("初始化数据库失败: %v", err)
 	}
 
 	return &DB{
 		db:      db,
 		options: options,
+		dialect: dialect,
 	}, nil
 }
 
 // initDB 初始化数据库
 func initDB(db *gorm.DB) error {
-	// 自动迁移表结构
-	return db.AutoMigrate(
-		&Screenshot{},
-		&ScanSession{},
-		&Tag{},
-		&ScreenshotTag{},
-	)
+	// 使用ScreenshotTag作为截图与标签的关联表
+	if err := db.SetupJoinTable(&Screenshot{}, "Tags", &ScreenshotTag{}); err != nil {
+		return err
+	}
+
+	// 执行版本化迁移
+	return migrate(db)
+}
+
+// Dialect 返回数据库类型
+func (d *DB) Dialect() string {
+	return d.dialect
 }
 
 // Close 关闭数据库连接
@@ -77,16 +125,44 @@ func (d *DB) Close() error {
 	return sqlDB.Close()
 }
 
-// SaveResult 保存扫描结果
+// withRelations 预加载截图记录的所有关联数据
+func withRelations(tx *gorm.DB) *gorm.DB {
+	return tx.Preload(clause.Associations)
+}
+
+// SaveResult 在一个事务中保存扫描结果及其所有关联数据
 func (d *DB) SaveResult(result *models.Result) error {
+	return d.SaveSessionResult(result, 0)
+}
+
+// SaveSessionResult 保存扫描结果并关联到指定的扫描会话，sessionID为0时不关联
+func (d *DB) SaveSessionResult(result *models.Result, sessionID uint) error {
 	screenshot := &Screenshot{}
 	screenshot.FromResult(result)
+	if sessionID != 0 {
+		screenshot.SessionID = &sessionID
+	}
 
-	return d.db.Create(screenshot).Error
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(screenshot).Error; err != nil {
+			return err
+		}
+		if err := indexScreenshots(tx, []*Screenshot{screenshot}); err != nil {
+			return err
+		}
+		if err := saveBlobs(tx, []*Screenshot{screenshot}); err != nil {
+			return err
+		}
+		return tagScreenshot(tx, screenshot.ID, result.Tags)
+	})
 }
 
-// SaveResults 批量保存扫描结果
+// SaveResults 在一个事务中批量保存扫描结果及其所有关联数据
 func (d *DB) SaveResults(results []*models.Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+
 	screenshots := make([]*Screenshot, 0, len(results))
 	for _, result := range results {
 		screenshot := &Screenshot{}
@@ -94,46 +170,74 @@ func (d *DB) SaveResults(results []*models.Result) error {
 		screenshots = append(screenshots, screenshot)
 	}
 
-	return d.db.Create(&screenshots).Error
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&screenshots).Error; err != nil {
+			return err
+		}
+		if err := indexScreenshots(tx, screenshots); err != nil {
+			return err
+		}
+		if err := saveBlobs(tx, screenshots); err != nil {
+			return err
+		}
+		for i, screenshot := range screenshots {
+			if err := tagScreenshot(tx, screenshot.ID, results[i].Tags); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // GetScreenshot 获取截图信息
 func (d *DB) GetScreenshot(id uint) (*Screenshot, error) {
 	var screenshot Screenshot
-	if err := d.db.First(&screenshot, id).Error; err != nil {
+	if err := withRelations(d.db).First(&screenshot, id).Error; err != nil {
 		return nil, err
 	}
 	return &screenshot, nil
 }
 
-// GetScreenshotByURL 通过URL获取截图信息
+// GetScreenshotByURL 获取URL最近一次的截图信息
 func (d *DB) GetScreenshotByURL(url string) (*Screenshot, error) {
 	var screenshot Screenshot
-	if err := d.db.Where("url = ?", url).First(&screenshot).Error; err != nil {
+	if err := withRelations(d.db).Where("url_hash = ? AND url = ?", urlHash(url), url).Order("probed_at DESC, id DESC").First(&screenshot).Error; err != nil {
 		return nil, err
 	}
 	return &screenshot, nil
 }
 
+// GetURLHistory 获取URL的所有截图记录，按探测时间排序
+func (d *DB) GetURLHistory(url string) ([]*Screenshot, error) {
+	var screenshots []*Screenshot
+	if err := withRelations(d.db).Where("url_hash = ? AND url = ?", urlHash(url), url).Order("probed_at, id").Find(&screenshots).Error; err != nil {
+		return nil, err
+	}
+	return screenshots, nil
+}
+
+// GetURLsProbedSince 获取在指定时间之后被探测过的URL
+func (d *DB) GetURLsProbedSince(since time.Time) ([]string, error) {
+	var urls []string
+	err := d.db.Model(&Screenshot{}).Where("probed_at >= ?", since).Distinct("url").Order("url").Pluck("url", &urls).Error
+	return urls, err
+}
+
 // GetAllScreenshots 获取所有截图
 func (d *DB) GetAllScreenshots() ([]*Screenshot, error) {
 	var screenshots []*Screenshot
-	if err := d.db.Find(&screenshots).Error; err != nil {
+	if err := withRelations(d.db).Order("id").Find(&screenshots).Error; err != nil {
 		return nil, err
 	}
 	return screenshots, nil
 }
 
 // CreateScanSession 创建扫描会话
-func (d *DB) CreateScanSession(name string) (*ScanSession, error) {
-	session := &ScanSession{
-		Name:      name,
-		StartedAt: models.Now(),
+func (d *DB) CreateScanSession(session *ScanSession) error {
+	if session.StartedAt.IsZero() {
+		session.StartedAt = models.Now()
 	}
-	if err := d.db.Create(session).Error; err != nil {
-		return nil, err
-	}
-	return session, nil
+	return d.db.Create(session).Error
 }
 
 // EndScanSession 结束扫描会话
@@ -141,47 +245,200 @@ func (d *DB) EndScanSession(id uint) error {
 	return d.db.Model(&ScanSession{}).Where("id = ?", id).Update("ended_at", models.Now()).Error
 }
 
-// AddTag 添加标签
-func (d *DB) AddTag(name string) (*Tag, error) {
-	tag := &Tag{
-		Name: name,
+// SetScanSessionTargetCount 更新扫描会话的目标数量
+func (d *DB) SetScanSessionTargetCount(id uint, count int) error {
+	return d.db.Model(&ScanSession{}).Where("id = ?", id).Update("target_count", count).Error
+}
+
+// sessionStats 表示会话结果统计
+type sessionStats struct {
+	SessionID   uint
+	ResultCount int
+	FailedCount int
+}
+
+// fillSessionStats 为会话填充结果数量和失败数量
+func (d *DB) fillSessionStats(sessions []*ScanSession) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(sessions))
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+
+	var stats []sessionStats
+	err := d.db.Model(&Screenshot{}).
+		Select("session_id, COUNT(*) AS result_count, SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed_count").
+		Where("session_id IN ?", ids).
+		Group("session_id").
+		Scan(&stats).Error
+	if err != nil {
+		return err
 	}
 
-	// 先查看标签是否已存在
-	var existingTag Tag
-	if err := d.db.Where("name = ?", name).First(&existingTag).Error; err == nil {
-		return &existingTag, nil
+	byID := make(map[uint]sessionStats, len(stats))
+	for _, stat := range stats {
+		byID[stat.SessionID] = stat
 	}
+	for _, session := range sessions {
+		session.ResultCount = byID[session.ID].ResultCount
+		session.FailedCount = byID[session.ID].FailedCount
+	}
+	return nil
+}
 
-	// 创建新标签
-	if err := d.db.Create(tag).Error; err != nil {
+// GetScanSessions 获取所有扫描会话，按开始时间倒序排列
+func (d *DB) GetScanSessions() ([]*ScanSession, error) {
+	var sessions []*ScanSession
+	if err := d.db.Order("started_at DESC").Find(&sessions).Error; err != nil {
 		return nil, err
 	}
-	return tag, nil
+	if err := d.fillSessionStats(sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
 }
 
-// AddTagToScreenshot 给截图添加标签
-func (d *DB) AddTagToScreenshot(screenshotID uint, tagID uint) error {
-	relation := &ScreenshotTag{
-		ScreenshotID: screenshotID,
-		TagID:        tagID,
+// GetScanSession 获取指定的扫描会话
+func (d *DB) GetScanSession(id uint) (*ScanSession, error) {
+	var session ScanSession
+	if err := d.db.First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	if err := d.fillSessionStats([]*ScanSession{&session}); err != nil {
+		return nil, err
 	}
-	return d.db.Create(relation).Error
+	return &session, nil
 }
 
-// GetAllTags 获取所有标签
-func (d *DB) GetAllTags() ([]*Tag, error) {
-	var tags []*Tag
-	if err := d.db.Find(&tags).Error; err != nil {
+// GetSessionScreenshots 获取扫描会话中的所有截图记录
+func (d *DB) GetSessionScreenshots(id uint) ([]*Screenshot, error) {
+	var screenshots []*Screenshot
+	if err := withRelations(d.db).Where("session_id = ?", id).Order("id").Find(&screenshots).Error; err != nil {
 		return nil, err
 	}
-	return tags, nil
+	return screenshots, nil
+}
+
+// DeleteScanSession 删除扫描会话及其所有截图记录
+func (d *DB) DeleteScanSession(id uint) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Model(&Screenshot{}).Where("session_id = ?", id).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if err := deleteScreenshots(tx, ids); err != nil {
+			return err
+		}
+		result := tx.Delete(&ScanSession{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// deleteBatchSize 每批删除的记录数，避免超出SQL参数数量限制
+const deleteBatchSize = 500
+
+// GetRetentionEntries 获取所有截图记录的基本信息，用于保留策略计算，按探测时间从新到旧排序
+func (d *DB) GetRetentionEntries() ([]*Screenshot, error) {
+	var screenshots []*Screenshot
+	err := d.db.Select("id", "url", "probed_at", "filename", "screenshot", "pdf", "archive", "har").
+		Preload("Viewports", func(tx *gorm.DB) *gorm.DB {
+			return tx.Select("id", "result_id", "screenshot")
+		}).
+		Order("probed_at DESC, id DESC").Find(&screenshots).Error
+	return screenshots, err
+}
+
+// DeleteScreenshots 在一个事务中删除截图记录及其所有关联数据
+func (d *DB) DeleteScreenshots(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		return deleteScreenshots(tx, ids)
+	})
+}
+
+// GetReferencedFiles 返回仍被截图记录引用的文件路径
+func (d *DB) GetReferencedFiles(paths []string) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	for start := 0; start < len(paths); start += deleteBatchSize {
+		end := min(start+deleteBatchSize, len(paths))
+		batch := paths[start:end]
+
+		var rows []Screenshot
+		err := d.db.Select("filename", "screenshot", "pdf", "archive", "har").
+			Where("filename IN ? OR screenshot IN ? OR pdf IN ? OR archive IN ? OR har IN ?", batch, batch, batch, batch, batch).Find(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			referenced[row.Filename] = true
+			referenced[row.Screenshot] = true
+			referenced[row.PDF] = true
+			referenced[row.Archive] = true
+			referenced[row.HAR] = true
+		}
+
+		var viewports []models.Viewport
+		if err := d.db.Select("screenshot").Where("screenshot IN ?", batch).Find(&viewports).Error; err != nil {
+			return nil, err
+		}
+		for _, v := range viewports {
+			referenced[v.Screenshot] = true
+		}
+	}
+	return referenced, nil
+}
+
+// deleteScreenshots 删除截图记录及其所有关联数据
+func deleteScreenshots(tx *gorm.DB, ids []uint) error {
+	for start := 0; start < len(ids); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+
+		related := []interface{}{
+			&models.TLS{},
+			&models.Technology{},
+			&models.Header{},
+			&models.NetworkLog{},
+			&models.ConsoleLog{},
+			&models.Cookie{},
+			&models.Viewport{},
+		}
+		for _, model := range related {
+			if err := tx.Where("result_id IN ?", batch).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("screenshot_id IN ?", batch).Delete(&ScreenshotTag{}).Error; err != nil {
+			return err
+		}
+		if err := removeFromSearchIndex(tx, batch); err != nil {
+			return err
+		}
+		if err := tx.Delete(&Screenshot{}, batch).Error; err != nil {
+			return err
+		}
+	}
+	return removeUnusedBlobs(tx)
 }
 
 // ExportResults 导出扫描结果
 func (d *DB) ExportResults() ([]*models.Result, error) {
-	var screenshots []*Screenshot
-	if err := d.db.Find(&screenshots).Error; err != nil {
+	screenshots, err := d.GetAllScreenshots()
+	if err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,421 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/cyberspacesec/go-snir/pkg/log"
+)
+
+// migrationLockID 是迁移时使用的数据库锁标识，防止多个实例同时执行迁移
+const migrationLockID = 727300
+
+// SchemaMigration 记录已执行的数据库迁移
+type SchemaMigration struct {
+	Version   uint      `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// migration 表示一个版本化的数据库迁移
+type migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+// migrations 是按版本排列的迁移列表，新的迁移只能追加到末尾，已发布的迁移不能修改
+// 迁移使用定义在迁移内部的表结构快照，不引用当前的模型，模型以后的修改不会改变已发布迁移的结果
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up:      createInitialSchema,
+	},
+	{
+		Version: 2,
+		Name:    "full-text search index",
+		Up:      createSearchIndex,
+	},
+	{
+		Version: 3,
+		Name:    "content-addressed screenshot blobs",
+		Up:      createBlobs,
+	},
+	{
+		Version: 4,
+		Name:    "pdf captures",
+		Up:      addPDFColumns,
+	},
+	{
+		Version: 5,
+		Name:    "mhtml page archives",
+		Up:      addArchiveColumns,
+	},
+	{
+		Version: 6,
+		Name:    "har files",
+		Up:      addHARColumns,
+	},
+	{
+		Version: 7,
+		Name:    "device profiles",
+		Up: func(tx *gorm.DB) error {
+			type screenshot struct {
+				Device string `gorm:"index;size:64"`
+			}
+			return addColumns(tx, &screenshot{})
+		},
+	},
+	{
+		Version: 8,
+		Name:    "viewport screenshots",
+		Up: func(tx *gorm.DB) error {
+			type viewport struct {
+				ID             uint `gorm:"primarykey"`
+				ResultID       uint
+				Width          int64
+				Height         int64
+				Screenshot     string
+				Hash           string `gorm:"index;size:64"`
+				PerceptionHash string
+			}
+			// 截图记录的快照只用于声明外键，screenshots表已存在
+			type screenshot struct {
+				ID        uint       `gorm:"primaryKey"`
+				Viewports []viewport `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+			}
+			return createTables(tx, &screenshot{}, &viewport{})
+		},
+	},
+	{
+		Version: 9,
+		Name:    "result proxy",
+		Up: func(tx *gorm.DB) error {
+			type screenshot struct {
+				Proxy string `gorm:"index;size:255"`
+			}
+			return addColumns(tx, &screenshot{})
+		},
+	},
+	{
+		Version: 10,
+		Name:    "error classes",
+		Up: func(tx *gorm.DB) error {
+			type screenshot struct {
+				ErrorClass string `gorm:"index;size:32"`
+				Attempts   int
+			}
+			return addColumns(tx, &screenshot{})
+		},
+	},
+	{
+		Version: 11,
+		Name:    "result source",
+		Up: func(tx *gorm.DB) error {
+			type screenshot struct {
+				Source string `gorm:"index;size:32"`
+			}
+			return addColumns(tx, &screenshot{})
+		},
+	},
+	{
+		Version: 12,
+		Name:    "url hashes",
+		Up:      addURLHashes,
+	},
+}
+
+// createInitialSchema 创建第一个版本的表结构
+// 旧版本通过AutoMigrate创建的数据库也会执行此迁移，已存在的表只补充缺失的列和索引
+// 建立索引的字符串列需要指定长度，MySQL中没有长度的字符串列为longtext，不能直接建立索引
+func createInitialSchema(tx *gorm.DB) error {
+	type tls struct {
+		ID              uint `gorm:"primarykey"`
+		ResultID        uint
+		Version         string
+		CipherSuite     string
+		Issuer          string
+		Subject         string
+		NotBefore       time.Time
+		NotAfter        time.Time
+		SANs            string
+		FingerprintSHA1 string
+	}
+	type technology struct {
+		ID       uint `gorm:"primarykey"`
+		ResultID uint
+		Name     string
+		Version  string
+	}
+	type header struct {
+		ID       uint `gorm:"primarykey"`
+		ResultID uint
+		Name     string
+		Value    string
+	}
+	type networkLog struct {
+		ID          uint `gorm:"primarykey"`
+		ResultID    uint
+		Type        int
+		URL         string
+		Method      string
+		StatusCode  int
+		ContentType string
+		Body        string
+	}
+	type consoleLog struct {
+		ID       uint `gorm:"primarykey"`
+		ResultID uint
+		Level    string
+		Message  string
+	}
+	type cookie struct {
+		ID       uint `gorm:"primarykey"`
+		ResultID uint
+		Name     string
+		Value    string
+		Domain   string
+		Path     string
+	}
+	type tag struct {
+		ID        uint   `gorm:"primaryKey"`
+		Name      string `gorm:"uniqueIndex;size:255"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+	type screenshot struct {
+		ID                    uint `gorm:"primaryKey"`
+		URL                   string
+		Title                 string
+		Path                  string
+		Filename              string
+		Screenshot            string
+		IsPDF                 bool
+		FinalURL              string
+		ResponseCode          int
+		ResponseReason        string
+		Protocol              string
+		ContentLength         int64
+		HTML                  string
+		PerceptionHash        string `gorm:"index;size:64"`
+		PerceptionHashGroupId uint   `gorm:"index"`
+		ProbedAt              time.Time
+		Failed                bool
+		FailedReason          string
+		SessionID             *uint `gorm:"index"`
+		CreatedAt             time.Time
+		UpdatedAt             time.Time
+
+		TLS          *tls         `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+		Technologies []technology `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+		Headers      []header     `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+		Network      []networkLog `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+		Console      []consoleLog `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+		Cookies      []cookie     `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+	}
+	type scanSession struct {
+		ID          uint `gorm:"primaryKey"`
+		Name        string
+		CommandLine string
+		Options     string
+		Operator    string
+		TargetCount int
+		StartedAt   time.Time
+		EndedAt     time.Time
+		CreatedAt   time.Time
+		UpdatedAt   time.Time
+	}
+	type screenshotTag struct {
+		ScreenshotID uint `gorm:"primaryKey"`
+		TagID        uint `gorm:"primaryKey"`
+		Screenshot   screenshot
+		Tag          tag
+	}
+
+	// 先创建被引用的表，再创建带外键的表
+	return createTables(tx,
+		&tag{},
+		&screenshot{},
+		&tls{},
+		&technology{},
+		&header{},
+		&networkLog{},
+		&consoleLog{},
+		&cookie{},
+		&scanSession{},
+		&screenshotTag{},
+	)
+}
+
+// addURLHashes 为截图记录添加URL哈希列并为已有的记录计算哈希
+// URL可能超过MySQL索引的长度限制，按URL查询时使用哈希列的索引，旧版本数据库中URL列上的索引被删除
+func addURLHashes(tx *gorm.DB) error {
+	type screenshot struct {
+		ID      uint `gorm:"primaryKey"`
+		URL     string
+		URLHash string `gorm:"index;size:64"`
+	}
+	if err := addColumns(tx, &screenshot{}); err != nil {
+		return err
+	}
+
+	migrator := tx.Migrator()
+	if migrator.HasIndex(&screenshot{}, "idx_screenshots_url") {
+		if err := migrator.DropIndex(&screenshot{}, "idx_screenshots_url"); err != nil {
+			return fmt.Errorf("删除索引 idx_screenshots_url 失败: %v", err)
+		}
+	}
+
+	var screenshots []screenshot
+	return tx.Select("id", "url").Where("url_hash IS NULL OR url_hash = ''").
+		FindInBatches(&screenshots, deleteBatchSize, func(batch *gorm.DB, _ int) error {
+			for _, s := range screenshots {
+				if err := tx.Model(&screenshot{}).Where("id = ?", s.ID).Update("url_hash", urlHash(s.URL)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// createTables 按表结构快照依次创建表，表已存在时只添加缺失的列和索引
+// 外键约束只在创建表时添加，已存在的表不修改约束
+func createTables(tx *gorm.DB, tables ...interface{}) error {
+	migrator := tx.Migrator()
+	for _, table := range tables {
+		if migrator.HasTable(table) {
+			if err := addColumns(tx, table); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := migrator.CreateTable(table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumns 为已存在的表添加表结构快照中缺失的列和索引，快照的类型名决定表名
+func addColumns(tx *gorm.DB, table interface{}) error {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(table); err != nil {
+		return err
+	}
+
+	migrator := tx.Migrator()
+	for _, column := range stmt.Schema.DBNames {
+		if migrator.HasColumn(table, column) {
+			continue
+		}
+		if err := migrator.AddColumn(table, column); err != nil {
+			return fmt.Errorf("添加列 %s.%s 失败: %v", stmt.Schema.Table, column, err)
+		}
+	}
+
+	names := make([]string, 0)
+	for name := range stmt.Schema.ParseIndexes() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if migrator.HasIndex(table, name) {
+			continue
+		}
+		if err := migrator.CreateIndex(table, name); err != nil {
+			return fmt.Errorf("创建索引 %s 失败: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// migrate 执行所有未执行的迁移
+func migrate(db *gorm.DB) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		// 在同一个连接上开启新会话，避免多次调用之间共享语句状态
+		conn = conn.Session(&gorm.Session{})
+
+		unlock, err := lockMigrations(conn)
+		if err != nil {
+			return fmt.Errorf("获取迁移锁失败: %v", err)
+		}
+		defer unlock()
+
+		if err := conn.AutoMigrate(&SchemaMigration{}); err != nil {
+			return err
+		}
+
+		var applied []SchemaMigration
+		if err := conn.Find(&applied).Error; err != nil {
+			return err
+		}
+		done := make(map[uint]bool, len(applied))
+		for _, m := range applied {
+			done[m.Version] = true
+		}
+
+		pending := make([]migration, 0, len(migrations))
+		for _, m := range migrations {
+			if !done[m.Version] {
+				pending = append(pending, m)
+			}
+		}
+		sort.Slice(pending, func(i, j int) bool { return pending[i].Version < pending[j].Version })
+
+		for _, m := range pending {
+			log.Debug("执行数据库迁移", "version", m.Version, "name", m.Name)
+			// MySQL的DDL语句不支持事务，迁移本身必须是可重复执行的
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := m.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{
+					Version:   m.Version,
+					Name:      m.Name,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("执行迁移 %d (%s) 失败: %v", m.Version, m.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// lockMigrations 获取数据库级别的迁移锁，返回释放锁的函数
+// SQLite在写事务中会锁定整个数据库文件，不需要额外加锁
+func lockMigrations(conn *gorm.DB) (func(), error) {
+	switch conn.Dialector.Name() {
+	case DialectPostgres:
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error; err != nil {
+			return nil, err
+		}
+		return func() { conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockID) }, nil
+
+	case DialectMySQL:
+		name := fmt.Sprintf("snir_migrations_%d", migrationLockID)
+		var locked int
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", name, 60).Scan(&locked).Error; err != nil {
+			return nil, err
+		}
+		if locked != 1 {
+			return nil, fmt.Errorf("等待迁移锁超时")
+		}
+		return func() { conn.Exec("SELECT RELEASE_LOCK(?)", name) }, nil
+
+	default:
+		return func() {}, nil
+	}
+}
+
+// SchemaVersion 返回数据库当前的迁移版本
+func (d *DB) SchemaVersion() (uint, error) {
+	var version uint
+	err := d.db.Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
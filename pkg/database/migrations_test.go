@@ -0,0 +1,459 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/cyberspacesec/go-snir/pkg/models"
+)
+
+// openTestDB 在临时目录中创建SQLite数据库并执行迁移
+func openTestDB(t *testing.T, path string) *DB {
+	t.Helper()
+	db, err := NewDB(Options{Path: path})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// latestVersion 返回最后一个迁移的版本
+func latestVersion() uint {
+	return migrations[len(migrations)-1].Version
+}
+
+// assertSchema 检查迁移后的表结构包含当前模型的所有列和索引
+// 模型添加了字段却没有对应的迁移时失败
+func assertSchema(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	migrator := db.Migrator()
+	for _, model := range []interface{}{
+		&Screenshot{},
+		&models.TLS{},
+		&models.Technology{},
+		&models.Header{},
+		&models.NetworkLog{},
+		&models.ConsoleLog{},
+		&models.Cookie{},
+		&models.Viewport{},
+		&ScanSession{},
+		&Tag{},
+		&ScreenshotTag{},
+		&Blob{},
+		&SchemaMigration{},
+	} {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatalf("解析模型失败: %v", err)
+		}
+		if !migrator.HasTable(model) {
+			t.Errorf("缺少表 %s", stmt.Schema.Table)
+			continue
+		}
+		for _, column := range stmt.Schema.DBNames {
+			if !migrator.HasColumn(model, column) {
+				t.Errorf("表 %s 缺少列 %s", stmt.Schema.Table, column)
+			}
+		}
+		for name := range stmt.Schema.ParseIndexes() {
+			if !migrator.HasIndex(model, name) {
+				t.Errorf("表 %s 缺少索引 %s", stmt.Schema.Table, name)
+			}
+		}
+	}
+}
+
+// assertMigrations 检查每个迁移都记录在schema_migrations表中
+func assertMigrations(t *testing.T, db *DB) {
+	t.Helper()
+	var applied []SchemaMigration
+	if err := db.db.Order("version").Find(&applied).Error; err != nil {
+		t.Fatalf("读取迁移记录失败: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("迁移记录数量为 %d，应为 %d", len(applied), len(migrations))
+	}
+	for i, m := range migrations {
+		if applied[i].Version != m.Version || applied[i].Name != m.Name {
+			t.Errorf("第 %d 条迁移记录为 %d (%s)，应为 %d (%s)",
+				i, applied[i].Version, applied[i].Name, m.Version, m.Name)
+		}
+		if applied[i].AppliedAt.IsZero() {
+			t.Errorf("迁移 %d 没有记录执行时间", m.Version)
+		}
+	}
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("读取数据库版本失败: %v", err)
+	}
+	if version != latestVersion() {
+		t.Errorf("数据库版本为 %d，应为 %d", version, latestVersion())
+	}
+}
+
+// testResult 返回包含所有关联数据的扫描结果
+func testResult(url string) *models.Result {
+	return &models.Result{
+		URL:            url,
+		Title:          "Example",
+		Device:         "iphone-15",
+		Proxy:          "http://127.0.0.1:8080",
+		Source:         "wordlist",
+		ScreenshotHash: "aa",
+		ErrorClass:     "http_5xx",
+		Attempts:       2,
+		ResponseCode:   503,
+		ProbedAt:       time.Now(),
+		TLS:            models.TLS{Version: "TLS 1.3"},
+		Technologies:   []models.Technology{{Name: "nginx"}},
+		Headers:        []models.Header{{Name: "Server", Value: "nginx"}},
+		Network:        []models.NetworkLog{{URL: url, StatusCode: 503}},
+		Console:        []models.ConsoleLog{{Level: "error", Message: "boom"}},
+		Cookies:        []models.Cookie{{Name: "session", Value: "1"}},
+		Viewports:      []models.Viewport{{Width: 390, Height: 844, Hash: "bb"}},
+		Tags:           []string{"login"},
+	}
+}
+
+// assertRoundTrip 保存并读取一个扫描结果，检查后续迁移添加的列和表可以使用
+func assertRoundTrip(t *testing.T, db *DB, url string) {
+	t.Helper()
+	if err := db.SaveResult(testResult(url)); err != nil {
+		t.Fatalf("保存结果失败: %v", err)
+	}
+	screenshot, err := db.GetScreenshotByURL(url)
+	if err != nil {
+		t.Fatalf("读取结果失败: %v", err)
+	}
+	if screenshot.Device != "iphone-15" || screenshot.Source != "wordlist" || screenshot.Attempts != 2 {
+		t.Errorf("读取的结果与保存的不一致: %+v", screenshot)
+	}
+	if len(screenshot.Viewports) != 1 || len(screenshot.Headers) != 1 || len(screenshot.Tags) != 1 {
+		t.Errorf("读取的关联数据与保存的不一致: viewports=%d headers=%d tags=%d",
+			len(screenshot.Viewports), len(screenshot.Headers), len(screenshot.Tags))
+	}
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "snir.db"))
+
+	assertSchema(t, db.db)
+	assertMigrations(t, db)
+	assertRoundTrip(t, db, "https://example.com")
+}
+
+func TestMigrateBaselineDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snir.db")
+
+	// 版本化迁移之前的数据库由AutoMigrate创建，没有schema_migrations表
+	type screenshot struct {
+		ID             uint   `gorm:"primaryKey"`
+		URL            string `gorm:"index"`
+		Title          string
+		Filename       string
+		FinalURL       string
+		ResponseCode   int
+		ResponseReason string
+		Protocol       string
+		ContentLength  int64
+		HTML           string
+		ProbedAt       time.Time
+		Failed         bool
+		FailedReason   string
+		CreatedAt      time.Time
+		UpdatedAt      time.Time
+	}
+	type scanSession struct {
+		ID        uint `gorm:"primaryKey"`
+		Name      string
+		StartedAt time.Time
+		EndedAt   time.Time
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+	type tag struct {
+		ID        uint   `gorm:"primaryKey"`
+		Name      string `gorm:"uniqueIndex"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+	type screenshotTag struct {
+		ScreenshotID uint `gorm:"primaryKey"`
+		TagID        uint `gorm:"primaryKey"`
+	}
+
+	baseline, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	if err := baseline.AutoMigrate(&screenshot{}, &scanSession{}, &tag{}, &screenshotTag{}); err != nil {
+		t.Fatalf("创建旧版本表结构失败: %v", err)
+	}
+	old := screenshot{URL: "https://old.example.com", Title: "Old", ResponseCode: 200, ProbedAt: time.Now()}
+	if err := baseline.Create(&old).Error; err != nil {
+		t.Fatalf("写入旧版本记录失败: %v", err)
+	}
+	sqlDB, _ := baseline.DB()
+	sqlDB.Close()
+
+	db := openTestDB(t, path)
+
+	assertSchema(t, db.db)
+	assertMigrations(t, db)
+
+	// 旧记录在迁移后保留，并能通过全文搜索找到
+	kept, err := db.GetScreenshotByURL(old.URL)
+	if err != nil {
+		t.Fatalf("读取旧记录失败: %v", err)
+	}
+	if kept.ID != old.ID || kept.Title != "Old" || kept.ResponseCode != 200 {
+		t.Errorf("旧记录在迁移后被修改: %+v", kept)
+	}
+	found, err := db.Search("old.example.com", 10)
+	if err != nil {
+		t.Fatalf("搜索失败: %v", err)
+	}
+	if len(found) != 1 || found[0].ID != old.ID {
+		t.Errorf("搜索索引中没有旧记录: %+v", found)
+	}
+
+	if db.db.Migrator().HasIndex("screenshots", "idx_screenshots_url") {
+		t.Error("迁移后URL列上的索引没有删除")
+	}
+
+	assertRoundTrip(t, db, "https://example.com")
+}
+
+func TestMigrateTwice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snir.db")
+
+	db, err := NewDB(Options{Path: path})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+
+	// MySQL的DDL语句不能回滚，失败后重新执行的迁移会遇到已经创建的表、列和索引
+	for _, m := range migrations {
+		if err := db.db.Transaction(m.Up); err != nil {
+			t.Errorf("重复执行迁移 %d (%s) 失败: %v", m.Version, m.Name, err)
+		}
+	}
+	if err := db.SaveResult(testResult("https://example.com")); err != nil {
+		t.Fatalf("保存结果失败: %v", err)
+	}
+	db.Close()
+
+	// 再次打开时不再执行已记录的迁移，已有数据不受影响
+	db = openTestDB(t, path)
+	assertSchema(t, db.db)
+	assertMigrations(t, db)
+	if _, err := db.GetScreenshotByURL("https://example.com"); err != nil {
+		t.Errorf("再次打开数据库后读取结果失败: %v", err)
+	}
+}
+
+// ddlLog 记录迁移执行的语句
+type ddlLog struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+// ddlConn 是不连接数据库的驱动连接，用于检查MySQL和PostgreSQL迁移生成的DDL
+// 执行的语句都被记录下来，查询表、列和索引是否存在时总是返回0，迁移按新数据库执行
+type ddlConn struct{ log *ddlLog }
+
+func (c ddlConn) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c ddlConn) Driver() driver.Driver                        { return c }
+func (c ddlConn) Open(string) (driver.Conn, error)             { return c, nil }
+func (c ddlConn) Close() error                                 { return nil }
+func (c ddlConn) Begin() (driver.Tx, error)                    { return c, nil }
+func (c ddlConn) Commit() error                                { return nil }
+func (c ddlConn) Rollback() error                              { return nil }
+
+func (c ddlConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("不支持预处理语句: %s", query)
+}
+
+func (c ddlConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.log.mu.Lock()
+	defer c.log.mu.Unlock()
+	c.log.statements = append(c.log.statements, query)
+	return ddlResult{}, nil
+}
+
+func (c ddlConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	switch q := strings.ToLower(query); {
+	case strings.Contains(q, "count("):
+		return &ddlRows{value: int64(0)}, nil
+	case strings.Contains(q, "get_lock("):
+		return &ddlRows{value: int64(1)}, nil
+	case strings.Contains(q, "version()"):
+		return &ddlRows{value: "8.0.36"}, nil
+	case strings.Contains(q, "database()"):
+		return &ddlRows{value: "snir"}, nil
+	default:
+		return &ddlRows{}, nil
+	}
+}
+
+// ddlResult 是执行语句的结果
+type ddlResult struct{}
+
+func (ddlResult) LastInsertId() (int64, error) { return 0, nil }
+func (ddlResult) RowsAffected() (int64, error) { return 0, nil }
+
+// ddlRows 是查询结果，value 不为空时返回只有一列的一行，否则返回空结果
+type ddlRows struct {
+	value driver.Value
+	done  bool
+}
+
+func (r *ddlRows) Columns() []string {
+	if r.value == nil {
+		return nil
+	}
+	return []string{"value"}
+}
+
+func (r *ddlRows) Close() error { return nil }
+
+func (r *ddlRows) Next(dest []driver.Value) error {
+	if r.value == nil || r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+var (
+	createTablePattern  = regexp.MustCompile("(?s)^CREATE TABLE [`\"](\\w+)[`\"] \\((.*)\\)")
+	addColumnPattern    = regexp.MustCompile("^ALTER TABLE [`\"](\\w+)[`\"] ADD [`\"](\\w+)[`\"] (\\S+)")
+	createIndexPattern  = regexp.MustCompile("^CREATE (?:UNIQUE )?INDEX (?:IF NOT EXISTS )?[`\"](\\w+)[`\"] ON [`\"](\\w+)[`\"] ?\\((.*)\\)")
+	tableIndexPattern   = regexp.MustCompile("^(?:UNIQUE )?INDEX [`\"](\\w+)[`\"] \\((.*)\\)")
+	quotedNamePattern   = regexp.MustCompile("[`\"](\\w+)[`\"]")
+	varcharPattern      = regexp.MustCompile(`^(?:varchar|character varying)\((\d+)\)`)
+	maxIndexedStringLen = 768 // utf8mb4下InnoDB的索引键最长3072字节
+)
+
+// splitColumns 按顶层的逗号拆分建表语句中的列和约束定义
+func splitColumns(body string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range body {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(body[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(body[start:]))
+}
+
+// parseDDL 从DDL语句中得到每一列的类型和建立了索引的列，键为 表名.列名
+func parseDDL(t *testing.T, statements []string) (columns map[string]string, indexed map[string]string) {
+	t.Helper()
+	columns, indexed = make(map[string]string), make(map[string]string)
+	addIndex := func(table, name, list string) {
+		for _, m := range quotedNamePattern.FindAllStringSubmatch(list, -1) {
+			indexed[table+"."+m[1]] = name
+		}
+	}
+
+	for _, statement := range statements {
+		if m := createTablePattern.FindStringSubmatch(statement); m != nil {
+			for _, part := range splitColumns(m[2]) {
+				if c := quotedNamePattern.FindStringSubmatchIndex(part); c != nil && c[0] == 0 {
+					fields := strings.Fields(part[c[1]:])
+					if len(fields) > 0 {
+						columns[m[1]+"."+part[c[2]:c[3]]] = strings.ToLower(fields[0])
+					}
+				} else if idx := tableIndexPattern.FindStringSubmatch(part); idx != nil {
+					addIndex(m[1], idx[1], idx[2])
+				}
+			}
+		} else if m := addColumnPattern.FindStringSubmatch(statement); m != nil {
+			columns[m[1]+"."+m[2]] = strings.ToLower(m[3])
+		} else if m := createIndexPattern.FindStringSubmatch(statement); m != nil {
+			addIndex(m[2], m[1], m[3])
+		}
+	}
+	return columns, indexed
+}
+
+func TestMigrationDDL(t *testing.T) {
+	for _, tt := range []struct {
+		dialect   string
+		dialector func(conn gorm.ConnPool) gorm.Dialector
+	}{
+		{DialectMySQL, func(conn gorm.ConnPool) gorm.Dialector { return mysql.New(mysql.Config{Conn: conn}) }},
+		{DialectPostgres, func(conn gorm.ConnPool) gorm.Dialector { return postgres.New(postgres.Config{Conn: conn}) }},
+	} {
+		t.Run(tt.dialect, func(t *testing.T) {
+			ddl := &ddlLog{}
+			db, err := gorm.Open(tt.dialector(sql.OpenDB(ddlConn{ddl})), &gorm.Config{
+				Logger:               logger.Default.LogMode(logger.Silent),
+				DisableAutomaticPing: true,
+			})
+			if err != nil {
+				t.Fatalf("打开数据库失败: %v", err)
+			}
+			if err := migrate(db); err != nil {
+				t.Fatalf("执行迁移失败: %v", err)
+			}
+
+			columns, indexed := parseDDL(t, ddl.statements)
+			for _, column := range []string{"tags.name", "screenshots.url_hash", "screenshots.perception_hash", "screenshots.proxy"} {
+				if _, ok := indexed[column]; !ok {
+					t.Errorf("列 %s 没有索引", column)
+				}
+			}
+			if name, ok := indexed["screenshots.url"]; ok {
+				t.Errorf("URL列上有索引 %s，URL的长度可能超过索引键的长度限制", name)
+			}
+			if typ := columns["screenshots.url"]; !strings.Contains(typ, "text") {
+				t.Errorf("URL列的类型为 %s，应为不限长度的文本", typ)
+			}
+
+			for column, name := range indexed {
+				typ, ok := columns[column]
+				if !ok {
+					t.Errorf("索引 %s 的列 %s 不存在", name, column)
+					continue
+				}
+				if strings.Contains(typ, "text") {
+					t.Errorf("索引 %s 的列 %s 类型为 %s，建立索引的字符串列应指定长度", name, column, typ)
+				}
+				if m := varcharPattern.FindStringSubmatch(typ); m != nil {
+					if size, _ := strconv.Atoi(m[1]); size > maxIndexedStringLen {
+						t.Errorf("索引 %s 的列 %s 类型为 %s，超过索引键的长度限制", name, column, typ)
+					}
+				}
+			}
+		})
+	}
+}
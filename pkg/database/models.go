@@ -1,6 +1,8 @@
 package database
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"github.com/cyberspacesec/go-snir/pkg/models"
@@ -8,71 +10,265 @@ import (
 
 // Screenshot 表示数据库中的截图记录
 type Screenshot struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	URL            string    `gorm:"index" json:"url"`
-	Title          string    `json:"title"`
-	Filename       string    `json:"filename"`
-	FinalURL       string    `json:"final_url"`
-	ResponseCode   int       `json:"response_code"`
-	ResponseReason string    `json:"response_reason"`
-	Protocol       string    `json:"protocol"`
-	ContentLength  int64     `json:"content_length"`
-	HTML           string    `json:"html"`
-	ProbedAt       time.Time `json:"probed_at"`
-	Failed         bool      `json:"failed"`
-	FailedReason   string    `json:"failed_reason"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID                    uint      `gorm:"primaryKey" json:"id"`
+	URL                   string    `json:"url"`
+	URLHash               string    `gorm:"index;size:64" json:"-"` // URL可能超过索引的长度限制，按URL查询时使用哈希列的索引
+	Device                string    `gorm:"index;size:64" json:"device,omitempty"`
+	Proxy                 string    `gorm:"index;size:255" json:"proxy,omitempty"`
+	Source                string    `gorm:"index;size:32" json:"source,omitempty"`
+	Title                 string    `json:"title"`
+	Path                  string    `json:"path"`
+	Filename              string    `json:"filename"`
+	Screenshot            string    `json:"screenshot"`
+	BlobHash              string    `gorm:"index;size:64" json:"blob_hash,omitempty"`
+	IsPDF                 bool      `json:"is_pdf"`
+	PDF                   string    `json:"pdf,omitempty"`
+	PDFHash               string    `gorm:"index;size:64" json:"pdf_hash,omitempty"`
+	FinalURL              string    `json:"final_url"`
+	ResponseCode          int       `json:"response_code"`
+	ResponseReason        string    `json:"response_reason"`
+	Protocol              string    `json:"protocol"`
+	ContentLength         int64     `json:"content_length"`
+	HTML                  string    `json:"html"`
+	PerceptionHash        string    `gorm:"index;size:64" json:"perception_hash"`
+	PerceptionHashGroupId uint      `gorm:"index" json:"perception_hash_group_id"`
+	ProbedAt              time.Time `json:"probed_at"`
+	Failed                bool      `json:"failed"`
+	FailedReason          string    `json:"failed_reason"`
+	ErrorClass            string    `gorm:"index;size:32" json:"error_class,omitempty"`
+	Attempts              int       `json:"attempts,omitempty"`
+	SessionID             *uint     `gorm:"index" json:"session_id,omitempty"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+
+	// MHTML页面存档及其内容哈希和采集时间
+	Archive     string     `json:"archive,omitempty"`
+	ArchiveHash string     `gorm:"index;size:64" json:"archive_hash,omitempty"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+
+	// 页面网络活动的HAR文件及其内容哈希
+	HAR     string `json:"har,omitempty"`
+	HARHash string `gorm:"index;size:64" json:"har_hash,omitempty"`
+
+	// 关联数据，通过ResultID外键关联到截图记录
+	TLS          *models.TLS         `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE" json:"tls,omitempty"`
+	Technologies []models.Technology `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE" json:"technologies,omitempty"`
+	Headers      []models.Header     `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE" json:"headers,omitempty"`
+	Network      []models.NetworkLog `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE" json:"network,omitempty"`
+	Console      []models.ConsoleLog `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE" json:"console,omitempty"`
+	Cookies      []models.Cookie     `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE" json:"cookies,omitempty"`
+	Viewports    []models.Viewport   `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE" json:"viewports,omitempty"`
+
+	// 标签，通过screenshot_tags表关联
+	Tags []Tag `gorm:"many2many:screenshot_tags;joinForeignKey:ScreenshotID;joinReferences:TagID" json:"tags,omitempty"`
+}
+
+// urlHash 返回URL的SHA-256哈希，用于按URL查询截图记录
+func urlHash(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
 }
 
-// FromResult 从扫描结果创建数据库记录
+// FromResult 从扫描结果创建数据库记录，包括所有关联数据
+// 保存时总是创建新记录，不使用 result.ID 和关联数据的主键，与 ToResult 互为逆操作（记录ID除外）
+// 标签需要单独保存，见 DB.SaveSessionResult
 func (s *Screenshot) FromResult(result *models.Result) {
 	s.URL = result.URL
+	s.URLHash = urlHash(result.URL)
+	s.Device = result.Device
+	s.Proxy = result.Proxy
+	s.Source = result.Source
 	s.Title = result.Title
+	s.Path = result.Path
 	s.Filename = result.Filename
+	s.Screenshot = result.Screenshot
+	s.BlobHash = result.ScreenshotHash
+	s.IsPDF = result.IsPDF
+	s.PDF = result.PDF
+	s.PDFHash = result.PDFHash
+	s.Archive = result.Archive
+	s.ArchiveHash = result.ArchiveHash
+	s.ArchivedAt = result.ArchivedAt
+	s.HAR = result.HAR
+	s.HARHash = result.HARHash
 	s.FinalURL = result.FinalURL
 	s.ResponseCode = result.ResponseCode
 	s.ResponseReason = result.ResponseReason
 	s.Protocol = result.Protocol
 	s.ContentLength = result.ContentLength
 	s.HTML = result.HTML
+	s.PerceptionHash = result.PerceptionHash
+	s.PerceptionHashGroupId = result.PerceptionHashGroupId
 	s.ProbedAt = result.ProbedAt
 	s.Failed = result.Failed
 	s.FailedReason = result.FailedReason
+	s.ErrorClass = result.ErrorClass
+	s.Attempts = result.Attempts
+
+	// 关联数据的主键和外键由数据库重新分配
+	tls := result.TLS
+	tls.ID, tls.ResultID = 0, 0
+	if tls != (models.TLS{}) {
+		s.TLS = &tls
+	}
+
+	s.Technologies = make([]models.Technology, 0, len(result.Technologies))
+	for _, t := range result.Technologies {
+		t.ID, t.ResultID = 0, 0
+		s.Technologies = append(s.Technologies, t)
+	}
+
+	s.Headers = make([]models.Header, 0, len(result.Headers))
+	for _, h := range result.Headers {
+		h.ID, h.ResultID = 0, 0
+		s.Headers = append(s.Headers, h)
+	}
+
+	s.Network = make([]models.NetworkLog, 0, len(result.Network))
+	for _, n := range result.Network {
+		n.ID, n.ResultID = 0, 0
+		s.Network = append(s.Network, n)
+	}
+
+	s.Console = make([]models.ConsoleLog, 0, len(result.Console))
+	for _, c := range result.Console {
+		c.ID, c.ResultID = 0, 0
+		s.Console = append(s.Console, c)
+	}
+
+	s.Cookies = make([]models.Cookie, 0, len(result.Cookies))
+	for _, c := range result.Cookies {
+		c.ID, c.ResultID = 0, 0
+		s.Cookies = append(s.Cookies, c)
+	}
+
+	s.Viewports = make([]models.Viewport, 0, len(result.Viewports))
+	for _, v := range result.Viewports {
+		v.ID, v.ResultID = 0, 0
+		s.Viewports = append(s.Viewports, v)
+	}
+}
+
+// Files 返回记录引用的截图、PDF、页面存档、HAR文件和多视口截图位置
+func (s *Screenshot) Files() []string {
+	var files []string
+	if s.Filename != "" {
+		files = append(files, s.Filename)
+	} else if s.Screenshot != "" {
+		files = append(files, s.Screenshot)
+	}
+	if s.PDF != "" {
+		files = append(files, s.PDF)
+	}
+	if s.Archive != "" {
+		files = append(files, s.Archive)
+	}
+	if s.HAR != "" {
+		files = append(files, s.HAR)
+	}
+	for _, v := range s.Viewports {
+		if v.Screenshot != "" {
+			files = append(files, v.Screenshot)
+		}
+	}
+	return files
 }
 
-// ToResult 转换为扫描结果
+// ToResult 转换为扫描结果，关联数据需要预先加载
+// result.ID 为记录ID，用于在标签和变化检测等命令中引用记录；关联数据的主键和外键是存储细节，在结果中为0
 func (s *Screenshot) ToResult() *models.Result {
-	return &models.Result{
-		URL:            s.URL,
-		Title:          s.Title,
-		Filename:       s.Filename,
-		FinalURL:       s.FinalURL,
-		ResponseCode:   s.ResponseCode,
-		ResponseReason: s.ResponseReason,
-		Protocol:       s.Protocol,
-		ContentLength:  s.ContentLength,
-		HTML:           s.HTML,
-		ProbedAt:       s.ProbedAt,
-		Failed:         s.Failed,
-		FailedReason:   s.FailedReason,
+	result := &models.Result{
+		ID:                    s.ID,
+		URL:                   s.URL,
+		Title:                 s.Title,
+		Path:                  s.Path,
+		Filename:              s.Filename,
+		Screenshot:            s.Screenshot,
+		ScreenshotHash:        s.BlobHash,
+		IsPDF:                 s.IsPDF,
+		PDF:                   s.PDF,
+		PDFHash:               s.PDFHash,
+		Archive:               s.Archive,
+		ArchiveHash:           s.ArchiveHash,
+		ArchivedAt:            s.ArchivedAt,
+		HAR:                   s.HAR,
+		HARHash:               s.HARHash,
+		Device:                s.Device,
+		Proxy:                 s.Proxy,
+		Source:                s.Source,
+		FinalURL:              s.FinalURL,
+		ResponseCode:          s.ResponseCode,
+		ResponseReason:        s.ResponseReason,
+		Protocol:              s.Protocol,
+		ContentLength:         s.ContentLength,
+		HTML:                  s.HTML,
+		PerceptionHash:        s.PerceptionHash,
+		PerceptionHashGroupId: s.PerceptionHashGroupId,
+		ProbedAt:              s.ProbedAt,
+		Failed:                s.Failed,
+		FailedReason:          s.FailedReason,
+		ErrorClass:            s.ErrorClass,
+		Attempts:              s.Attempts,
+	}
+
+	// 没有关联数据时保持为nil，与原始结果保持一致
+	if s.TLS != nil {
+		result.TLS = *s.TLS
+		result.TLS.ID, result.TLS.ResultID = 0, 0
+	}
+	for _, t := range s.Technologies {
+		t.ID, t.ResultID = 0, 0
+		result.Technologies = append(result.Technologies, t)
+	}
+	for _, h := range s.Headers {
+		h.ID, h.ResultID = 0, 0
+		result.Headers = append(result.Headers, h)
+	}
+	for _, n := range s.Network {
+		n.ID, n.ResultID = 0, 0
+		result.Network = append(result.Network, n)
+	}
+	for _, c := range s.Console {
+		c.ID, c.ResultID = 0, 0
+		result.Console = append(result.Console, c)
+	}
+	for _, c := range s.Cookies {
+		c.ID, c.ResultID = 0, 0
+		result.Cookies = append(result.Cookies, c)
+	}
+	for _, v := range s.Viewports {
+		v.ID, v.ResultID = 0, 0
+		result.Viewports = append(result.Viewports, v)
 	}
+	for _, tag := range s.Tags {
+		result.Tags = append(result.Tags, tag.Name)
+	}
+
+	return result
 }
 
 // ScanSession 表示一次扫描会话
 type ScanSession struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `json:"name"`
-	StartedAt time.Time `json:"started_at"`
-	EndedAt   time.Time `json:"ended_at"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `json:"name"`
+	CommandLine string    `json:"command_line"` // 启动会话的命令行或API请求
+	Options     string    `json:"options"`      // 扫描选项（JSON）
+	Operator    string    `json:"operator"`     // 操作者
+	TargetCount int       `json:"target_count"` // 目标数量
+	StartedAt   time.Time `json:"started_at"`
+	EndedAt     time.Time `json:"ended_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// 统计信息，查询时计算
+	ResultCount int `gorm:"-" json:"result_count"`
+	FailedCount int `gorm:"-" json:"failed_count"`
 }
 
 // Tag 表示截图的标签
 type Tag struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"uniqueIndex" json:"name"`
+	Name      string    `gorm:"uniqueIndex;size:255" json:"name"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -82,3 +278,16 @@ type ScreenshotTag struct {
 	ScreenshotID uint `gorm:"primaryKey" json:"screenshot_id"`
 	TagID        uint `gorm:"primaryKey" json:"tag_id"`
 }
+
+// TagCount 表示标签及其关联的截图数量
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Blob 表示按内容哈希保存的截图文件，内容相同的多条截图记录引用同一个Blob
+type Blob struct {
+	Hash      string    `gorm:"primaryKey;size:64" json:"hash"`
+	Ext       string    `gorm:"size:16" json:"ext"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResultRoundTrip(t *testing.T) {
+	want := testResult("https://example.com")
+	want.Tags = nil // 标签单独保存，不在截图记录中
+
+	screenshot := &Screenshot{}
+	screenshot.FromResult(want)
+	if got := screenshot.ToResult(); !reflect.DeepEqual(got, want) {
+		t.Errorf("转换后的结果与原始结果不一致\n得到: %+v\n应为: %+v", got, want)
+	}
+}
+
+func TestSavedResultRoundTrip(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "snir.db"))
+	want := testResult("https://example.com")
+	if err := db.SaveResult(want); err != nil {
+		t.Fatalf("保存结果失败: %v", err)
+	}
+	screenshot, err := db.GetScreenshotByURL(want.URL)
+	if err != nil {
+		t.Fatalf("读取结果失败: %v", err)
+	}
+
+	got := screenshot.ToResult()
+	// 结果中只有记录ID，关联数据的主键和外键不出现在结果中
+	if got.ID != screenshot.ID || got.ID == 0 {
+		t.Errorf("结果ID为 %d，应为记录ID %d", got.ID, screenshot.ID)
+	}
+	if got.TLS.ID != 0 || got.TLS.ResultID != 0 || got.Headers[0].ID != 0 || got.Headers[0].ResultID != 0 ||
+		got.Network[0].ID != 0 || got.Console[0].ID != 0 || got.Cookies[0].ID != 0 ||
+		got.Technologies[0].ID != 0 || got.Viewports[0].ID != 0 {
+		t.Errorf("结果中包含关联数据的主键: %+v", got)
+	}
+
+	// 再次保存读取的结果时创建新记录，不使用原记录的ID
+	if err := db.SaveResult(got); err != nil {
+		t.Fatalf("再次保存结果失败: %v", err)
+	}
+	history, err := db.GetURLHistory(want.URL)
+	if err != nil {
+		t.Fatalf("读取结果历史失败: %v", err)
+	}
+	if len(history) != 2 || history[0].ID == history[1].ID {
+		t.Fatalf("再次保存后有 %d 条记录，应为2条", len(history))
+	}
+	saved := history[0].ToResult()
+	if history[0].ID == got.ID {
+		saved = history[1].ToResult()
+	}
+
+	if !saved.ProbedAt.Equal(got.ProbedAt) {
+		t.Errorf("探测时间为 %s，应为 %s", saved.ProbedAt, got.ProbedAt)
+	}
+	saved.ID, saved.ProbedAt = got.ID, got.ProbedAt
+	if !reflect.DeepEqual(saved, got) {
+		t.Errorf("再次保存的结果与原结果不一致\n得到: %+v\n应为: %+v", saved, got)
+	}
+}
@@ -0,0 +1,483 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// searchTable 是全文搜索索引表名
+const searchTable = "screenshot_search"
+
+// defaultSearchLimit 是未指定数量时返回的最大搜索结果数
+const defaultSearchLimit = 50
+
+// 搜索支持的字段前缀
+const (
+	SearchFieldTitle  = "title"  // 页面标题
+	SearchFieldURL    = "url"    // 扫描URL和最终URL
+	SearchFieldHeader = "header" // 响应头，header:server=nginx 匹配名称和值
+	SearchFieldBody   = "body"   // HTML内容
+	SearchFieldTech   = "tech"   // 技术栈名称
+)
+
+// searchColumns 是索引表的列，顺序与FTS表定义一致
+var searchColumns = []string{"title", "url", "headers", "body", "tech"}
+
+// searchWeights 是各列在排序时的权重，顺序与searchColumns一致
+var searchWeights = []float64{10, 5, 1, 1, 3}
+
+// SearchTerm 表示搜索语句中的一个条件
+type SearchTerm struct {
+	Field string // 字段前缀，为空时搜索所有字段
+	Name  string // 响应头名称，仅用于header字段
+	Value string // 搜索内容，以*结尾时按前缀匹配
+}
+
+// SearchResult 表示一条搜索结果
+type SearchResult struct {
+	ID           uint      `json:"id"`
+	URL          string    `json:"url"`
+	FinalURL     string    `json:"final_url"`
+	Title        string    `json:"title"`
+	ResponseCode int       `json:"response_code"`
+	Filename     string    `json:"file_name"`
+	ProbedAt     time.Time `json:"probed_at"`
+	SessionID    *uint     `json:"session_id,omitempty"`
+	Rank         float64   `json:"rank"`
+}
+
+// searchDocument 是非SQLite数据库中的搜索索引记录，SQLite使用同名的FTS虚拟表
+type searchDocument struct {
+	ScreenshotID uint `gorm:"primaryKey;autoIncrement:false"`
+	Title        string
+	URL          string
+	Headers      string
+	Body         string
+	Tech         string
+}
+
+// TableName 返回搜索索引表名
+func (searchDocument) TableName() string {
+	return searchTable
+}
+
+// values 返回与searchColumns顺序一致的列内容
+func (doc *searchDocument) values() []string {
+	return []string{doc.Title, doc.URL, doc.Headers, doc.Body, doc.Tech}
+}
+
+// newSearchDocument 根据截图记录生成搜索索引记录，截图记录需要预先加载响应头和技术栈
+func newSearchDocument(s *Screenshot) searchDocument {
+	var headers strings.Builder
+	for _, h := range s.Headers {
+		headers.WriteString(strings.ToLower(h.Name))
+		headers.WriteString(": ")
+		headers.WriteString(h.Value)
+		headers.WriteString("\n")
+	}
+
+	techs := make([]string, 0, len(s.Technologies))
+	for _, t := range s.Technologies {
+		tech := t.Name
+		if t.Version != "" {
+			tech += " " + t.Version
+		}
+		techs = append(techs, tech)
+	}
+
+	url := s.URL
+	if s.FinalURL != "" && s.FinalURL != s.URL {
+		url += "\n" + s.FinalURL
+	}
+
+	return searchDocument{
+		ScreenshotID: s.ID,
+		Title:        s.Title,
+		URL:          url,
+		Headers:      headers.String(),
+		Body:         s.HTML,
+		Tech:         strings.Join(techs, "\n"),
+	}
+}
+
+// isSQLite 判断是否为SQLite数据库
+func isSQLite(tx *gorm.DB) bool {
+	return tx.Dialector.Name() == DialectSQLite
+}
+
+// createSearchIndex 创建搜索索引表并为已有的截图建立索引
+// SQLite优先使用FTS5，当前构建不支持时退回到FTS4；其他数据库使用普通表
+func createSearchIndex(tx *gorm.DB) error {
+	if isSQLite(tx) {
+		columns := strings.Join(searchColumns, ", ")
+		err := tx.Exec(fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(%s)", searchTable, columns)).Error
+		if err != nil {
+			err = tx.Exec(fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts4(%s)", searchTable, columns)).Error
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		type searchDocument struct {
+			ScreenshotID uint `gorm:"primaryKey;autoIncrement:false"`
+			Title        string
+			URL          string
+			Headers      string
+			Body         string
+			Tech         string
+		}
+		migrator := tx.Table(searchTable).Migrator()
+		if !migrator.HasTable(&searchDocument{}) {
+			if err := migrator.CreateTable(&searchDocument{}); err != nil {
+				return err
+			}
+		}
+	}
+
+	var screenshots []*Screenshot
+	return tx.Preload("Headers").Preload("Technologies").
+		FindInBatches(&screenshots, deleteBatchSize, func(batch *gorm.DB, _ int) error {
+			return indexScreenshots(tx, screenshots)
+		}).Error
+}
+
+// indexScreenshots 将截图记录写入搜索索引
+func indexScreenshots(tx *gorm.DB, screenshots []*Screenshot) error {
+	for _, screenshot := range screenshots {
+		doc := newSearchDocument(screenshot)
+		if !isSQLite(tx) {
+			if err := tx.Create(&doc).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		// FTS表使用rowid关联截图ID
+		err := tx.Exec(
+			fmt.Sprintf("INSERT INTO %s (rowid, %s) VALUES (?, ?, ?, ?, ?, ?)", searchTable, strings.Join(searchColumns, ", ")),
+			doc.ScreenshotID, doc.Title, doc.URL, doc.Headers, doc.Body, doc.Tech,
+		).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeFromSearchIndex 从搜索索引中删除截图记录
+func removeFromSearchIndex(tx *gorm.DB, ids []uint) error {
+	if isSQLite(tx) {
+		return tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE rowid IN ?", searchTable), ids).Error
+	}
+	return tx.Where("screenshot_id IN ?", ids).Delete(&searchDocument{}).Error
+}
+
+// ParseSearchQuery 解析搜索语句
+//
+// 语句由空格分隔的条件组成，所有条件都需要匹配。条件可以带有字段前缀，
+// 如 title:login、header:server=nginx、body:password、tech:WordPress、url:admin，
+// 使用双引号搜索包含空格的短语，如 title:"sign in"。
+func ParseSearchQuery(query string) []SearchTerm {
+	var terms []SearchTerm
+	for _, token := range splitSearchQuery(query) {
+		term := SearchTerm{Value: token}
+
+		if prefix, value, found := strings.Cut(token, ":"); found {
+			switch strings.ToLower(prefix) {
+			case SearchFieldTitle, SearchFieldURL, SearchFieldBody, SearchFieldTech:
+				term = SearchTerm{Field: strings.ToLower(prefix), Value: value}
+			case SearchFieldHeader:
+				term = SearchTerm{Field: SearchFieldHeader, Value: value}
+				if name, headerValue, found := strings.Cut(value, "="); found {
+					term.Name = strings.ToLower(strings.TrimSpace(name))
+					term.Value = headerValue
+				}
+			}
+		}
+
+		term.Value = strings.TrimSpace(strings.Trim(term.Value, `"`))
+		if term.Value == "" && term.Name == "" {
+			continue
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// splitSearchQuery 按空格拆分搜索语句，双引号内的空格不拆分
+func splitSearchQuery(query string) []string {
+	var tokens []string
+	var current strings.Builder
+	quoted := false
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t') && !quoted:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// termColumns 返回条件需要匹配的索引列
+func termColumns(term SearchTerm) []string {
+	switch term.Field {
+	case SearchFieldTitle:
+		return []string{"title"}
+	case SearchFieldURL:
+		return []string{"url"}
+	case SearchFieldHeader:
+		return []string{"headers"}
+	case SearchFieldBody:
+		return []string{"body"}
+	case SearchFieldTech:
+		return []string{"tech"}
+	default:
+		return searchColumns
+	}
+}
+
+// termText 返回条件要匹配的文本，响应头条件按索引中的"名称: 值"格式组合
+func termText(term SearchTerm) (text string, prefix bool) {
+	text = term.Value
+	if term.Field == SearchFieldHeader && term.Name != "" {
+		text = term.Name + ": " + term.Value
+		if term.Value == "" {
+			text = term.Name
+		}
+	}
+	if strings.HasSuffix(text, "*") {
+		return strings.TrimRight(text, "*"), true
+	}
+	return text, false
+}
+
+// ftsMatchQuery 将搜索条件转换为FTS的MATCH语句
+// FTS4的列过滤只作用于单个词，因此按词拆分短语，短语匹配由scoreDocument再次校验
+func ftsMatchQuery(terms []SearchTerm, fts5 bool) string {
+	var parts []string
+	for _, term := range terms {
+		text, prefix := termText(term)
+		column := ""
+		if term.Field != "" {
+			column = termColumns(term)[0] + ":"
+		}
+
+		if fts5 {
+			phrase := `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+			if prefix {
+				phrase += " *"
+			}
+			parts = append(parts, column+phrase)
+			continue
+		}
+
+		words := strings.FieldsFunc(text, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for i, word := range words {
+			if prefix && i == len(words)-1 {
+				word += "*"
+			}
+			parts = append(parts, column+word)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// scoreDocument 计算索引记录与搜索条件的相关度，用于不支持bm25的数据库
+// 所有条件都以子串形式出现在对应字段中时matched为true
+func scoreDocument(doc *searchDocument, terms []SearchTerm) (score float64, matched bool) {
+	values := doc.values()
+	for _, term := range terms {
+		text, _ := termText(term)
+		needle := strings.ToLower(text)
+		found := false
+		for _, column := range termColumns(term) {
+			for i, name := range searchColumns {
+				if name != column {
+					continue
+				}
+				if n := strings.Count(strings.ToLower(values[i]), needle); n > 0 {
+					score += searchWeights[i] * (1 + math.Log(float64(n)))
+					found = true
+				}
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return score, true
+}
+
+// escapeLike 转义LIKE语句中的通配符，使用!作为转义字符
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// Search 在标题、URL、响应头、HTML内容和技术栈中搜索截图，按相关度排序
+func (d *DB) Search(query string, limit int) ([]SearchResult, error) {
+	terms := ParseSearchQuery(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("搜索语句不能为空")
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	var ranks map[uint]float64
+	var err error
+	if isSQLite(d.db) {
+		ranks, err = d.searchFTS(terms, limit)
+	} else {
+		ranks, err = d.searchLike(terms)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("搜索失败: %v", err)
+	}
+
+	ids := make([]uint, 0, len(ranks))
+	for id := range ranks {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ranks[ids[i]] != ranks[ids[j]] {
+			return ranks[ids[i]] > ranks[ids[j]]
+		}
+		return ids[i] > ids[j]
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	if len(ids) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	var screenshots []Screenshot
+	if err := d.db.Where("id IN ?", ids).Find(&screenshots).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*Screenshot, len(screenshots))
+	for i := range screenshots {
+		byID[screenshots[i].ID] = &screenshots[i]
+	}
+
+	results := make([]SearchResult, 0, len(ids))
+	for _, id := range ids {
+		s, ok := byID[id]
+		if !ok {
+			continue
+		}
+		results = append(results, SearchResult{
+			ID:           s.ID,
+			URL:          s.URL,
+			FinalURL:     s.FinalURL,
+			Title:        s.Title,
+			ResponseCode: s.ResponseCode,
+			Filename:     s.Filename,
+			ProbedAt:     s.ProbedAt,
+			SessionID:    s.SessionID,
+			Rank:         ranks[id],
+		})
+	}
+	return results, nil
+}
+
+// searchFTS 使用SQLite FTS表搜索，FTS5使用bm25排序，FTS4在内存中计算相关度
+func (d *DB) searchFTS(terms []SearchTerm, limit int) (map[uint]float64, error) {
+	var definition string
+	if err := d.db.Raw("SELECT sql FROM sqlite_master WHERE name = ?", searchTable).Scan(&definition).Error; err != nil {
+		return nil, err
+	}
+	fts5 := strings.Contains(strings.ToLower(definition), "fts5")
+	match := ftsMatchQuery(terms, fts5)
+	ranks := make(map[uint]float64)
+	if match == "" {
+		return ranks, nil
+	}
+
+	if fts5 {
+		weights := make([]string, len(searchWeights))
+		for i, w := range searchWeights {
+			weights[i] = fmt.Sprintf("%g", w)
+		}
+
+		var rows []struct {
+			ID   uint
+			Rank float64
+		}
+		// bm25越小越相关，取负值使结果按降序排列
+		err := d.db.Raw(fmt.Sprintf(
+			"SELECT rowid AS id, -bm25(%s, %s) AS rank FROM %s WHERE %s MATCH ? ORDER BY rank DESC LIMIT ?",
+			searchTable, strings.Join(weights, ", "), searchTable, searchTable), match, limit).Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			ranks[row.ID] = row.Rank
+		}
+		return ranks, nil
+	}
+
+	var docs []searchDocument
+	err := d.db.Raw(fmt.Sprintf(
+		"SELECT rowid AS screenshot_id, %s FROM %s WHERE %s MATCH ?",
+		strings.Join(searchColumns, ", "), searchTable, searchTable), match).Scan(&docs).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range docs {
+		if score, matched := scoreDocument(&docs[i], terms); matched {
+			ranks[docs[i].ScreenshotID] = score
+		}
+	}
+	return ranks, nil
+}
+
+// searchLike 使用不区分大小写的子串匹配搜索，用于PostgreSQL和MySQL
+func (d *DB) searchLike(terms []SearchTerm) (map[uint]float64, error) {
+	tx := d.db.Model(&searchDocument{})
+	for _, term := range terms {
+		text, _ := termText(term)
+		pattern := "%" + escapeLike(strings.ToLower(text)) + "%"
+
+		columns := termColumns(term)
+		conditions := make([]string, 0, len(columns))
+		args := make([]interface{}, 0, len(columns))
+		for _, column := range columns {
+			conditions = append(conditions, fmt.Sprintf("LOWER(%s) LIKE ? ESCAPE '!'", column))
+			args = append(args, pattern)
+		}
+		tx = tx.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+
+	var docs []searchDocument
+	if err := tx.Find(&docs).Error; err != nil {
+		return nil, err
+	}
+
+	ranks := make(map[uint]float64, len(docs))
+	for i := range docs {
+		score, _ := scoreDocument(&docs[i], terms)
+		ranks[docs[i].ScreenshotID] = score
+	}
+	return ranks, nil
+}
@@ -0,0 +1,299 @@
+package database
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 标签规则支持的字段
+const (
+	TagFieldTitle    = "title"     // 页面标题
+	TagFieldURL      = "url"       // 扫描URL
+	TagFieldFinalURL = "final_url" // 最终URL
+	TagFieldHTML     = "html"      // HTML内容
+	TagFieldHeader   = "header"    // 响应头，按"名称: 值"匹配
+	TagFieldTech     = "tech"      // 技术栈名称
+	TagFieldStatus   = "status"    // 响应状态码
+)
+
+// TagRule 表示一条批量打标签规则：字段内容匹配正则表达式时添加标签
+type TagRule struct {
+	Tag     string `json:"tag"`
+	Field   string `json:"field"`
+	Pattern string `json:"pattern"`
+
+	re *regexp.Regexp
+}
+
+// compile 校验并编译规则
+func (r *TagRule) compile() error {
+	r.Tag = strings.TrimSpace(r.Tag)
+	if r.Tag == "" {
+		return fmt.Errorf("标签规则缺少标签名称")
+	}
+
+	switch r.Field {
+	case TagFieldTitle, TagFieldURL, TagFieldFinalURL, TagFieldHTML, TagFieldHeader, TagFieldTech, TagFieldStatus:
+	default:
+		return fmt.Errorf("不支持的标签规则字段: %s", r.Field)
+	}
+
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return fmt.Errorf("无效的标签规则正则表达式 '%s': %v", r.Pattern, err)
+	}
+	r.re = re
+	return nil
+}
+
+// Match 检查截图记录是否匹配规则，截图记录需要预先加载关联数据
+func (r *TagRule) Match(s *Screenshot) bool {
+	if r.re == nil {
+		if err := r.compile(); err != nil {
+			return false
+		}
+	}
+
+	switch r.Field {
+	case TagFieldTitle:
+		return r.re.MatchString(s.Title)
+	case TagFieldURL:
+		return r.re.MatchString(s.URL)
+	case TagFieldFinalURL:
+		return r.re.MatchString(s.FinalURL)
+	case TagFieldHTML:
+		return r.re.MatchString(s.HTML)
+	case TagFieldStatus:
+		return r.re.MatchString(strconv.Itoa(s.ResponseCode))
+	case TagFieldHeader:
+		for _, h := range s.Headers {
+			if r.re.MatchString(h.Name + ": " + h.Value) {
+				return true
+			}
+		}
+	case TagFieldTech:
+		for _, t := range s.Technologies {
+			if r.re.MatchString(t.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParseTagRules 从文本中读取标签规则，每行格式为"标签 字段 正则表达式"
+// 空行和以#开头的行将被忽略
+func ParseTagRules(r io.Reader) ([]TagRule, error) {
+	var rules []TagRule
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("第%d行格式错误，应为\"标签 字段 正则表达式\"", lineNo)
+		}
+
+		// 正则表达式可能包含空格，取字段之后的全部内容
+		rest := strings.TrimSpace(line[len(parts[0]):])
+		pattern := strings.TrimSpace(rest[len(parts[1]):])
+		rule := TagRule{Tag: parts[0], Field: parts[1], Pattern: pattern}
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("第%d行: %v", lineNo, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// addTag 查找或创建标签
+func addTag(tx *gorm.DB, name string) (*Tag, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("标签名称不能为空")
+	}
+
+	var tag Tag
+	if err := tx.Where(Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// linkTag 关联截图和标签，已关联时忽略
+func linkTag(tx *gorm.DB, screenshotID, tagID uint) error {
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ScreenshotTag{
+		ScreenshotID: screenshotID,
+		TagID:        tagID,
+	}).Error
+}
+
+// tagScreenshot 为截图添加一组标签
+func tagScreenshot(tx *gorm.DB, screenshotID uint, names []string) error {
+	for _, name := range names {
+		tag, err := addTag(tx, name)
+		if err != nil {
+			return err
+		}
+		if err := linkTag(tx, screenshotID, tag.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddTag 添加标签，标签已存在时返回已有标签
+func (d *DB) AddTag(name string) (*Tag, error) {
+	return addTag(d.db, name)
+}
+
+// AddTagToScreenshot 给截图添加标签
+func (d *DB) AddTagToScreenshot(screenshotID uint, tagID uint) error {
+	return linkTag(d.db, screenshotID, tagID)
+}
+
+// TagScreenshots 给多个截图添加指定名称的标签，标签不存在时自动创建
+func (d *DB) TagScreenshots(screenshotIDs []uint, name string) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		tag, err := addTag(tx, name)
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&Screenshot{}).Where("id IN ?", screenshotIDs).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(screenshotIDs) {
+			return fmt.Errorf("部分截图记录不存在")
+		}
+
+		for _, id := range screenshotIDs {
+			if err := linkTag(tx, id, tag.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UntagScreenshots 移除多个截图上的指定标签，返回移除的关联数量
+func (d *DB) UntagScreenshots(screenshotIDs []uint, name string) (int64, error) {
+	var tag Tag
+	if err := d.db.Where("name = ?", name).First(&tag).Error; err != nil {
+		return 0, err
+	}
+
+	result := d.db.Where("tag_id = ? AND screenshot_id IN ?", tag.ID, screenshotIDs).Delete(&ScreenshotTag{})
+	return result.RowsAffected, result.Error
+}
+
+// GetAllTags 获取所有标签
+func (d *DB) GetAllTags() ([]*Tag, error) {
+	var tags []*Tag
+	if err := d.db.Order("name").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// GetTagCounts 获取所有标签及其关联的截图数量
+func (d *DB) GetTagCounts() ([]TagCount, error) {
+	var counts []TagCount
+	err := d.db.Model(&Tag{}).
+		Select("tags.name AS name, COUNT(screenshot_tags.screenshot_id) AS count").
+		Joins("LEFT JOIN screenshot_tags ON screenshot_tags.tag_id = tags.id").
+		Group("tags.id, tags.name").
+		Order("tags.name").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// GetScreenshotsByTag 获取带有指定标签的所有截图
+func (d *DB) GetScreenshotsByTag(name string) ([]*Screenshot, error) {
+	var screenshots []*Screenshot
+	err := withRelations(d.db).
+		Joins("JOIN screenshot_tags ON screenshot_tags.screenshot_id = screenshots.id").
+		Joins("JOIN tags ON tags.id = screenshot_tags.tag_id").
+		Where("tags.name = ?", name).
+		Order("screenshots.id").
+		Find(&screenshots).Error
+	return screenshots, err
+}
+
+// GetScreenshotIDsByURL 获取指定URL的所有截图记录ID
+func (d *DB) GetScreenshotIDsByURL(url string) ([]uint, error) {
+	var ids []uint
+	err := d.db.Model(&Screenshot{}).Where("url_hash = ? AND url = ?", urlHash(url), url).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ApplyTagRules 对所有截图应用标签规则，返回每个标签匹配到的截图数量
+func (d *DB) ApplyTagRules(rules []TagRule) (map[string]int, error) {
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	// 收集匹配结果，按标签分组
+	matches := make(map[string][]uint)
+	var batch []*Screenshot
+	err := withRelations(d.db).FindInBatches(&batch, 200, func(tx *gorm.DB, _ int) error {
+		for _, screenshot := range batch {
+			for i := range rules {
+				if rules[i].Match(screenshot) {
+					matches[rules[i].Tag] = append(matches[rules[i].Tag], screenshot.ID)
+				}
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rules))
+	err = d.db.Transaction(func(tx *gorm.DB) error {
+		for name, ids := range matches {
+			tag, err := addTag(tx, name)
+			if err != nil {
+				return err
+			}
+			seen := make(map[uint]bool, len(ids))
+			for _, id := range ids {
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				if err := linkTag(tx, id, tag.ID); err != nil {
+					return err
+				}
+			}
+			counts[name] = len(seen)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rules {
+		if _, ok := counts[rules[i].Tag]; !ok {
+			counts[rules[i].Tag] = 0
+		}
+	}
+	return counts, nil
+}
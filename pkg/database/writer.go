@@ -9,7 +9,8 @@ import (
 
 // DBWriter 实现 runner.Writer 接口，将结果写入数据库
 type DBWriter struct {
-	db *DB
+	db      *DB
+	session *ScanSession
 }
 
 // NewDBWriter 创建新的数据库写入器
@@ -19,9 +20,38 @@ func NewDBWriter(db *DB) *DBWriter {
 	}
 }
 
+// StartSession 创建扫描会话，之后写入的所有结果都会关联到该会话
+func (w *DBWriter) StartSession(session *ScanSession) error {
+	if err := w.db.CreateScanSession(session); err != nil {
+		return fmt.Errorf("创建扫描会话失败: %v", err)
+	}
+	w.session = session
+	log.Debug("已创建扫描会话", "id", session.ID, "name", session.Name)
+	return nil
+}
+
+// Session 返回当前的扫描会话，未创建时返回nil
+func (w *DBWriter) Session() *ScanSession {
+	return w.session
+}
+
+// SetTargetCount 记录当前扫描会话的目标数量
+func (w *DBWriter) SetTargetCount(count int) error {
+	if w.session == nil {
+		return nil
+	}
+	w.session.TargetCount = count
+	return w.db.SetScanSessionTargetCount(w.session.ID, count)
+}
+
 // Write 实现 runner.Writer 接口，将结果写入数据库
 func (w *DBWriter) Write(result *models.Result) error {
-	err := w.db.SaveResult(result)
+	var sessionID uint
+	if w.session != nil {
+		sessionID = w.session.ID
+	}
+
+	err := w.db.SaveSessionResult(result, sessionID)
 	if err != nil {
 		log.Error("保存结果到数据库失败", "error", err, "url", result.URL)
 		return fmt.Errorf("保存结果到数据库失败: %v", err)
@@ -30,7 +60,12 @@ func (w *DBWriter) Write(result *models.Result) error {
 	return nil
 }
 
-// Close 实现 runner.Writer 接口，关闭数据库连接
+// Close 实现 runner.Writer 接口，结束扫描会话
 func (w *DBWriter) Close() error {
+	if w.session != nil {
+		if err := w.db.EndScanSession(w.session.ID); err != nil {
+			return fmt.Errorf("结束扫描会话失败: %v", err)
+		}
+	}
 	return nil // DB会通过主程序关闭
 }
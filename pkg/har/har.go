@@ -0,0 +1,256 @@
+// Package har 记录页面的网络活动并生成HAR 1.2格式的文件
+//
+// 格式说明见 http://www.softwareishard.com/blog/har-12-spec/
+package har
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cyberspacesec/go-snir/pkg/ascii"
+	"github.com/cyberspacesec/go-snir/pkg/models"
+)
+
+// Version 是生成的HAR文件版本
+const Version = "1.2"
+
+// File 是HAR文件的顶层结构
+type File struct {
+	Log *Log `json:"log"`
+}
+
+// Log 包含页面和请求记录
+type Log struct {
+	Version string   `json:"version"`
+	Creator Creator  `json:"creator"`
+	Browser *Creator `json:"browser,omitempty"`
+	Pages   []Page   `json:"pages"`
+	Entries []Entry  `json:"entries"`
+	Comment string   `json:"comment,omitempty"`
+}
+
+// Creator 表示生成HAR文件的程序或浏览器
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Page 表示一个页面
+type Page struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	ID              string      `json:"id"`
+	Title           string      `json:"title"`
+	PageTimings     PageTimings `json:"pageTimings"`
+}
+
+// PageTimings 表示页面加载事件的时间，单位为毫秒，-1表示不可用
+type PageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+// Entry 表示一次请求及其响应
+type Entry struct {
+	PageRef         string    `json:"pageref,omitempty"`
+	StartedDateTime time.Time `json:"startedDateTime"`
+	Time            float64   `json:"time"`
+	Request         Request   `json:"request"`
+	Response        Response  `json:"response"`
+	Cache           Cache     `json:"cache"`
+	Timings         Timings   `json:"timings"`
+	ServerIPAddress string    `json:"serverIPAddress,omitempty"`
+	Connection      string    `json:"connection,omitempty"`
+
+	// 以下划线开头的是HAR规范允许的自定义字段，与Chrome导出的HAR文件一致
+	Initiator    *Initiator `json:"_initiator,omitempty"`
+	ResourceType string     `json:"_resourceType,omitempty"`
+}
+
+// Request 表示请求
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+// Response 表示响应
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+	Error       string      `json:"_error,omitempty"`
+}
+
+// Cookie 表示请求或响应中的Cookie
+type Cookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Path     string     `json:"path,omitempty"`
+	Domain   string     `json:"domain,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+}
+
+// NameValue 表示请求头、响应头或查询参数
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PostData 表示请求体
+type PostData struct {
+	MimeType string      `json:"mimeType"`
+	Params   []NameValue `json:"params,omitempty"`
+	Text     string      `json:"text"`
+}
+
+// Content 表示响应内容
+type Content struct {
+	Size        int64  `json:"size"`
+	Compression int64  `json:"compression,omitempty"`
+	MimeType    string `json:"mimeType"`
+	Text        string `json:"text,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+// Cache 表示缓存信息，不记录缓存内容
+type Cache struct{}
+
+// Timings 表示请求各阶段的耗时，单位为毫秒，-1表示不适用
+type Timings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// Initiator 表示发起请求的来源
+type Initiator struct {
+	Type       string  `json:"type"`
+	URL        string  `json:"url,omitempty"`
+	LineNumber float64 `json:"lineNumber,omitempty"`
+}
+
+// NewLog 创建空的HAR记录
+func NewLog() *Log {
+	return &Log{
+		Version: Version,
+		Creator: Creator{Name: "go-snir", Version: ascii.Version()},
+		Pages:   []Page{},
+		Entries: []Entry{},
+	}
+}
+
+// Marshal 把记录编码为HAR文件内容
+func Marshal(log *Log) ([]byte, error) {
+	return json.MarshalIndent(File{Log: log}, "", "  ")
+}
+
+// Unmarshal 解析HAR文件内容
+func Unmarshal(data []byte) (*Log, error) {
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if file.Log == nil {
+		return nil, fmt.Errorf("无效的HAR文件: 缺少log字段")
+	}
+	return file.Log, nil
+}
+
+// Merge 把多个HAR记录合并为一个，页面ID重复时重新编号
+func Merge(logs []*Log) *Log {
+	merged := NewLog()
+	ids := make(map[string]bool)
+	for i, log := range logs {
+		if log == nil {
+			continue
+		}
+		if merged.Browser == nil {
+			merged.Browser = log.Browser
+		}
+
+		rename := make(map[string]string)
+		for _, page := range log.Pages {
+			id := page.ID
+			if ids[id] {
+				id = fmt.Sprintf("page_%d_%s", i+1, page.ID)
+			}
+			ids[id] = true
+			rename[page.ID] = id
+			page.ID = id
+			merged.Pages = append(merged.Pages, page)
+		}
+		for _, entry := range log.Entries {
+			if id, ok := rename[entry.PageRef]; ok {
+				entry.PageRef = id
+			}
+			merged.Entries = append(merged.Entries, entry)
+		}
+	}
+	return merged
+}
+
+// FromResult 根据结果中的网络请求日志生成简化的HAR记录，用于没有保存HAR文件的结果
+// 网络请求日志中没有请求头和时间信息，这些字段使用空值
+func FromResult(result *models.Result) *Log {
+	log := NewLog()
+	if len(result.Network) == 0 {
+		return log
+	}
+
+	log.Pages = append(log.Pages, Page{
+		StartedDateTime: result.ProbedAt,
+		ID:              pageID,
+		Title:           result.Title,
+		PageTimings:     PageTimings{OnContentLoad: -1, OnLoad: -1},
+	})
+	for _, nl := range result.Network {
+		if nl.Type != models.HTTP {
+			continue
+		}
+		log.Entries = append(log.Entries, Entry{
+			PageRef:         pageID,
+			StartedDateTime: result.ProbedAt,
+			Request: Request{
+				Method:      nl.Method,
+				URL:         nl.URL,
+				Cookies:     []Cookie{},
+				Headers:     []NameValue{},
+				QueryString: queryString(nl.URL),
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+			Response: Response{
+				Status:      nl.StatusCode,
+				StatusText:  http.StatusText(nl.StatusCode),
+				Cookies:     []Cookie{},
+				Headers:     []NameValue{},
+				Content:     Content{MimeType: nl.ContentType},
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+			Timings: Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+		})
+	}
+	return log
+}
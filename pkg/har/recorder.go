@@ -0,0 +1,631 @@
+package har
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+
+	"github.com/cyberspacesec/go-snir/pkg/models"
+)
+
+// pageID 是记录中唯一页面的ID
+const pageID = "page_1"
+
+// Redacted 替换记录中请求头的值，避免HAR文件泄露凭据
+const Redacted = "[REDACTED]"
+
+// redactedHeaders 是总是隐藏值的请求头，身份验证后浏览器发送的凭据在这些请求头中
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization"}
+
+// Recorder 根据CDP网络事件记录页面的所有请求
+// 事件在chromedp的事件处理协程中到达，所有状态都由互斥锁保护
+type Recorder struct {
+	mu       sync.Mutex
+	requests []*request                       // 按开始时间排列的请求，重定向的每一跳是一条记录
+	current  map[network.RequestID]*request   // 每个请求ID当前所在的一跳
+	extra    map[network.RequestID]extraInfos // 在请求记录创建之前到达的额外信息
+	// 每个框架最后一次导航的文档响应，重定向时只有最终文档会触发 responseReceived
+	documents map[cdp.FrameID]*network.Response
+	redact    map[string]bool // 需要隐藏值的请求头，名称为小写
+
+	pageStart     float64 // 第一个请求的单调时间（秒）
+	onContentLoad float64 // DOMContentLoaded事件的单调时间（秒）
+	onLoad        float64 // load事件的单调时间（秒）
+}
+
+// extraInfos 表示网络栈实际发送和接收的请求头和响应头
+type extraInfos struct {
+	requestHeaders network.Headers
+	response       *network.EventResponseReceivedExtraInfo
+}
+
+// request 表示记录中的一次请求，重定向时每一跳单独记录
+type request struct {
+	id           network.RequestID
+	started      time.Time // 开始时间
+	startMono    float64   // 开始的单调时间（秒）
+	resourceType network.ResourceType
+	initiator    *network.Initiator
+	request      *network.Request
+	extra        extraInfos
+	response     *network.Response
+	responseMono float64 // 收到响应头的单调时间（秒）
+	endMono      float64 // 结束的单调时间（秒）
+	encodedSize  float64 // 实际接收的字节数，包括响应头
+	dataSize     int64   // 解码后的响应体大小
+	redirectURL  string
+	errorText    string
+	finished     bool
+	body         []byte
+	bodyFetched  bool
+}
+
+// NewRecorder 创建网络活动记录器
+// redact 为除 Authorization 和 Proxy-Authorization 外需要隐藏值的请求头，如自定义的令牌请求头
+func NewRecorder(redact ...string) *Recorder {
+	r := &Recorder{
+		current:   make(map[network.RequestID]*request),
+		extra:     make(map[network.RequestID]extraInfos),
+		documents: make(map[cdp.FrameID]*network.Response),
+		redact:    make(map[string]bool),
+	}
+	for _, name := range append(redactedHeaders, redact...) {
+		r.redact[strings.ToLower(name)] = true
+	}
+	return r
+}
+
+// Handle 处理CDP事件，传给 chromedp.ListenTarget 使用
+// 事件处理函数中不能执行CDP命令，响应体在页面加载完成后由 FetchBodies 获取
+func (r *Recorder) Handle(ev interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		r.requestWillBeSent(e)
+	case *network.EventRequestWillBeSentExtraInfo:
+		// 重定向时下一跳的额外信息可能先于下一跳的请求事件到达
+		if req, ok := r.current[e.RequestID]; ok && req.response == nil && req.extra.requestHeaders == nil {
+			req.extra.requestHeaders = e.Headers
+		} else {
+			info := r.extra[e.RequestID]
+			info.requestHeaders = e.Headers
+			r.extra[e.RequestID] = info
+		}
+	case *network.EventResponseReceived:
+		if e.Type == network.ResourceTypeDocument && e.FrameID != "" {
+			r.documents[e.FrameID] = e.Response
+		}
+		if req, ok := r.current[e.RequestID]; ok {
+			req.response = e.Response
+			req.responseMono = monotonic(e.Timestamp)
+			if req.resourceType == "" {
+				req.resourceType = e.Type
+			}
+		}
+	case *network.EventResponseReceivedExtraInfo:
+		if req, ok := r.current[e.RequestID]; ok && req.extra.response == nil {
+			req.extra.response = e
+		} else {
+			info := r.extra[e.RequestID]
+			info.response = e
+			r.extra[e.RequestID] = info
+		}
+	case *network.EventDataReceived:
+		if req, ok := r.current[e.RequestID]; ok {
+			req.dataSize += e.DataLength
+		}
+	case *network.EventLoadingFinished:
+		if req, ok := r.current[e.RequestID]; ok {
+			req.finished = true
+			req.endMono = monotonic(e.Timestamp)
+			req.encodedSize = e.EncodedDataLength
+		}
+	case *network.EventLoadingFailed:
+		if req, ok := r.current[e.RequestID]; ok {
+			req.endMono = monotonic(e.Timestamp)
+			req.errorText = e.ErrorText
+			if e.BlockedReason != "" {
+				req.errorText = fmt.Sprintf("%s (%s)", e.ErrorText, e.BlockedReason)
+			}
+		}
+	case *page.EventDomContentEventFired:
+		if r.onContentLoad == 0 {
+			r.onContentLoad = monotonic(e.Timestamp)
+		}
+	case *page.EventLoadEventFired:
+		if r.onLoad == 0 {
+			r.onLoad = monotonic(e.Timestamp)
+		}
+	}
+}
+
+// requestWillBeSent 开始记录请求，重定向时先用重定向响应结束上一跳
+func (r *Recorder) requestWillBeSent(e *network.EventRequestWillBeSent) {
+	now := monotonic(e.Timestamp)
+	if prev, ok := r.current[e.RequestID]; ok && e.RedirectResponse != nil {
+		prev.response = e.RedirectResponse
+		prev.responseMono = now
+		prev.endMono = now
+		prev.encodedSize = e.RedirectResponse.EncodedDataLength
+		prev.redirectURL = e.Request.URL
+		prev.finished = true
+		prev.bodyFetched = true
+	}
+
+	req := &request{
+		id:           e.RequestID,
+		startMono:    now,
+		resourceType: e.Type,
+		initiator:    e.Initiator,
+		request:      e.Request,
+	}
+	if e.WallTime != nil {
+		req.started = e.WallTime.Time()
+	} else {
+		req.started = time.Now()
+	}
+	if info, ok := r.extra[e.RequestID]; ok {
+		req.extra = info
+		delete(r.extra, e.RequestID)
+	}
+	if r.pageStart == 0 {
+		r.pageStart = now
+	}
+
+	r.current[e.RequestID] = req
+	r.requests = append(r.requests, req)
+}
+
+// FetchBodies 返回获取响应体的任务，只获取大小不超过limit字节的响应体
+// 响应体可能已被浏览器释放，获取失败的请求不记录响应体
+func (r *Recorder) FetchBodies(limit int64) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if limit <= 0 {
+			return nil
+		}
+
+		r.mu.Lock()
+		var pending []*request
+		for _, req := range r.requests {
+			if req.finished && !req.bodyFetched && req.response != nil && req.dataSize <= limit {
+				req.bodyFetched = true
+				pending = append(pending, req)
+			}
+		}
+		r.mu.Unlock()
+
+		for _, req := range pending {
+			body, err := network.GetResponseBody(req.id).Do(ctx)
+			if err != nil || int64(len(body)) > limit {
+				continue
+			}
+			r.mu.Lock()
+			req.body = body
+			r.mu.Unlock()
+		}
+		return nil
+	})
+}
+
+// Status 返回框架最后一次导航的文档响应状态码，发生重定向时是最终文档的状态码
+// 传入主框架ID时即为页面的状态码，没有收到文档响应时返回0
+func (r *Recorder) Status(frameID cdp.FrameID) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if response, ok := r.documents[frameID]; ok {
+		return int(response.Status)
+	}
+	return 0
+}
+
+// NetworkLogs 返回每个请求的摘要
+func (r *Recorder) NetworkLogs() []models.NetworkLog {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	logs := make([]models.NetworkLog, 0, len(r.requests))
+	for _, req := range r.requests {
+		nl := models.NetworkLog{
+			Type:   models.HTTP,
+			URL:    req.request.URL,
+			Method: req.request.Method,
+		}
+		if req.response != nil {
+			nl.StatusCode = int(req.response.Status)
+			nl.ContentType = req.response.MimeType
+		}
+		logs = append(logs, nl)
+	}
+	return logs
+}
+
+// Log 生成HAR记录，title为页面标题
+func (r *Recorder) Log(title string) *Log {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	log := NewLog()
+	if len(r.requests) == 0 {
+		return log
+	}
+
+	log.Pages = append(log.Pages, Page{
+		StartedDateTime: r.requests[0].started,
+		ID:              pageID,
+		Title:           title,
+		PageTimings: PageTimings{
+			OnContentLoad: r.sincePageStart(r.onContentLoad),
+			OnLoad:        r.sincePageStart(r.onLoad),
+		},
+	})
+
+	for _, req := range r.requests {
+		log.Entries = append(log.Entries, req.entry(r.redact))
+	}
+	sort.SliceStable(log.Entries, func(i, j int) bool {
+		return log.Entries[i].StartedDateTime.Before(log.Entries[j].StartedDateTime)
+	})
+	return log
+}
+
+// sincePageStart 返回事件相对于页面开始的毫秒数，事件未发生时返回-1
+func (r *Recorder) sincePageStart(mono float64) float64 {
+	if mono == 0 {
+		return -1
+	}
+	return milliseconds(mono - r.pageStart)
+}
+
+// entry 把请求转换为HAR记录，redact 中的请求头只保留名称
+func (req *request) entry(redact map[string]bool) Entry {
+	entry := Entry{
+		PageRef:         pageID,
+		StartedDateTime: req.started,
+		Request:         req.harRequest(redact),
+		Response:        req.harResponse(),
+		Timings:         req.timings(),
+		ResourceType:    string(req.resourceType),
+	}
+
+	for _, t := range []float64{entry.Timings.Blocked, entry.Timings.DNS, entry.Timings.Connect,
+		entry.Timings.Send, entry.Timings.Wait, entry.Timings.Receive} {
+		if t > 0 {
+			entry.Time += t
+		}
+	}
+
+	if req.response != nil {
+		entry.ServerIPAddress = strings.Trim(req.response.RemoteIPAddress, "[]")
+		if req.response.ConnectionID != 0 {
+			entry.Connection = fmt.Sprintf("%.0f", req.response.ConnectionID)
+		}
+	}
+	if req.initiator != nil {
+		entry.Initiator = &Initiator{
+			Type:       string(req.initiator.Type),
+			URL:        req.initiator.URL,
+			LineNumber: req.initiator.LineNumber,
+		}
+	}
+	return entry
+}
+
+// harRequest 转换请求，优先使用网络栈实际发送的请求头
+func (req *request) harRequest(redact map[string]bool) Request {
+	headers := req.request.Headers
+	if req.extra.requestHeaders != nil {
+		headers = req.extra.requestHeaders
+	} else if req.response != nil && req.response.RequestHeaders != nil {
+		headers = req.response.RequestHeaders
+	}
+
+	harReq := Request{
+		Method:      req.request.Method,
+		URL:         req.request.URL + req.request.URLFragment,
+		HTTPVersion: httpVersion(req.response),
+		Cookies:     []Cookie{},
+		Headers:     nameValues(headers),
+		QueryString: queryString(req.request.URL),
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+
+	for i, header := range harReq.Headers {
+		if redact[strings.ToLower(header.Name)] {
+			harReq.Headers[i].Value = Redacted
+		}
+	}
+
+	if cookie := headerValue(headers, "Cookie"); cookie != "" {
+		harReq.Cookies = parseCookies(cookie)
+		if redact["cookie"] {
+			for i := range harReq.Cookies {
+				harReq.Cookies[i].Value = Redacted
+			}
+		}
+	}
+
+	if req.request.HasPostData {
+		var body []byte
+		for _, entry := range req.request.PostDataEntries {
+			if data, err := base64.StdEncoding.DecodeString(entry.Bytes); err == nil {
+				body = append(body, data...)
+			}
+		}
+		harReq.PostData = &PostData{
+			MimeType: headerValue(headers, "Content-Type"),
+			Text:     string(body),
+		}
+		harReq.BodySize = int64(len(body))
+	}
+	return harReq
+}
+
+// harResponse 转换响应，请求失败或没有响应时返回状态码为0的响应
+func (req *request) harResponse() Response {
+	res := Response{
+		Cookies:     []Cookie{},
+		Headers:     []NameValue{},
+		RedirectURL: req.redirectURL,
+		HeadersSize: -1,
+		BodySize:    -1,
+		Error:       req.errorText,
+	}
+	if req.response == nil {
+		res.Content = Content{MimeType: "x-unknown"}
+		return res
+	}
+
+	headers := req.response.Headers
+	status := req.response.Status
+	if info := req.extra.response; info != nil {
+		if info.Headers != nil {
+			headers = info.Headers
+		}
+		if info.StatusCode != 0 {
+			status = info.StatusCode
+		}
+		if info.HeadersText != "" {
+			res.HeadersSize = int64(len(info.HeadersText))
+		}
+	}
+
+	res.Status = int(status)
+	res.StatusText = req.response.StatusText
+	if res.StatusText == "" {
+		res.StatusText = http.StatusText(res.Status)
+	}
+	res.HTTPVersion = httpVersion(req.response)
+	res.Headers = nameValues(headers)
+	for _, value := range headerValues(headers, "Set-Cookie") {
+		if cookie, err := http.ParseSetCookie(value); err == nil {
+			res.Cookies = append(res.Cookies, setCookie(cookie))
+		}
+	}
+
+	// 实际接收的字节数包括响应头，无法得到响应头大小时全部计为响应体
+	if req.finished {
+		res.BodySize = int64(req.encodedSize)
+		if res.HeadersSize > 0 {
+			res.BodySize -= res.HeadersSize
+		}
+		if res.BodySize < 0 {
+			res.BodySize = 0
+		}
+	}
+	if req.response.FromDiskCache || req.response.FromServiceWorker || req.response.FromPrefetchCache {
+		res.BodySize = 0
+	}
+
+	res.Content = Content{
+		Size:     req.dataSize,
+		MimeType: req.response.MimeType,
+	}
+	if req.response.Charset != "" && !strings.Contains(res.Content.MimeType, "charset") {
+		res.Content.MimeType += "; charset=" + req.response.Charset
+	}
+	if res.BodySize > 0 && req.dataSize > res.BodySize {
+		res.Content.Compression = req.dataSize - res.BodySize
+	}
+	if req.body != nil {
+		if isText(req.response.MimeType) && utf8.Valid(req.body) {
+			res.Content.Text = string(req.body)
+		} else {
+			res.Content.Text = base64.StdEncoding.EncodeToString(req.body)
+			res.Content.Encoding = "base64"
+		}
+	}
+	return res
+}
+
+// timings 根据CDP的资源时间计算各阶段耗时
+func (req *request) timings() Timings {
+	t := Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+	if req.response == nil || req.response.Timing == nil {
+		// 没有详细时间信息（如缓存或请求失败）时，把全部耗时计为等待
+		if req.endMono > 0 {
+			t.Wait = milliseconds(req.endMono - req.startMono)
+		}
+		return t
+	}
+
+	timing := req.response.Timing
+	// 请求发出前的阻塞时间，包括请求在队列中的时间
+	queued := milliseconds(timing.RequestTime - req.startMono)
+	if queued < 0 {
+		queued = 0
+	}
+	blocked := firstNonNegative(timing.DNSStart, timing.ConnectStart, timing.SendStart)
+	if blocked >= 0 {
+		t.Blocked = queued + blocked
+	}
+	if timing.DNSStart >= 0 && timing.DNSEnd >= timing.DNSStart {
+		t.DNS = timing.DNSEnd - timing.DNSStart
+	}
+	if timing.ConnectStart >= 0 && timing.ConnectEnd >= timing.ConnectStart {
+		t.Connect = timing.ConnectEnd - timing.ConnectStart
+	}
+	if timing.SslStart >= 0 && timing.SslEnd >= timing.SslStart {
+		t.SSL = timing.SslEnd - timing.SslStart
+	}
+	t.Send = max(timing.SendEnd-timing.SendStart, 0)
+	t.Wait = max(timing.ReceiveHeadersEnd-timing.SendEnd, 0)
+
+	if req.endMono > 0 {
+		t.Receive = max(milliseconds(req.endMono-timing.RequestTime)-timing.ReceiveHeadersEnd, 0)
+	}
+	return t
+}
+
+// monotonic 把CDP单调时间转换为秒
+func monotonic(t *cdp.MonotonicTime) float64 {
+	if t == nil || cdp.MonotonicTimeEpoch == nil {
+		return 0
+	}
+	return t.Time().Sub(*cdp.MonotonicTimeEpoch).Seconds()
+}
+
+// milliseconds 把秒转换为毫秒并保留三位小数
+func milliseconds(seconds float64) float64 {
+	return float64(int64(seconds*1e6)) / 1e3
+}
+
+// firstNonNegative 返回第一个非负值，都为负时返回-1
+func firstNonNegative(values ...float64) float64 {
+	for _, v := range values {
+		if v >= 0 {
+			return v
+		}
+	}
+	return -1
+}
+
+// httpVersion 返回HAR使用的协议版本
+func httpVersion(response *network.Response) string {
+	if response == nil || response.Protocol == "" {
+		return "HTTP/1.1"
+	}
+	switch protocol := strings.ToLower(response.Protocol); protocol {
+	case "h2":
+		return "HTTP/2.0"
+	case "h3":
+		return "HTTP/3.0"
+	default:
+		return strings.ToUpper(protocol)
+	}
+}
+
+// nameValues 把CDP请求头转换为按名称排序的列表，同名的多个值各占一项
+func nameValues(headers network.Headers) []NameValue {
+	values := []NameValue{}
+	for name, value := range headers {
+		for _, v := range strings.Split(fmt.Sprint(value), "\n") {
+			values = append(values, NameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return strings.ToLower(values[i].Name) < strings.ToLower(values[j].Name)
+	})
+	return values
+}
+
+// headerValues 返回请求头的所有值，名称不区分大小写
+func headerValues(headers network.Headers, name string) []string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return strings.Split(fmt.Sprint(value), "\n")
+		}
+	}
+	return nil
+}
+
+// headerValue 返回请求头的值，多个值用逗号连接
+func headerValue(headers network.Headers, name string) string {
+	return strings.Join(headerValues(headers, name), ", ")
+}
+
+// queryString 解析URL中的查询参数
+func queryString(rawURL string) []NameValue {
+	values := []NameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return values
+	}
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(value); err == nil {
+			value = unescaped
+		}
+		values = append(values, NameValue{Name: name, Value: value})
+	}
+	return values
+}
+
+// parseCookies 解析请求中的Cookie头
+func parseCookies(header string) []Cookie {
+	cookies := []Cookie{}
+	for _, part := range strings.Split(header, ";") {
+		name, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found || name == "" {
+			continue
+		}
+		cookies = append(cookies, Cookie{Name: name, Value: value})
+	}
+	return cookies
+}
+
+// setCookie 转换响应中设置的Cookie
+func setCookie(c *http.Cookie) Cookie {
+	cookie := Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		HTTPOnly: c.HttpOnly,
+		Secure:   c.Secure,
+	}
+	if !c.Expires.IsZero() {
+		expires := c.Expires
+		cookie.Expires = &expires
+	}
+	return cookie
+}
+
+// isText 判断内容类型是否为文本，文本内容直接保存，其他内容以base64编码保存
+func isText(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		mediaType = strings.ToLower(mimeType)
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	for _, suffix := range []string{"json", "javascript", "xml", "x-www-form-urlencoded", "svg+xml"} {
+		if strings.HasSuffix(mediaType, suffix) {
+			return true
+		}
+	}
+	return false
+}
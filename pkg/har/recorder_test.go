@@ -0,0 +1,89 @@
+package har
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestRecorderRedactsCredentials(t *testing.T) {
+	recorder := NewRecorder("X-Api-Key", "Cookie")
+	recorder.Handle(&network.EventRequestWillBeSent{
+		RequestID: "1",
+		Request: &network.Request{
+			URL:     "https://example.com/",
+			Method:  "GET",
+			Headers: network.Headers{"Accept": "text/html"},
+		},
+	})
+	// 网络栈实际发送的请求头包括身份验证后浏览器添加的凭据
+	recorder.Handle(&network.EventRequestWillBeSentExtraInfo{
+		RequestID: "1",
+		Headers: network.Headers{
+			"Accept":              "text/html",
+			"Authorization":       "Basic YWRtaW46czNjcmV0",
+			"proxy-authorization": "Basic dXNlcjpzM2NyZXQ=",
+			"X-Api-Key":           "s3cret",
+			"Cookie":              "sid=s3cret; theme=dark",
+		},
+	})
+	recorder.Handle(&network.EventResponseReceived{
+		RequestID: "1",
+		Type:      network.ResourceTypeDocument,
+		Response:  &network.Response{URL: "https://example.com/", Status: 200, MimeType: "text/html"},
+	})
+
+	log := recorder.Log("Example")
+	if len(log.Entries) != 1 {
+		t.Fatalf("记录中有 %d 个请求，应为1个", len(log.Entries))
+	}
+	request := log.Entries[0].Request
+	headers := map[string]string{}
+	for _, header := range request.Headers {
+		headers[header.Name] = header.Value
+	}
+	for _, name := range []string{"Authorization", "proxy-authorization", "X-Api-Key", "Cookie"} {
+		if headers[name] != Redacted {
+			t.Errorf("请求头 %s 的值为 %q，应为 %q", name, headers[name], Redacted)
+		}
+	}
+	if headers["Accept"] != "text/html" {
+		t.Errorf("请求头 Accept 的值为 %q，应保留原值", headers["Accept"])
+	}
+	if len(request.Cookies) != 2 || request.Cookies[0].Name != "sid" || request.Cookies[0].Value != Redacted {
+		t.Errorf("请求Cookie为 %+v，应只保留名称", request.Cookies)
+	}
+
+	data, err := Marshal(log)
+	if err != nil {
+		t.Fatalf("生成HAR文件失败: %v", err)
+	}
+	for _, secret := range []string{"s3cret", "YWRtaW46czNjcmV0", "dXNlcjpzM2NyZXQ="} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("HAR文件中包含凭据 %s", secret)
+		}
+	}
+}
+
+func TestRecorderKeepsHeadersByDefault(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.Handle(&network.EventRequestWillBeSent{
+		RequestID: "1",
+		Request: &network.Request{
+			URL:     "https://example.com/",
+			Method:  "GET",
+			Headers: network.Headers{"Cookie": "theme=dark", "Authorization": "Bearer s3cret"},
+		},
+	})
+
+	request := recorder.Log("Example").Entries[0].Request
+	for _, header := range request.Headers {
+		if header.Name == "Cookie" && header.Value != "theme=dark" {
+			t.Errorf("没有设置Cookie时，Cookie请求头的值为 %q，应保留原值", header.Value)
+		}
+		if header.Name == "Authorization" && header.Value != Redacted {
+			t.Errorf("Authorization请求头的值为 %q，应为 %q", header.Value, Redacted)
+		}
+	}
+}
@@ -33,11 +33,40 @@ type Result struct {
 
 	// Name of the screenshot file
 	Filename string `json:"filename"` // 截图文件名
+	// SHA-256 of the screenshot content, identical captures share one stored file
+	ScreenshotHash string `json:"screenshot_hash,omitempty"`
 	IsPDF    bool   `json:"is_pdf"`
+	// Location of the PDF capture, set when the page was printed to PDF
+	PDF string `json:"pdf,omitempty"`
+	// SHA-256 of the PDF content
+	PDFHash string `json:"pdf_hash,omitempty"`
+	// Location of the MHTML page archive
+	Archive string `json:"archive,omitempty"`
+	// SHA-256 of the archive content
+	ArchiveHash string `json:"archive_hash,omitempty"`
+	// Time the archive was captured
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	// Location of the HAR file with the page's network activity
+	HAR string `json:"har,omitempty"`
+	// SHA-256 of the HAR file
+	HARHash string `json:"har_hash,omitempty"`
+	// Name of the emulated device profile, empty for the default window
+	Device string `json:"device,omitempty"`
+	// Proxy the page was loaded through, without credentials
+	Proxy string `json:"proxy,omitempty"`
+	// Expansion source that produced the host, such as ct or wordlist
+	Source string `json:"source,omitempty"`
 
 	// Failed flag set if the result should be considered failed
 	Failed       bool   `json:"failed"`
 	FailedReason string `json:"failed_reason"`
+	// Classified cause of the failure, such as dns, timeout or http_5xx
+	ErrorClass string `json:"error_class,omitempty"`
+	// Number of times the target was loaded, including retries
+	Attempts int `json:"attempts,omitempty"`
+
+	// Tags assigned to the result by analysts or tag rules
+	Tags []string `json:"tags,omitempty" gorm:"-"`
 
 	TLS          TLS          `json:"tls" gorm:"constraint:OnDelete:CASCADE"`
 	Technologies []Technology `json:"technologies" gorm:"constraint:OnDelete:CASCADE"`
@@ -46,6 +75,9 @@ type Result struct {
 	Network []NetworkLog `json:"network" gorm:"constraint:OnDelete:CASCADE"`
 	Console []ConsoleLog `json:"console" gorm:"constraint:OnDelete:CASCADE"`
 	Cookies []Cookie     `json:"cookies" gorm:"constraint:OnDelete:CASCADE"`
+
+	// Screenshots captured at additional viewport sizes after the same page load
+	Viewports []Viewport `json:"viewports,omitempty" gorm:"constraint:OnDelete:CASCADE"`
 }
 
 // HeaderMap returns a map of headers
@@ -109,6 +141,17 @@ type TLS struct {
 	FingerprintSHA1 string    `json:"fingerprint_sha1"`
 }
 
+// Viewport represents a screenshot captured at an additional viewport size
+type Viewport struct {
+	ID             uint   `json:"id" gorm:"primarykey"`
+	ResultID       uint   `json:"result_id"`
+	Width          int64  `json:"width"`
+	Height         int64  `json:"height"`
+	Screenshot     string `json:"screenshot"`
+	Hash           string `json:"hash" gorm:"index;size:64"`
+	PerceptionHash string `json:"perception_hash"`
+}
+
 // Technology represents a detected technology
 type Technology struct {
 	ID       uint   `json:"id" gorm:"primarykey"`
@@ -0,0 +1,110 @@
+// Package phash 计算截图的感知哈希，用于判断两张截图在视觉上是否相似
+package phash
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg" // 注册JPEG解码器
+	_ "image/png"  // 注册PNG解码器
+	"math/bits"
+	"strconv"
+)
+
+// 差异哈希使用9x8的灰度缩略图，比较每行相邻像素得到64位哈希
+const (
+	hashWidth  = 9
+	hashHeight = 8
+)
+
+// maxSamples 是计算每个缩略图像素时在每个方向上的最大采样数，避免大图计算过慢
+const maxSamples = 16
+
+// DefaultThreshold 是判断两张截图发生变化的默认汉明距离阈值
+const DefaultThreshold = 10
+
+// FromBytes 解码PNG或JPEG图片并计算差异哈希，返回16位十六进制字符串
+func FromBytes(data []byte) (string, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("解码图片失败: %v", err)
+	}
+	return Format(DHash(img)), nil
+}
+
+// DHash 计算图片的差异哈希
+func DHash(img image.Image) uint64 {
+	var gray [hashHeight][hashWidth]float64
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return 0
+	}
+
+	for y := 0; y < hashHeight; y++ {
+		y0 := bounds.Min.Y + y*height/hashHeight
+		y1 := bounds.Min.Y + (y+1)*height/hashHeight
+		for x := 0; x < hashWidth; x++ {
+			x0 := bounds.Min.X + x*width/hashWidth
+			x1 := bounds.Min.X + (x+1)*width/hashWidth
+			gray[y][x] = averageLuminance(img, x0, y0, x1, y1)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < hashHeight; y++ {
+		for x := 0; x < hashWidth-1; x++ {
+			hash <<= 1
+			if gray[y][x] < gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// averageLuminance 计算区域内采样像素的平均亮度
+func averageLuminance(img image.Image, x0, y0, x1, y1 int) float64 {
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+	stepX := max((x1-x0)/maxSamples, 1)
+	stepY := max((y1-y0)/maxSamples, 1)
+
+	var sum float64
+	var count int
+	for y := y0; y < y1; y += stepY {
+		for x := x0; x < x1; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			count++
+		}
+	}
+	return sum / float64(count)
+}
+
+// Format 将哈希格式化为16位十六进制字符串
+func Format(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// Parse 解析十六进制格式的哈希
+func Parse(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
+
+// Distance 计算两个十六进制哈希之间的汉明距离
+func Distance(a, b string) (int, error) {
+	ha, err := Parse(a)
+	if err != nil {
+		return 0, fmt.Errorf("无效的感知哈希: %s", a)
+	}
+	hb, err := Parse(b)
+	if err != nil {
+		return 0, fmt.Errorf("无效的感知哈希: %s", b)
+	}
+	return bits.OnesCount64(ha ^ hb), nil
+}
@@ -0,0 +1,463 @@
+// Package prune 按保留策略清理数据库中的截图记录和截图存储中的文件
+package prune
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyberspacesec/go-snir/pkg/database"
+	"github.com/cyberspacesec/go-snir/pkg/log"
+	"github.com/cyberspacesec/go-snir/pkg/storage"
+)
+
+// 清理原因
+const (
+	ReasonAge    = "age"    // 超过保留时间
+	ReasonCount  = "count"  // 超过每个URL保留的数量
+	ReasonSize   = "size"   // 超过总大小限制
+	ReasonOrphan = "orphan" // 文件未被任何记录引用
+)
+
+// fileExtensions 是截图存储中由扫描生成、允许被清理的文件类型
+var fileExtensions = []string{".png", ".jpg", ".jpeg", ".pdf", ".mhtml", ".har"}
+
+// Policy 保留策略，值为0的限制不生效
+type Policy struct {
+	MaxAge       time.Duration // 最长保留时间
+	KeepPerURL   int           // 每个URL保留的最新记录数
+	MaxTotalSize int64         // 截图文件总大小上限（字节），超过时从最旧的记录开始清理
+	Orphans      bool          // 是否清理截图存储中未被数据库引用的文件
+	OrphanGrace  time.Duration // 孤立文件的保留时间，正在进行的扫描已保存文件但尚未写入数据库，修改时间在此之内的文件不清理
+	DryRun       bool          // 只列出要清理的内容，不实际删除
+}
+
+// Enabled 判断策略是否设置了任何限制
+func (p Policy) Enabled() bool {
+	return p.MaxAge > 0 || p.KeepPerURL > 0 || p.MaxTotalSize > 0 || p.Orphans
+}
+
+// Item 表示一条要清理的记录或文件
+type Item struct {
+	ID       uint      `json:"id,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	ProbedAt time.Time `json:"probed_at"`
+	Files    []string  `json:"files,omitempty"`
+	Size     int64     `json:"size"`
+	Reason   string    `json:"reason"`
+}
+
+// Report 清理结果
+type Report struct {
+	DryRun       bool     `json:"dry_run"`
+	Items        []Item   `json:"items"`
+	DeletedRows  int      `json:"deleted_rows"`
+	DeletedFiles int      `json:"deleted_files"`
+	FreedBytes   int64    `json:"freed_bytes"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+// entry 表示参与保留策略计算的记录或文件
+type entry struct {
+	id       uint
+	url      string
+	probedAt time.Time
+	files    []file // 记录引用的截图、PDF和页面存档文件
+}
+
+// file 表示记录引用的一个文件
+type file struct {
+	location string // 扫描结果中保存的位置
+	key      string // 截图存储中的key
+	size     int64
+}
+
+// locations 返回记录引用的文件位置
+func (e entry) locations() []string {
+	locations := make([]string, 0, len(e.files))
+	for _, f := range e.files {
+		locations = append(locations, f.location)
+	}
+	return locations
+}
+
+// size 返回记录引用的文件总大小，同一个文件只计算一次
+func (e entry) size() int64 {
+	var size int64
+	for i, f := range e.files {
+		duplicate := false
+		for _, other := range e.files[:i] {
+			duplicate = duplicate || other.key == f.key
+		}
+		if !duplicate {
+			size += f.size
+		}
+	}
+	return size
+}
+
+// Run 按策略清理截图记录和截图存储中的文件。db为nil时只按文件修改时间和总大小清理截图存储
+// 先在事务中删除数据库记录，再删除不再被任何记录引用的文件；文件删除失败时记录错误，可由后续的孤立文件清理处理
+func Run(ctx context.Context, db *database.DB, store *storage.BlobStore, policy Policy, now time.Time) (*Report, error) {
+	report := &Report{DryRun: policy.DryRun, Items: []Item{}}
+
+	objects, err := listObjects(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64, len(objects))
+	for _, object := range objects {
+		sizes[object.Key] = object.Size
+	}
+
+	var entries []entry
+	if db != nil {
+		if entries, err = databaseEntries(db, store, sizes); err != nil {
+			return nil, err
+		}
+	} else {
+		if policy.KeepPerURL > 0 {
+			log.Warn("未启用数据库，忽略每个URL保留数量的限制")
+		}
+		entries = fileEntries(store, objects)
+	}
+
+	selected := selectEntries(entries, policy, db != nil, now)
+
+	// 统计每个文件被多少条记录引用，多条记录可能引用同一个文件
+	references := make(map[string]int)
+	for _, e := range entries {
+		if e.id == 0 {
+			continue
+		}
+		for _, f := range e.files {
+			references[f.key]++
+		}
+	}
+	remaining := make(map[string]int, len(references))
+	for key, count := range references {
+		remaining[key] = count
+	}
+
+	var ids []uint
+	var candidates []file
+	for _, e := range selected {
+		report.Items = append(report.Items, Item{
+			ID:       e.entry.id,
+			URL:      e.entry.url,
+			ProbedAt: e.entry.probedAt,
+			Files:    e.entry.locations(),
+			Size:     e.entry.size(),
+			Reason:   e.reason,
+		})
+		if e.entry.id != 0 {
+			ids = append(ids, e.entry.id)
+			for _, f := range e.entry.files {
+				remaining[f.key]--
+			}
+		}
+		candidates = append(candidates, e.entry.files...)
+	}
+
+	if db != nil && policy.Orphans {
+		for _, object := range objects {
+			if _, ok := references[object.Key]; ok {
+				continue
+			}
+			if now.Sub(object.ModTime) < policy.OrphanGrace {
+				continue
+			}
+			orphan := file{
+				location: store.Location(object.Key),
+				key:      object.Key,
+				size:     object.Size,
+			}
+			report.Items = append(report.Items, Item{
+				ProbedAt: object.ModTime,
+				Files:    []string{orphan.location},
+				Size:     orphan.size,
+				Reason:   ReasonOrphan,
+			})
+			candidates = append(candidates, orphan)
+		}
+	}
+
+	// 只删除不再被任何记录引用的文件，同一个文件可能以不同的位置保存在记录中
+	var removable []string
+	locations := make(map[string][]string)
+	for _, c := range candidates {
+		if remaining[c.key] > 0 {
+			continue
+		}
+		if _, ok := locations[c.key]; !ok {
+			removable = append(removable, c.key)
+		}
+		locations[c.key] = append(locations[c.key], c.location, store.Location(c.key))
+	}
+
+	if policy.DryRun {
+		for _, key := range removable {
+			report.FreedBytes += sizes[key]
+		}
+		return report, nil
+	}
+
+	if db != nil && len(ids) > 0 {
+		if err := db.DeleteScreenshots(ids); err != nil {
+			return nil, fmt.Errorf("删除截图记录失败: %v", err)
+		}
+		report.DeletedRows = len(ids)
+	}
+
+	// 清理期间可能有新的扫描结果引用了相同的文件，删除前再检查一次
+	referenced := map[string]bool{}
+	if db != nil && len(removable) > 0 {
+		var all []string
+		for _, key := range removable {
+			all = append(all, locations[key]...)
+		}
+		if referenced, err = db.GetReferencedFiles(all); err != nil {
+			return nil, fmt.Errorf("检查文件引用失败: %v", err)
+		}
+	}
+
+	for _, key := range removable {
+		inUse := false
+		for _, location := range locations[key] {
+			inUse = inUse || referenced[location]
+		}
+		if inUse {
+			continue
+		}
+		if _, ok := sizes[key]; !ok {
+			continue
+		}
+		if err := store.Backend().Delete(ctx, key); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("删除文件失败 %s: %v", key, err))
+			continue
+		}
+		report.DeletedFiles++
+		report.FreedBytes += sizes[key]
+	}
+
+	return report, nil
+}
+
+// selection 表示被选中清理的记录及原因
+type selection struct {
+	entry  entry
+	reason string
+}
+
+// selectEntries 按策略选出要清理的记录，entries按探测时间从新到旧排序
+func selectEntries(entries []entry, policy Policy, perURL bool, now time.Time) []selection {
+	var selected []selection
+	perURLCount := make(map[string]int)
+	countedFiles := make(map[string]bool)
+	var totalSize int64
+
+	for _, e := range entries {
+		reason := ""
+		if perURL {
+			perURLCount[e.url]++
+		}
+
+		switch {
+		case policy.MaxAge > 0 && now.Sub(e.probedAt) > policy.MaxAge:
+			reason = ReasonAge
+		case perURL && policy.KeepPerURL > 0 && perURLCount[e.url] > policy.KeepPerURL:
+			reason = ReasonCount
+		case policy.MaxTotalSize > 0:
+			// 共享同一文件的记录只计算一次大小
+			var size int64
+			for _, f := range e.files {
+				if !countedFiles[f.key] {
+					size += f.size
+				}
+			}
+			if totalSize+size > policy.MaxTotalSize {
+				reason = ReasonSize
+			} else {
+				totalSize += size
+				for _, f := range e.files {
+					countedFiles[f.key] = true
+				}
+			}
+		}
+
+		if reason != "" {
+			selected = append(selected, selection{entry: e, reason: reason})
+		}
+	}
+	return selected
+}
+
+// listObjects 列出截图存储中由扫描生成的文件
+func listObjects(ctx context.Context, store *storage.BlobStore) ([]storage.Object, error) {
+	objects, err := store.Backend().List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("读取截图存储失败: %v", err)
+	}
+
+	prunable := objects[:0]
+	for _, object := range objects {
+		if isPrunableFile(object.Key) {
+			prunable = append(prunable, object)
+		}
+	}
+	return prunable, nil
+}
+
+// databaseEntries 读取数据库中的截图记录及其文件大小
+func databaseEntries(db *database.DB, store *storage.BlobStore, sizes map[string]int64) ([]entry, error) {
+	screenshots, err := db.GetRetentionEntries()
+	if err != nil {
+		return nil, fmt.Errorf("读取截图记录失败: %v", err)
+	}
+
+	entries := make([]entry, 0, len(screenshots))
+	for _, s := range screenshots {
+		e := entry{
+			id:       s.ID,
+			url:      s.URL,
+			probedAt: s.ProbedAt,
+		}
+		for _, location := range s.Files() {
+			key := store.Key(location)
+			e.files = append(e.files, file{location: location, key: key, size: sizes[key]})
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// fileEntries 把截图存储中的文件按修改时间从新到旧排序
+func fileEntries(store *storage.BlobStore, objects []storage.Object) []entry {
+	entries := make([]entry, 0, len(objects))
+	for _, object := range objects {
+		entries = append(entries, entry{
+			probedAt: object.ModTime,
+			files: []file{{
+				location: store.Location(object.Key),
+				key:      object.Key,
+				size:     object.Size,
+			}},
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].probedAt.After(entries[j].probedAt)
+	})
+	return entries
+}
+
+// isPrunableFile 判断文件是否为扫描生成的截图文件
+func isPrunableFile(key string) bool {
+	ext := strings.ToLower(path.Ext(key))
+	for _, valid := range fileExtensions {
+		if ext == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// StartBackground 在后台定期执行清理，ctx取消时停止
+func StartBackground(ctx context.Context, db *database.DB, store *storage.BlobStore, policy Policy, interval time.Duration) {
+	if interval <= 0 || !policy.Enabled() {
+		return
+	}
+
+	log.Info("已启用定期清理", "interval", interval.String())
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			report, err := Run(ctx, db, store, policy, time.Now())
+			if err != nil {
+				log.Error("定期清理失败", "error", err)
+			} else if len(report.Items) > 0 {
+				log.Info("定期清理完成",
+					"rows", report.DeletedRows,
+					"files", report.DeletedFiles,
+					"freed", FormatSize(report.FreedBytes))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// ParseDuration 解析保留时间，在time.ParseDuration的基础上支持以d结尾的天数
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" {
+		return 0, nil
+	}
+	if days, found := strings.CutSuffix(value, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("无效的时间: %s", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("无效的时间: %s", value)
+	}
+	return d, nil
+}
+
+// sizeUnits 是支持的大小单位
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize 解析大小，支持 B、KB、MB、GB、TB 单位，不带单位时为字节
+func ParseSize(input string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(input))
+	if value == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if number, found := strings.CutSuffix(value, unit.suffix); found {
+			value = strings.TrimSpace(number)
+			multiplier = unit.bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("无效的大小: %s", input)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// FormatSize 将字节数格式化为便于阅读的大小
+func FormatSize(size int64) string {
+	for _, unit := range sizeUnits {
+		if size >= unit.bytes && unit.bytes > 1 {
+			return fmt.Sprintf("%.1f%s", float64(size)/float64(unit.bytes), unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", size)
+}
@@ -0,0 +1,51 @@
+package prune
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cyberspacesec/go-snir/pkg/database"
+	"github.com/cyberspacesec/go-snir/pkg/storage"
+)
+
+func TestOrphanGrace(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	store := storage.NewBlobStore(storage.NewLocal(root))
+	db, err := database.NewDB(database.Options{Path: filepath.Join(t.TempDir(), "snir.db")})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	files := map[string]time.Time{
+		"old.png":      now.Add(-2 * time.Hour),    // 超过保留时间的孤立文件
+		"scanning.png": now.Add(-10 * time.Minute), // 正在进行的扫描刚保存、尚未写入数据库的文件
+	}
+	for key, modTime := range files {
+		if err := store.Backend().Put(ctx, key, []byte("png"), "image/png"); err != nil {
+			t.Fatalf("保存文件失败: %v", err)
+		}
+		if err := os.Chtimes(filepath.Join(root, key), modTime, modTime); err != nil {
+			t.Fatalf("设置修改时间失败: %v", err)
+		}
+	}
+
+	report, err := Run(ctx, db, store, Policy{Orphans: true, OrphanGrace: time.Hour}, now)
+	if err != nil {
+		t.Fatalf("清理失败: %v", err)
+	}
+	if report.DeletedFiles != 1 || len(report.Items) != 1 || report.Items[0].Reason != ReasonOrphan {
+		t.Fatalf("清理结果为 %+v，应只清理一个孤立文件", report)
+	}
+	if _, err := os.Stat(filepath.Join(root, "old.png")); !os.IsNotExist(err) {
+		t.Error("超过保留时间的孤立文件没有被清理")
+	}
+	if _, err := os.Stat(filepath.Join(root, "scanning.png")); err != nil {
+		t.Errorf("保留时间内的文件被清理: %v", err)
+	}
+}
@@ -14,12 +14,14 @@ import (
 
 // ConvertOptions 包含报告转换选项
 type ConvertOptions struct {
-	FromFile string // 输入文件
-	ToFile   string // 输出文件
+	FromFile       string // 输入文件
+	ToFile         string // 输出文件
+	ScreenshotPath string // 截图目录，输出HAR文件时从中读取每个结果的HAR文件
+	Storage        string // 截图存储URI
 }
 
 // 支持的文件扩展名
-var supportedExtensions = []string{".sqlite3", ".db", ".jsonl", ".csv"}
+var supportedExtensions = []string{".sqlite3", ".db", ".jsonl", ".csv", ".har"}
 
 // Convert 转换报告格式
 func Convert(options ConvertOptions) error {
@@ -43,7 +45,7 @@ func Convert(options ConvertOptions) error {
 	toExt := strings.ToLower(filepath.Ext(options.ToFile))
 
 	// 验证文件扩展名
-	if !isValidExtension(fromExt) {
+	if !isValidExtension(fromExt) || fromExt == ".har" {
 		return fmt.Errorf("不支持的输入文件格式: %s", fromExt)
 	}
 	if !isValidExtension(toExt) {
@@ -56,8 +58,13 @@ func Convert(options ConvertOptions) error {
 		return fmt.Errorf("读取输入文件失败: %v", err)
 	}
 
-	// 写入结果
-	if err := writeResults(options.ToFile, toExt, results); err != nil {
+	// 写入结果，HAR文件需要从截图存储中读取每个结果的网络活动记录
+	if toExt == ".har" {
+		err = writeHAR(options, results)
+	} else {
+		err = writeResults(options.ToFile, toExt, results)
+	}
+	if err != nil {
 		return fmt.Errorf("写入输出文件失败: %v", err)
 	}
 
@@ -0,0 +1,58 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cyberspacesec/go-snir/pkg/har"
+	"github.com/cyberspacesec/go-snir/pkg/islazy"
+	"github.com/cyberspacesec/go-snir/pkg/log"
+	"github.com/cyberspacesec/go-snir/pkg/models"
+	"github.com/cyberspacesec/go-snir/pkg/storage"
+)
+
+// writeHAR 把所有结果的网络活动合并写入一个HAR文件
+// 结果保存了HAR文件时使用完整记录，否则根据网络请求日志生成简化记录
+func writeHAR(options ConvertOptions, results []*models.Result) error {
+	store, err := storage.OpenBlobStore(options.Storage, options.ScreenshotPath)
+	if err != nil {
+		return fmt.Errorf("打开截图存储失败: %v", err)
+	}
+
+	logs := make([]*har.Log, 0, len(results))
+	for _, result := range results {
+		harLog, err := readResultHAR(store, result)
+		if err != nil {
+			log.Warn("读取HAR文件失败，使用网络请求日志", "url", result.URL, "file", result.HAR, "error", err)
+			harLog = nil
+		}
+		if harLog == nil {
+			harLog = har.FromResult(result)
+		}
+		logs = append(logs, harLog)
+	}
+
+	data, err := har.Marshal(har.Merge(logs))
+	if err != nil {
+		return fmt.Errorf("生成HAR文件失败: %v", err)
+	}
+	if _, err := islazy.CreateDir(filepath.Dir(options.ToFile)); err != nil {
+		return fmt.Errorf("创建输出目录失败: %v", err)
+	}
+	return os.WriteFile(options.ToFile, data, 0644)
+}
+
+// readResultHAR 读取结果保存的HAR文件，结果没有HAR文件时返回nil
+func readResultHAR(store *storage.BlobStore, result *models.Result) (*har.Log, error) {
+	location := resolveFile(store, result.HAR, result.HARHash)
+	if location == "" {
+		return nil, nil
+	}
+	data, err := store.Get(context.Background(), store.Key(location))
+	if err != nil {
+		return nil, err
+	}
+	return har.Unmarshal(data)
+}
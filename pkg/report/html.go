@@ -2,38 +2,66 @@ package report
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/cyberspacesec/go-snir/pkg/islazy"
 	"github.com/cyberspacesec/go-snir/pkg/log"
 	"github.com/cyberspacesec/go-snir/pkg/models"
+	"github.com/cyberspacesec/go-snir/pkg/storage"
 )
 
 // HTMLOptions 包含HTML报告选项
 type HTMLOptions struct {
-	InputFile  string // 输入文件
-	OutputPath string // 输出路径
+	InputFile      string // 输入文件
+	OutputPath     string // 输出路径
+	ScreenshotPath string // 截图目录，截图文件已移动时按内容哈希在此目录中查找
+	Storage        string // 截图存储URI，使用对象存储时报告中引用带签名的截图链接
 }
 
 // ReportData 表示报告数据结构
 type ReportData struct {
 	GeneratedAt string
 	Results     []ReportResult
+	Tags        []ReportTag
+}
+
+// ReportTag 表示报告中的标签及其数量，用于筛选
+type ReportTag struct {
+	Name  string
+	Count int
 }
 
 // ReportResult 表示报告结果项
 type ReportResult struct {
 	URL             string
 	Title           string
+	Device          string
+	Proxy           string
+	Source          string
 	Screenshot      string
+	PDF             string
+	Archive         string
+	ArchiveHash     string
+	HAR             string
+	Viewports       []ReportViewport
 	ResponseCode    int
 	StatusCodeClass string
 	ProbedAt        time.Time
+	Tags            []string
+}
+
+// ReportViewport 表示报告中的一个多视口截图
+type ReportViewport struct {
+	Size       string
+	Screenshot string
 }
 
 // HTMLTemplate 是HTML报告模板
@@ -95,6 +123,28 @@ const HTMLTemplate = `<!DOCTYPE html>
         .screenshot-info {
             padding: 15px;
         }
+        .viewport-strip {
+            display: flex;
+            gap: 8px;
+            align-items: flex-start;
+            padding: 10px 15px 0;
+        }
+        .viewport-strip a {
+            flex: 1;
+            min-width: 0;
+            text-align: center;
+            font-size: 0.75em;
+            color: #7f8c8d;
+            text-decoration: none;
+        }
+        .viewport-strip img {
+            display: block;
+            width: 100%;
+            max-height: 160px;
+            object-fit: contain;
+            object-position: top;
+            border: 1px solid #eee;
+        }
         .screenshot-title {
             font-weight: bold;
             margin-bottom: 10px;
@@ -137,6 +187,31 @@ const HTMLTemplate = `<!DOCTYPE html>
             background-color: #95a5a6;
             color: white;
         }
+        .tag-filter {
+            margin-bottom: 20px;
+        }
+        .tag-filter button {
+            border: 1px solid #3498db;
+            background-color: #fff;
+            color: #3498db;
+            border-radius: 3px;
+            padding: 4px 10px;
+            margin: 0 5px 5px 0;
+            cursor: pointer;
+        }
+        .tag-filter button.active {
+            background-color: #3498db;
+            color: #fff;
+        }
+        .tag {
+            display: inline-block;
+            padding: 2px 6px;
+            margin: 5px 5px 0 0;
+            border-radius: 3px;
+            font-size: 0.8em;
+            background-color: #ecf0f1;
+            color: #2c3e50;
+        }
     </style>
 </head>
 <body>
@@ -146,10 +221,15 @@ const HTMLTemplate = `<!DOCTYPE html>
             <p><strong>生成时间:</strong> {{.GeneratedAt}}</p>
             <p><strong>总计截图:</strong> {{len .Results}}</p>
         </div>
-        
+        {{if .Tags}}
+        <div class="tag-filter">
+            <button class="active" data-tag="">全部 ({{len .Results}})</button>
+            {{range .Tags}}<button data-tag="{{.Name}}">{{.Name}} ({{.Count}})</button>{{end}}
+        </div>
+        {{end}}
         <div class="screenshot-grid">
             {{range .Results}}
-            <div class="screenshot-item">
+            <div class="screenshot-item" data-tags="{{join .Tags ","}}">
                 {{if .Screenshot}}
                 <img src="{{.Screenshot}}" alt="{{.Title}}" class="screenshot-img">
                 {{else}}
@@ -157,18 +237,46 @@ const HTMLTemplate = `<!DOCTYPE html>
                     <span>无截图</span>
                 </div>
                 {{end}}
+                {{if .Viewports}}
+                <div class="viewport-strip">
+                    {{range .Viewports}}<a href="{{.Screenshot}}" target="_blank"><img src="{{.Screenshot}}" alt="{{.Size}}">{{.Size}}</a>{{end}}
+                </div>
+                {{end}}
                 <div class="screenshot-info">
                     <div class="screenshot-title">{{if .Title}}{{.Title}}{{else}}无标题{{end}}</div>
                     <div class="screenshot-url">{{.URL}}</div>
                     <div class="screenshot-meta">
                         <span class="status-code status-{{.StatusCodeClass}}">{{.ResponseCode}}</span>
                         <span>{{.ProbedAt.Format "2006-01-02 15:04:05"}}</span>
+                        {{if .Device}}<span>{{.Device}}</span>{{end}}
+                        {{if .Proxy}}<span title="代理">{{.Proxy}}</span>{{end}}
+                        {{if .Source}}<span title="目标来源">{{.Source}}</span>{{end}}
+                        {{if .PDF}}<a href="{{.PDF}}" target="_blank">PDF</a>{{end}}
+                        {{if .Archive}}<a href="{{.Archive}}" title="SHA-256: {{.ArchiveHash}}" download>MHTML</a>{{end}}
+                        {{if .HAR}}<a href="{{.HAR}}" download>HAR</a>{{end}}
                     </div>
+                    {{range .Tags}}<span class="tag">{{.}}</span>{{end}}
                 </div>
             </div>
             {{end}}
         </div>
     </div>
+    <script>
+        // 按标签筛选截图
+        document.querySelectorAll('.tag-filter button').forEach(function(button) {
+            button.addEventListener('click', function() {
+                var tag = this.getAttribute('data-tag');
+                document.querySelectorAll('.tag-filter button').forEach(function(b) {
+                    b.classList.remove('active');
+                });
+                this.classList.add('active');
+                document.querySelectorAll('.screenshot-item').forEach(function(item) {
+                    var tags = item.getAttribute('data-tags').split(',');
+                    item.style.display = (tag === '' || tags.indexOf(tag) !== -1) ? '' : 'none';
+                });
+            });
+        });
+    </script>
 </body>
 </html>`
 
@@ -184,9 +292,9 @@ func GenerateHTML(options HTMLOptions) error {
 		return fmt.Errorf("输入文件不存在: %s", options.InputFile)
 	}
 
-	// 读取JSONL文件
+	// 按扩展名读取结果文件，数据库文件中包含标签信息
 	log.Info("读取结果文件", "file", options.InputFile)
-	results, err := ReadJSONLResults(options.InputFile)
+	results, err := readResults(options.InputFile, strings.ToLower(filepath.Ext(options.InputFile)))
 	if err != nil {
 		return fmt.Errorf("读取结果文件失败: %v", err)
 	}
@@ -203,8 +311,18 @@ func GenerateHTML(options HTMLOptions) error {
 		Results:     make([]ReportResult, 0, len(results)),
 	}
 
+	store, err := storage.OpenBlobStore(options.Storage, options.ScreenshotPath)
+	if err != nil {
+		return fmt.Errorf("打开截图存储失败: %v", err)
+	}
+
 	// 处理每个结果
+	tagCounts := make(map[string]int)
 	for _, result := range results {
+		for _, tag := range result.Tags {
+			tagCounts[tag]++
+		}
+
 		// 获取状态码类别
 		statusClass := "0"
 		if result.ResponseCode >= 200 && result.ResponseCode < 300 {
@@ -217,28 +335,45 @@ func GenerateHTML(options HTMLOptions) error {
 			statusClass = "5xx"
 		}
 
-		// 处理截图路径，使其相对于报告文件
-		screenshotPath := result.Filename
-		if screenshotPath != "" {
-			// 如果是绝对路径，转换为相对路径
-			if filepath.IsAbs(screenshotPath) {
-				relPath, err := filepath.Rel(filepath.Dir(options.OutputPath), screenshotPath)
-				if err == nil {
-					screenshotPath = relPath
-				}
-			}
+		// 处理截图和PDF路径，使其相对于报告文件；使用对象存储时引用带签名的链接
+		screenshotPath := reportLink(store, resolveFile(store, result.Filename, result.ScreenshotHash), options.OutputPath)
+		pdfPath := reportLink(store, resolveFile(store, result.PDF, result.PDFHash), options.OutputPath)
+		archivePath := reportLink(store, resolveFile(store, result.Archive, result.ArchiveHash), options.OutputPath)
+		harPath := reportLink(store, resolveFile(store, result.HAR, result.HARHash), options.OutputPath)
+		viewports := make([]ReportViewport, 0, len(result.Viewports))
+		for _, viewport := range result.Viewports {
+			viewports = append(viewports, ReportViewport{
+				Size:       fmt.Sprintf("%dx%d", viewport.Width, viewport.Height),
+				Screenshot: reportLink(store, resolveFile(store, viewport.Screenshot, viewport.Hash), options.OutputPath),
+			})
 		}
 
 		reportData.Results = append(reportData.Results, ReportResult{
 			URL:             result.URL,
 			Title:           result.Title,
+			Device:          result.Device,
+			Proxy:           result.Proxy,
+			Source:          result.Source,
 			Screenshot:      screenshotPath,
+			PDF:             pdfPath,
+			Archive:         archivePath,
+			ArchiveHash:     result.ArchiveHash,
+			HAR:             harPath,
+			Viewports:       viewports,
 			ResponseCode:    result.ResponseCode,
 			StatusCodeClass: statusClass,
 			ProbedAt:        result.ProbedAt,
+			Tags:            result.Tags,
 		})
 	}
 
+	for name, count := range tagCounts {
+		reportData.Tags = append(reportData.Tags, ReportTag{Name: name, Count: count})
+	}
+	sort.Slice(reportData.Tags, func(i, j int) bool {
+		return reportData.Tags[i].Name < reportData.Tags[j].Name
+	})
+
 	// 确保输出目录存在
 	outputDir := filepath.Dir(options.OutputPath)
 	if _, err := islazy.CreateDir(outputDir); err != nil {
@@ -253,7 +388,9 @@ func GenerateHTML(options HTMLOptions) error {
 	defer outputFile.Close()
 
 	// 解析模板
-	tmpl, err := template.New("report").Parse(HTMLTemplate)
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(HTMLTemplate)
 	if err != nil {
 		return fmt.Errorf("解析报告模板失败: %v", err)
 	}
@@ -301,3 +438,42 @@ func ReadJSONLResults(filePath string) ([]*models.Result, error) {
 
 	return results, nil
 }
+
+// signedURLExpiry 是报告中对象存储截图链接的有效期
+const signedURLExpiry = 7 * 24 * time.Hour
+
+// resolveFile 返回结果中截图或PDF文件的位置
+// 使用本地存储且记录中的路径不存在时，按内容哈希在截图目录中查找
+func resolveFile(store *storage.BlobStore, location, hash string) string {
+	if location == "" || !store.IsLocal() || islazy.FileExists(location) || hash == "" {
+		return location
+	}
+	path := store.Location(storage.BlobKey(hash, filepath.Ext(location)))
+	if islazy.FileExists(path) {
+		return path
+	}
+	return location
+}
+
+// reportLink 返回报告中引用文件的链接，本地文件使用相对于报告文件的路径，对象存储使用带签名的链接
+func reportLink(store *storage.BlobStore, location, outputPath string) string {
+	if location == "" {
+		return ""
+	}
+	if !store.IsLocal() {
+		signedURL, err := store.Backend().SignedURL(context.Background(), store.Key(location), signedURLExpiry)
+		if err != nil {
+			log.Warn("生成文件链接失败", "file", location, "error", err)
+		}
+		return signedURL
+	}
+
+	outputDir, errOut := filepath.Abs(filepath.Dir(outputPath))
+	absPath, errAbs := filepath.Abs(location)
+	if errOut == nil && errAbs == nil {
+		if relPath, err := filepath.Rel(outputDir, absPath); err == nil {
+			return filepath.ToSlash(relPath)
+		}
+	}
+	return location
+}
@@ -1,14 +1,23 @@
 package report
 
 import (
+	"context"
 	"fmt"
+	"html"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/cyberspacesec/go-snir/pkg/database"
 	"github.com/cyberspacesec/go-snir/pkg/islazy"
 	"github.com/cyberspacesec/go-snir/pkg/log"
+	"github.com/cyberspacesec/go-snir/pkg/models"
+	"github.com/cyberspacesec/go-snir/pkg/prune"
+	"github.com/cyberspacesec/go-snir/pkg/storage"
 )
 
 // ServerOptions 包含Web服务器选项
@@ -17,11 +26,17 @@ type ServerOptions struct {
 	Port           int
 	ScreenshotPath string
 	ReportPath     string
+	Storage        string        // 截图存储URI，为空时使用本地截图目录
+	DBPath         string        // 数据库路径，设置后从数据库读取截图并支持按标签筛选
+	Prune          prune.Policy  // 保留策略
+	PruneInterval  time.Duration // 定期清理的间隔，为0时不启用
 }
 
 // Server 表示Web服务器
 type Server struct {
 	Options ServerOptions
+	db      *database.DB
+	store   *storage.BlobStore
 }
 
 // NewServer 创建一个新的Web服务器
@@ -33,11 +48,20 @@ func NewServer(options ServerOptions) *Server {
 
 // Run 启动Web服务器
 func (s *Server) Run() error {
-	// 确保截图目录存在
-	screenshotPath, err := islazy.CreateDir(s.Options.ScreenshotPath)
+	// 打开截图存储，使用本地目录时确保目录存在
+	screenshotPath := s.Options.ScreenshotPath
+	if s.Options.Storage == "" {
+		path, err := islazy.CreateDir(screenshotPath)
+		if err != nil {
+			return fmt.Errorf("创建截图目录失败: %v", err)
+		}
+		screenshotPath = path
+	}
+	store, err := storage.OpenBlobStore(s.Options.Storage, screenshotPath)
 	if err != nil {
-		return fmt.Errorf("创建截图目录失败: %v", err)
+		return fmt.Errorf("打开截图存储失败: %v", err)
 	}
+	s.store = store
 
 	// 确保报告目录存在
 	reportPath, err := islazy.CreateDir(s.Options.ReportPath)
@@ -45,13 +69,28 @@ func (s *Server) Run() error {
 		return fmt.Errorf("创建报告目录失败: %v", err)
 	}
 
+	// 打开数据库
+	if s.Options.DBPath != "" {
+		db, err := database.NewDB(database.Options{Path: s.Options.DBPath})
+		if err != nil {
+			return fmt.Errorf("连接数据库失败: %v", err)
+		}
+		defer db.Close()
+		s.db = db
+	}
+
+	// 按保留策略定期清理旧的结果和截图文件
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	prune.StartBackground(ctx, s.db, s.store, s.Options.Prune, s.Options.PruneInterval)
+
 	// 设置HTTP处理函数
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		s.serveIndex(w, r, screenshotPath, reportPath)
+		s.serveIndex(w, r, reportPath)
 	})
 
 	// 设置静态文件服务
-	http.Handle("/screenshots/", http.StripPrefix("/screenshots/", http.FileServer(http.Dir(screenshotPath))))
+	http.Handle("/screenshots/", http.StripPrefix("/screenshots/", s.store))
 	http.Handle("/reports/", http.StripPrefix("/reports/", http.FileServer(http.Dir(reportPath))))
 
 	// 启动服务器
@@ -63,7 +102,7 @@ func (s *Server) Run() error {
 }
 
 // serveIndex 处理首页请求
-func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request, screenshotPath, reportPath string) {
+func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request, reportPath string) {
 	// 如果请求的不是根路径，返回404
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
@@ -71,12 +110,28 @@ func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request, screenshotPa
 	}
 
 	// 获取截图文件列表
-	screenshots, err := getFiles(screenshotPath, ".png", ".jpeg", ".jpg")
+	screenshots, err := s.listScreenshots(r.Context())
 	if err != nil {
 		http.Error(w, "获取截图列表失败", http.StatusInternalServerError)
 		return
 	}
 
+	// 从数据库读取截图结果和标签
+	var results []*models.Result
+	var tagCounts []database.TagCount
+	tag := r.URL.Query().Get("tag")
+	if s.db != nil {
+		results, tagCounts, err = s.loadResults(tag)
+		if err != nil {
+			http.Error(w, "读取数据库失败", http.StatusInternalServerError)
+			return
+		}
+	}
+	screenshotCount := len(screenshots)
+	if s.db != nil {
+		screenshotCount = len(results)
+	}
+
 	// 获取报告文件列表
 	reports, err := getFiles(reportPath, ".json", ".csv", ".html")
 	if err != nil {
@@ -100,6 +155,8 @@ func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request, screenshotPa
 	fmt.Fprintf(w, "    .screenshot { border: 1px solid #ddd; padding: 10px; border-radius: 5px; }\n")
 	fmt.Fprintf(w, "    .screenshot img { width: 100%%; height: auto; }\n")
 	fmt.Fprintf(w, "    .screenshot-info { margin-top: 10px; }\n")
+	fmt.Fprintf(w, "    .viewports { display: flex; gap: 8px; align-items: flex-start; margin-top: 8px; }\n")
+	fmt.Fprintf(w, "    .viewports a { flex: 1; min-width: 0; text-align: center; font-size: 0.75em; color: #666; text-decoration: none; }\n")
 	fmt.Fprintf(w, "    .reports { margin-top: 30px; }\n")
 	fmt.Fprintf(w, "    .report-item { margin-bottom: 10px; }\n")
 	fmt.Fprintf(w, "    .tabs { display: flex; margin-bottom: 20px; }\n")
@@ -107,34 +164,36 @@ func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request, screenshotPa
 	fmt.Fprintf(w, "    .tab.active { background: #007bff; color: white; }\n")
 	fmt.Fprintf(w, "    .tab-content { display: none; }\n")
 	fmt.Fprintf(w, "    .tab-content.active { display: block; }\n")
+	fmt.Fprintf(w, "    .tag-filter { margin-bottom: 20px; }\n")
+	fmt.Fprintf(w, "    .tag-filter a { display: inline-block; padding: 4px 10px; margin: 0 5px 5px 0; border: 1px solid #007bff; border-radius: 3px; color: #007bff; text-decoration: none; }\n")
+	fmt.Fprintf(w, "    .tag-filter a.active { background: #007bff; color: white; }\n")
+	fmt.Fprintf(w, "    .tag { display: inline-block; padding: 2px 6px; margin-right: 5px; border-radius: 3px; background: #f1f1f1; font-size: 0.8em; }\n")
 	fmt.Fprintf(w, "  </style>\n")
 	fmt.Fprintf(w, "</head>\n")
 	fmt.Fprintf(w, "<body>\n")
 	fmt.Fprintf(w, "  <div class=\"container\">\n")
 	fmt.Fprintf(w, "    <h1>Go Web Screenshot - 结果查看器</h1>\n")
 	fmt.Fprintf(w, "    <div class=\"tabs\">\n")
-	fmt.Fprintf(w, "      <div class=\"tab active\" data-tab=\"screenshots\">截图 (%d)</div>\n", len(screenshots))
+	fmt.Fprintf(w, "      <div class=\"tab active\" data-tab=\"screenshots\">截图 (%d)</div>\n", screenshotCount)
 	fmt.Fprintf(w, "      <div class=\"tab\" data-tab=\"reports\">报告 (%d)</div>\n", len(reports))
 	fmt.Fprintf(w, "    </div>\n")
 
 	// 截图标签页内容
 	fmt.Fprintf(w, "    <div id=\"screenshots\" class=\"tab-content active\">\n")
-	if len(screenshots) > 0 {
+	if s.db != nil {
+		s.writeResultList(w, results, tagCounts, tag)
+	} else if len(screenshots) > 0 {
 		fmt.Fprintf(w, "      <div class=\"screenshots\">\n")
 		for _, screenshot := range screenshots {
-			fileName := filepath.Base(screenshot)
-			fileInfo, err := os.Stat(screenshot)
-			modTime := ""
-			if err == nil {
-				modTime = fileInfo.ModTime().Format("2006-01-02 15:04:05")
-			}
+			fileName := path.Base(screenshot.Key)
+			modTime := screenshot.ModTime.Format("2006-01-02 15:04:05")
 
 			// 从文件名中提取URL（假设文件名格式为URL_时间戳.扩展名）
 			urlPart := strings.Split(fileName, "_")[0]
 			urlPart = strings.ReplaceAll(urlPart, "_", "/")
 
 			fmt.Fprintf(w, "        <div class=\"screenshot\">\n")
-			fmt.Fprintf(w, "          <img src=\"/screenshots/%s\" alt=\"%s\">\n", fileName, fileName)
+			fmt.Fprintf(w, "          <img src=\"/screenshots/%s\" alt=\"%s\">\n", screenshot.Key, fileName)
 			fmt.Fprintf(w, "          <div class=\"screenshot-info\">\n")
 			fmt.Fprintf(w, "            <div><strong>文件:</strong> %s</div>\n", fileName)
 			fmt.Fprintf(w, "            <div><strong>URL:</strong> %s</div>\n", urlPart)
@@ -197,6 +256,122 @@ func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request, screenshotPa
 	fmt.Fprintf(w, "</html>\n")
 }
 
+// loadResults 从数据库读取截图结果，tag不为空时只返回带有该标签的结果
+func (s *Server) loadResults(tag string) ([]*models.Result, []database.TagCount, error) {
+	tagCounts, err := s.db.GetTagCounts()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var screenshots []*database.Screenshot
+	if tag != "" {
+		screenshots, err = s.db.GetScreenshotsByTag(tag)
+	} else {
+		screenshots, err = s.db.GetAllScreenshots()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]*models.Result, 0, len(screenshots))
+	for _, screenshot := range screenshots {
+		results = append(results, screenshot.ToResult())
+	}
+	return results, tagCounts, nil
+}
+
+// writeResultList 输出数据库中的截图结果列表及标签筛选链接
+func (s *Server) writeResultList(w http.ResponseWriter, results []*models.Result, tagCounts []database.TagCount, activeTag string) {
+	if len(tagCounts) > 0 {
+		fmt.Fprintf(w, "      <div class=\"tag-filter\">\n")
+		class := ""
+		if activeTag == "" {
+			class = " class=\"active\""
+		}
+		fmt.Fprintf(w, "        <a href=\"/\"%s>全部</a>\n", class)
+		for _, tagCount := range tagCounts {
+			class = ""
+			if tagCount.Name == activeTag {
+				class = " class=\"active\""
+			}
+			fmt.Fprintf(w, "        <a href=\"/?tag=%s\"%s>%s (%d)</a>\n",
+				url.QueryEscape(tagCount.Name), class, html.EscapeString(tagCount.Name), tagCount.Count)
+		}
+		fmt.Fprintf(w, "      </div>\n")
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintf(w, "      <p>数据库中没有匹配的截图结果。</p>\n")
+		return
+	}
+
+	fmt.Fprintf(w, "      <div class=\"screenshots\">\n")
+	for _, result := range results {
+		fmt.Fprintf(w, "        <div class=\"screenshot\">\n")
+		if result.Filename != "" {
+			key := s.store.Key(result.Filename)
+			fmt.Fprintf(w, "          <img src=\"/screenshots/%s\" alt=\"%s\">\n",
+				(&url.URL{Path: key}).EscapedPath(), html.EscapeString(filepath.Base(key)))
+		}
+		if len(result.Viewports) > 0 {
+			fmt.Fprintf(w, "          <div class=\"viewports\">\n")
+			for _, viewport := range result.Viewports {
+				src := (&url.URL{Path: s.store.Key(viewport.Screenshot)}).EscapedPath()
+				fmt.Fprintf(w, "            <a href=\"/screenshots/%s\" target=\"_blank\"><img src=\"/screenshots/%s\" alt=\"%dx%d\">%dx%d</a>\n",
+					src, src, viewport.Width, viewport.Height, viewport.Width, viewport.Height)
+			}
+			fmt.Fprintf(w, "          </div>\n")
+		}
+		fmt.Fprintf(w, "          <div class=\"screenshot-info\">\n")
+		fmt.Fprintf(w, "            <div><strong>标题:</strong> %s</div>\n", html.EscapeString(result.Title))
+		fmt.Fprintf(w, "            <div><strong>URL:</strong> %s</div>\n", html.EscapeString(result.URL))
+		fmt.Fprintf(w, "            <div><strong>状态码:</strong> %d</div>\n", result.ResponseCode)
+		fmt.Fprintf(w, "            <div><strong>时间:</strong> %s</div>\n", result.ProbedAt.Format("2006-01-02 15:04:05"))
+		if result.Device != "" {
+			fmt.Fprintf(w, "            <div><strong>设备:</strong> %s</div>\n", html.EscapeString(result.Device))
+		}
+		if result.PDF != "" {
+			fmt.Fprintf(w, "            <div><a href=\"/screenshots/%s\" target=\"_blank\">PDF</a></div>\n",
+				(&url.URL{Path: s.store.Key(result.PDF)}).EscapedPath())
+		}
+		if result.Archive != "" {
+			fmt.Fprintf(w, "            <div><a href=\"/screenshots/%s\" title=\"SHA-256: %s\" download>MHTML</a></div>\n",
+				(&url.URL{Path: s.store.Key(result.Archive)}).EscapedPath(), html.EscapeString(result.ArchiveHash))
+		}
+		if result.HAR != "" {
+			fmt.Fprintf(w, "            <div><a href=\"/screenshots/%s\" download>HAR</a></div>\n",
+				(&url.URL{Path: s.store.Key(result.HAR)}).EscapedPath())
+		}
+		if len(result.Tags) > 0 {
+			fmt.Fprintf(w, "            <div>")
+			for _, tag := range result.Tags {
+				fmt.Fprintf(w, "<span class=\"tag\">%s</span>", html.EscapeString(tag))
+			}
+			fmt.Fprintf(w, "</div>\n")
+		}
+		fmt.Fprintf(w, "          </div>\n")
+		fmt.Fprintf(w, "        </div>\n")
+	}
+	fmt.Fprintf(w, "      </div>\n")
+}
+
+// listScreenshots 列出截图存储中的图片文件
+func (s *Server) listScreenshots(ctx context.Context) ([]storage.Object, error) {
+	objects, err := s.store.Backend().List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var screenshots []storage.Object
+	for _, object := range objects {
+		switch strings.ToLower(path.Ext(object.Key)) {
+		case ".png", ".jpeg", ".jpg":
+			screenshots = append(screenshots, object)
+		}
+	}
+	return screenshots, nil
+}
+
 // getFiles 获取指定目录下的指定扩展名的文件列表
 func getFiles(dir string, extensions ...string) ([]string, error) {
 	var files []string
@@ -0,0 +1,23 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// captureArchive 返回把当前页面保存为MHTML存档的任务，存档内容写入buf，采集时间写入archivedAt
+func captureArchive(buf *[]byte, archivedAt *time.Time) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		data, err := page.CaptureSnapshot().WithFormat(page.CaptureSnapshotFormatMhtml).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("保存页面存档失败: %v", err)
+		}
+		*archivedAt = time.Now()
+		*buf = []byte(data)
+		return nil
+	})
+}
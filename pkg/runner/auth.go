@@ -0,0 +1,282 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// redacted 替换日志和扫描会话中的密码
+const redacted = "xxxxx"
+
+// Credential 表示HTTP身份验证凭据，支持Basic、Digest和NTLM（用户名可写为 域\用户名）
+type Credential struct {
+	Host     string `json:"host,omitempty"` // 主机模式，如 *.corp.example.com，为空时对所有主机生效
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// String 返回隐藏了密码的凭据描述，避免凭据被写入日志
+func (c Credential) String() string {
+	host := c.Host
+	if host == "" {
+		host = "*"
+	}
+	return fmt.Sprintf("%s=%s:%s", host, c.Username, redacted)
+}
+
+// matches 检查凭据是否适用于主机
+func (c Credential) matches(host string) bool {
+	return c.Host == "" || matchHost(c.Host, host)
+}
+
+// ParseCredentials 解析 "用户名:密码" 或 "主机模式=用户名:密码" 格式的凭据
+// 用户名中不能包含冒号和等号，密码可以包含任意字符；错误信息中不包含凭据内容
+func ParseCredentials(values []string) ([]Credential, error) {
+	credentials := make([]Credential, 0, len(values))
+	for i, value := range values {
+		credential, err := parseCredential(value)
+		if err != nil {
+			return nil, fmt.Errorf("第%d个身份验证凭据无效: %v", i+1, err)
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
+}
+
+// parseCredential 解析单条凭据
+func parseCredential(value string) (Credential, error) {
+	var credential Credential
+	user, password, ok := strings.Cut(value, ":")
+	if !ok {
+		return credential, fmt.Errorf("格式为 \"用户名:密码\" 或 \"主机模式=用户名:密码\"")
+	}
+	if pattern, username, found := strings.Cut(user, "="); found {
+		credential.Host, user = strings.ToLower(strings.TrimSpace(pattern)), username
+		if credential.Host == "" {
+			return credential, fmt.Errorf("主机模式不能为空")
+		}
+	}
+	credential.Username, credential.Password = strings.TrimSpace(user), password
+	return credential, credential.validate()
+}
+
+// validate 检查凭据
+func (c Credential) validate() error {
+	if c.Host != "" {
+		if _, err := path.Match(c.Host, ""); err != nil {
+			return fmt.Errorf("无效的主机模式: %s", c.Host)
+		}
+	}
+	if c.Username == "" {
+		return fmt.Errorf("用户名不能为空")
+	}
+	return nil
+}
+
+// ValidateCredentials 检查API请求中的凭据
+func ValidateCredentials(credentials []Credential) error {
+	for i, credential := range credentials {
+		if err := credential.validate(); err != nil {
+			return fmt.Errorf("第%d个身份验证凭据无效: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// LoadCredentials 合并Chrome.Credentials、Chrome.Auth和凭据文件中的凭据
+// 凭据文件每行一条，格式与 --auth 相同，忽略空行和 # 开头的注释；先匹配的凭据优先
+func LoadCredentials(opts *Options) ([]Credential, error) {
+	credentials := append([]Credential(nil), opts.Chrome.Credentials...)
+	if err := ValidateCredentials(credentials); err != nil {
+		return nil, err
+	}
+
+	parsed, err := ParseCredentials(opts.Chrome.Auth)
+	if err != nil {
+		return nil, err
+	}
+	credentials = append(credentials, parsed...)
+
+	if opts.Chrome.AuthFile == "" {
+		return credentials, nil
+	}
+	file, err := os.Open(opts.Chrome.AuthFile)
+	if err != nil {
+		return nil, fmt.Errorf("打开凭据文件失败: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		credential, err := parseCredential(text)
+		if err != nil {
+			return nil, fmt.Errorf("凭据文件第%d行无效: %v", line, err)
+		}
+		credentials = append(credentials, credential)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取凭据文件失败: %v", err)
+	}
+	return credentials, nil
+}
+
+// findCredential 返回第一个适用于主机的凭据
+func findCredential(credentials []Credential, host string) (Credential, bool) {
+	for _, credential := range credentials {
+		if credential.matches(host) {
+			return credential, true
+		}
+	}
+	return Credential{}, false
+}
+
+// splitProxy 拆分代理地址中的用户名和密码，Chrome的 --proxy-server 参数不支持在地址中携带凭据
+func splitProxy(proxy string) (string, *Credential, error) {
+	if !strings.Contains(proxy, "@") {
+		return proxy, nil, nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.User == nil {
+		return "", nil, fmt.Errorf("无效的代理地址: %s", RedactProxy(proxy))
+	}
+	password, _ := u.User.Password()
+	credential := &Credential{Username: u.User.Username(), Password: password}
+	u.User = nil
+	return u.String(), credential, nil
+}
+
+// RedactProxy 隐藏代理地址中的密码，用于日志输出
+func RedactProxy(proxy string) string {
+	at := strings.LastIndex(proxy, "@")
+	if at < 0 {
+		return proxy
+	}
+	prefix, userinfo := "", proxy[:at]
+	if scheme := strings.Index(userinfo, "://"); scheme >= 0 {
+		prefix, userinfo = userinfo[:scheme+3], userinfo[scheme+3:]
+	}
+	if user, _, ok := strings.Cut(userinfo, ":"); ok {
+		userinfo = user + ":" + redacted
+	}
+	return prefix + userinfo + proxy[at:]
+}
+
+// redactCredentials 返回隐藏了凭据的选项副本，用于保存扫描会话
+func redactCredentials(opts *Options) Options {
+	copied := *opts
+	copied.Chrome.Proxy = RedactProxy(opts.Chrome.Proxy)
+	if len(opts.Chrome.Proxies) > 0 {
+		copied.Chrome.Proxies = make([]string, 0, len(opts.Chrome.Proxies))
+		for _, proxy := range opts.Chrome.Proxies {
+			copied.Chrome.Proxies = append(copied.Chrome.Proxies, RedactProxy(proxy))
+		}
+	}
+	if len(opts.Chrome.Auth) > 0 {
+		copied.Chrome.Auth = make([]string, 0, len(opts.Chrome.Auth))
+		for _, value := range opts.Chrome.Auth {
+			user, _, _ := strings.Cut(value, ":")
+			copied.Chrome.Auth = append(copied.Chrome.Auth, user+":"+redacted)
+		}
+	}
+	if len(opts.Chrome.Credentials) > 0 {
+		copied.Chrome.Credentials = make([]Credential, 0, len(opts.Chrome.Credentials))
+		for _, credential := range opts.Chrome.Credentials {
+			credential.Password = redacted
+			copied.Chrome.Credentials = append(copied.Chrome.Credentials, credential)
+		}
+	}
+	// 自定义请求头常用于携带令牌，只保留名称
+	if len(opts.Chrome.Headers) > 0 {
+		copied.Chrome.Headers = make([]string, 0, len(opts.Chrome.Headers))
+		for _, header := range opts.Chrome.Headers {
+			copied.Chrome.Headers = append(copied.Chrome.Headers, redactHeader(header))
+		}
+	}
+	if len(opts.Chrome.CustomHeaders) > 0 {
+		copied.Chrome.CustomHeaders = make(map[string]string, len(opts.Chrome.CustomHeaders))
+		for name := range opts.Chrome.CustomHeaders {
+			copied.Chrome.CustomHeaders[name] = redacted
+		}
+	}
+	return copied
+}
+
+// credentialFlags 是值中包含凭据的命令行参数及隐藏凭据的方法
+var credentialFlags = map[string]func(string) string{
+	"--auth":   redactAuth,
+	"--proxy":  RedactProxy,
+	"--header": redactHeader,
+	"-H":       redactHeader,
+}
+
+// RedactCommandLine 返回隐藏了凭据的命令行，用于保存扫描会话
+// 隐藏 --auth 和 --proxy 中的密码、-H/--header 的值和参数中URL的 用户名:密码@ 部分
+func RedactCommandLine(args []string) string {
+	redactedArgs := make([]string, 0, len(args))
+	var next func(string) string
+	for _, arg := range args {
+		if next != nil {
+			redactedArgs = append(redactedArgs, next(arg))
+			next = nil
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch redact := credentialFlags[name]; {
+		case redact != nil && hasValue:
+			arg = name + "=" + redact(value)
+		case redact != nil:
+			// 参数值是下一个参数
+			next = redact
+		case strings.HasPrefix(arg, "-H") && len(arg) > 2:
+			arg = "-H" + redactHeader(arg[2:])
+		case strings.HasPrefix(arg, "-") && hasValue:
+			arg = name + "=" + redactURL(value)
+		default:
+			arg = redactURL(arg)
+		}
+		redactedArgs = append(redactedArgs, arg)
+	}
+	return strings.Join(redactedArgs, " ")
+}
+
+// redactAuth 隐藏 "用户名:密码" 或 "主机模式=用户名:密码" 格式凭据中的密码
+func redactAuth(value string) string {
+	user, _, ok := strings.Cut(value, ":")
+	if !ok {
+		return value
+	}
+	return user + ":" + redacted
+}
+
+// redactHeader 隐藏 "名称: 值" 或 "主机模式=名称: 值" 格式请求头的值
+func redactHeader(value string) string {
+	name, _, ok := strings.Cut(value, ":")
+	if !ok {
+		return value
+	}
+	return name + ": " + redacted
+}
+
+// redactURL 隐藏URL中 用户名:密码@ 的密码，不是URL时原样返回
+func redactURL(value string) string {
+	if !strings.Contains(value, "://") || !strings.Contains(value, "@") {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	if _, ok := u.User.Password(); !ok {
+		return value
+	}
+	return u.Redacted()
+}
@@ -47,23 +47,70 @@ var DefaultBlacklist = []string{
 	"ftp://.*",
 }
 
+// 黑名单规则类型
+const (
+	RuleTypeCIDR   = "cidr"   // CIDR网段
+	RuleTypeIP     = "ip"     // 单个IP地址
+	RuleTypeRegex  = "regex"  // 通配符/正则表达式
+	RuleTypeDomain = "domain" // 域名
+)
+
+// 黑名单规则来源
+const (
+	RuleSourceDefault = "default" // 默认黑名单
+	RuleSourceFlag    = "flag"    // 命令行或配置中的自定义规则
+	RuleSourceFile    = "file"    // 黑名单文件
+)
+
+// BlacklistRule 表示一条黑名单规则及其来源
+type BlacklistRule struct {
+	Pattern string `json:"pattern"`        // 原始规则
+	Type    string `json:"type"`           // 规则类型
+	Source  string `json:"source"`         // 规则来源
+	File    string `json:"file,omitempty"` // 来源文件（仅文件规则）
+	Line    int    `json:"line,omitempty"` // 来源文件行号（仅文件规则）
+
+	ipNet *net.IPNet
+	regex *regexp.Regexp
+}
+
+// Origin 返回规则来源的可读描述
+func (r *BlacklistRule) Origin() string {
+	if r.Source == RuleSourceFile {
+		return fmt.Sprintf("%s:%d", r.File, r.Line)
+	}
+	return r.Source
+}
+
+// BlacklistMatch 表示一条命中的黑名单规则
+type BlacklistMatch struct {
+	Rule   *BlacklistRule `json:"rule"`
+	Value  string         `json:"value"`  // 被匹配的值（URL、主机名、IP或主机:端口）
+	Reason string         `json:"reason"` // 命中原因
+}
+
+// BlacklistExplanation 表示对单个URL的黑名单检查详情
+type BlacklistExplanation struct {
+	URL         string           `json:"url"`
+	Host        string           `json:"host"`
+	Port        string           `json:"port,omitempty"`
+	ResolvedIPs []string         `json:"resolved_ips,omitempty"`
+	ResolveErr  string           `json:"resolve_error,omitempty"`
+	Blacklisted bool             `json:"blacklisted"`
+	Matches     []BlacklistMatch `json:"matches"`
+}
+
 // URLBlacklist 表示URL黑名单
 type URLBlacklist struct {
-	enabled        bool
-	patterns       []string
-	ipNetworks     []*net.IPNet
-	regexPatterns  []*regexp.Regexp
-	domainPatterns []string
+	enabled bool
+	rules   []*BlacklistRule
 }
 
 // NewURLBlacklist 创建一个新的URL黑名单
 func NewURLBlacklist(opts *Options) (*URLBlacklist, error) {
 	bl := &URLBlacklist{
-		enabled:        opts.Scan.EnableBlacklist,
-		patterns:       []string{},
-		ipNetworks:     []*net.IPNet{},
-		regexPatterns:  []*regexp.Regexp{},
-		domainPatterns: []string{},
+		enabled: opts.Scan.EnableBlacklist,
+		rules:   []*BlacklistRule{},
 	}
 
 	// 如果黑名单未启用，直接返回
@@ -73,106 +120,129 @@ func NewURLBlacklist(opts *Options) (*URLBlacklist, error) {
 
 	// 添加默认黑名单
 	if opts.Scan.DefaultBlacklist {
-		bl.patterns = append(bl.patterns, DefaultBlacklist...)
+		for _, pattern := range DefaultBlacklist {
+			if err := bl.addRule(&BlacklistRule{Pattern: pattern, Source: RuleSourceDefault}); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// 添加自定义黑名单
-	if len(opts.Scan.BlacklistPatterns) > 0 {
-		bl.patterns = append(bl.patterns, opts.Scan.BlacklistPatterns...)
+	for _, pattern := range opts.Scan.BlacklistPatterns {
+		if err := bl.addRule(&BlacklistRule{Pattern: pattern, Source: RuleSourceFlag}); err != nil {
+			return nil, err
+		}
 	}
 
 	// 从文件加载黑名单
 	if opts.Scan.BlacklistFile != "" {
-		patterns, err := loadPatternsFromFile(opts.Scan.BlacklistFile)
+		rules, err := loadRulesFromFile(opts.Scan.BlacklistFile)
 		if err != nil {
 			return nil, fmt.Errorf("加载黑名单文件失败: %v", err)
 		}
-		bl.patterns = append(bl.patterns, patterns...)
-	}
-
-	// 解析黑名单规则
-	if err := bl.parsePatterns(); err != nil {
-		return nil, err
+		for _, rule := range rules {
+			if err := bl.addRule(rule); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	log.Info("已启用URL黑名单", "规则数量", len(bl.patterns))
+	log.Info("已启用URL黑名单", "规则数量", len(bl.rules))
 	return bl, nil
 }
 
-// loadPatternsFromFile 从文件加载黑名单规则
-func loadPatternsFromFile(filepath string) ([]string, error) {
+// Rules 返回所有已加载的黑名单规则
+func (bl *URLBlacklist) Rules() []*BlacklistRule {
+	return bl.rules
+}
+
+// loadRulesFromFile 从文件加载黑名单规则，并记录每条规则的行号
+func loadRulesFromFile(filepath string) ([]*BlacklistRule, error) {
 	file, err := os.Open(filepath)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	var patterns []string
+	var rules []*BlacklistRule
 	scanner := bufio.NewScanner(file)
+	lineNo := 0
 	for scanner.Scan() {
+		lineNo++
 		line := strings.TrimSpace(scanner.Text())
 		// 跳过空行和注释
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
-		patterns = append(patterns, line)
+		rules = append(rules, &BlacklistRule{
+			Pattern: line,
+			Source:  RuleSourceFile,
+			File:    filepath,
+			Line:    lineNo,
+		})
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
 
-	return patterns, nil
+	return rules, nil
 }
 
-// parsePatterns 解析黑名单规则
-func (bl *URLBlacklist) parsePatterns() error {
-	for _, pattern := range bl.patterns {
-		// 尝试解析为CIDR
-		if _, ipNet, err := net.ParseCIDR(pattern); err == nil {
-			bl.ipNetworks = append(bl.ipNetworks, ipNet)
-			continue
-		}
-
-		// 尝试解析为IP地址
-		if ip := net.ParseIP(pattern); ip != nil {
-			mask := net.CIDRMask(32, 32)
-			if ip.To4() == nil { // IPv6
-				mask = net.CIDRMask(128, 128)
-			}
-			ipNet := &net.IPNet{
-				IP:   ip,
-				Mask: mask,
-			}
-			bl.ipNetworks = append(bl.ipNetworks, ipNet)
-			continue
-		}
+// addRule 解析黑名单规则并加入规则列表
+func (bl *URLBlacklist) addRule(rule *BlacklistRule) error {
+	pattern := rule.Pattern
 
-		// 检查是否为正则表达式
-		if strings.Contains(pattern, "*") || strings.Contains(pattern, "?") ||
-			strings.Contains(pattern, "[") || strings.Contains(pattern, ".*") {
-			// 转换通配符为正则表达式
-			regexStr := pattern
-			regexStr = strings.ReplaceAll(regexStr, ".", "\\.")
-			regexStr = strings.ReplaceAll(regexStr, "*", ".*")
-			regexStr = strings.ReplaceAll(regexStr, "?", ".")
-			regexStr = "^" + regexStr + "$"
+	// 尝试解析为CIDR
+	if _, ipNet, err := net.ParseCIDR(pattern); err == nil {
+		rule.Type = RuleTypeCIDR
+		rule.ipNet = ipNet
+		bl.rules = append(bl.rules, rule)
+		return nil
+	}
 
-			re, err := regexp.Compile(regexStr)
-			if err != nil {
-				return fmt.Errorf("无效的正则表达式 '%s': %v", pattern, err)
-			}
-			bl.regexPatterns = append(bl.regexPatterns, re)
-			continue
+	// 尝试解析为IP地址
+	if ip := net.ParseIP(pattern); ip != nil {
+		mask := net.CIDRMask(32, 32)
+		if ip.To4() == nil { // IPv6
+			mask = net.CIDRMask(128, 128)
 		}
+		rule.Type = RuleTypeIP
+		rule.ipNet = &net.IPNet{
+			IP:   ip,
+			Mask: mask,
+		}
+		bl.rules = append(bl.rules, rule)
+		return nil
+	}
 
-		// 当作域名处理
-		bl.domainPatterns = append(bl.domainPatterns, pattern)
+	// 检查是否为正则表达式
+	if strings.Contains(pattern, "*") || strings.Contains(pattern, "?") ||
+		strings.Contains(pattern, "[") || strings.Contains(pattern, ".*") {
+		// 转换通配符为正则表达式
+		regexStr := pattern
+		regexStr = strings.ReplaceAll(regexStr, ".", "\\.")
+		regexStr = strings.ReplaceAll(regexStr, "*", ".*")
+		regexStr = strings.ReplaceAll(regexStr, "?", ".")
+		regexStr = "^" + regexStr + "$"
+
+		re, err := regexp.Compile(regexStr)
+		if err != nil {
+			return fmt.Errorf("无效的正则表达式 '%s' (%s): %v", pattern, rule.Origin(), err)
+		}
+		rule.Type = RuleTypeRegex
+		rule.regex = re
+		bl.rules = append(bl.rules, rule)
+		return nil
 	}
+
+	// 当作域名处理
+	rule.Type = RuleTypeDomain
+	bl.rules = append(bl.rules, rule)
 	return nil
 }
 
-// IsBlacklisted 检查URL是否在黑名单中
+// IsBlacklisted 检查URL是否在黑名单中，返回第一条命中规则的原因
 func (bl *URLBlacklist) IsBlacklisted(targetURL string) (bool, string) {
 	// 如果黑名单未启用，直接返回false
 	if !bl.enabled {
@@ -186,6 +256,33 @@ func (bl *URLBlacklist) IsBlacklisted(targetURL string) (bool, string) {
 		return true, "无效的URL格式"
 	}
 
+	explanation := bl.evaluate(targetURL, parsedURL, true)
+	if len(explanation.Matches) > 0 {
+		return true, explanation.Matches[0].Reason
+	}
+
+	return false, ""
+}
+
+// Explain 检查URL并返回所有命中的规则以及解析到的IP地址
+func (bl *URLBlacklist) Explain(targetURL string) *BlacklistExplanation {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return &BlacklistExplanation{
+			URL:         targetURL,
+			Blacklisted: bl.enabled,
+			Matches: []BlacklistMatch{{
+				Value:  targetURL,
+				Reason: "无效的URL格式",
+			}},
+		}
+	}
+
+	return bl.evaluate(targetURL, parsedURL, false)
+}
+
+// evaluate 按固定顺序检查所有规则；firstOnly为true时命中第一条规则即返回
+func (bl *URLBlacklist) evaluate(targetURL string, parsedURL *url.URL, firstOnly bool) *BlacklistExplanation {
 	// 提取主机名和端口
 	host := parsedURL.Hostname()
 	port := parsedURL.Port()
@@ -194,36 +291,71 @@ func (bl *URLBlacklist) IsBlacklisted(targetURL string) (bool, string) {
 		hostPort = fmt.Sprintf("%s:%s", host, port)
 	}
 
+	exp := &BlacklistExplanation{
+		URL:     targetURL,
+		Host:    host,
+		Port:    port,
+		Matches: []BlacklistMatch{},
+	}
+
+	if !bl.enabled {
+		return exp
+	}
+
+	done := func() bool {
+		return firstOnly && len(exp.Matches) > 0
+	}
+	add := func(rule *BlacklistRule, value, reason string) {
+		exp.Matches = append(exp.Matches, BlacklistMatch{Rule: rule, Value: value, Reason: reason})
+		exp.Blacklisted = true
+	}
+
 	// 检查协议
-	for _, re := range bl.regexPatterns {
-		if re.MatchString(targetURL) {
-			return true, fmt.Sprintf("匹配正则表达式黑名单规则: %s", re.String())
+	for _, rule := range bl.rulesOfType(RuleTypeRegex) {
+		if rule.regex.MatchString(targetURL) {
+			add(rule, targetURL, fmt.Sprintf("匹配正则表达式黑名单规则: %s", rule.regex.String()))
+			if done() {
+				return exp
+			}
 		}
 	}
 
 	// 检查主机名是否为域名模式
-	for _, domain := range bl.domainPatterns {
-		if host == domain || strings.HasSuffix(host, "."+domain) {
-			return true, fmt.Sprintf("匹配域名黑名单: %s", domain)
+	for _, rule := range bl.rulesOfType(RuleTypeDomain) {
+		if host == rule.Pattern || strings.HasSuffix(host, "."+rule.Pattern) {
+			add(rule, host, fmt.Sprintf("匹配域名黑名单: %s", rule.Pattern))
+			if done() {
+				return exp
+			}
 		}
 	}
 
 	// 检查IP地址
-	ip := net.ParseIP(host)
-	if ip != nil {
-		for _, ipNet := range bl.ipNetworks {
-			if ipNet.Contains(ip) {
-				return true, fmt.Sprintf("IP地址在黑名单CIDR范围内: %s", ipNet.String())
+	ipRules := bl.rulesOfType(RuleTypeCIDR, RuleTypeIP)
+	if ip := net.ParseIP(host); ip != nil {
+		for _, rule := range ipRules {
+			if rule.ipNet.Contains(ip) {
+				add(rule, host, fmt.Sprintf("IP地址在黑名单CIDR范围内: %s", rule.ipNet.String()))
+				if done() {
+					return exp
+				}
 			}
 		}
-	} else {
+	} else if host != "" {
 		// 尝试解析主机名为IP
 		ips, err := net.LookupIP(host)
-		if err == nil {
-			for _, resolvedIP := range ips {
-				for _, ipNet := range bl.ipNetworks {
-					if ipNet.Contains(resolvedIP) {
-						return true, fmt.Sprintf("解析的IP地址在黑名单CIDR范围内: %s -> %s", host, resolvedIP.String())
+		if err != nil {
+			exp.ResolveErr = err.Error()
+		}
+		for _, resolvedIP := range ips {
+			exp.ResolvedIPs = append(exp.ResolvedIPs, resolvedIP.String())
+		}
+		for _, resolvedIP := range ips {
+			for _, rule := range ipRules {
+				if rule.ipNet.Contains(resolvedIP) {
+					add(rule, resolvedIP.String(), fmt.Sprintf("解析的IP地址在黑名单CIDR范围内: %s -> %s", host, resolvedIP.String()))
+					if done() {
+						return exp
 					}
 				}
 			}
@@ -232,12 +364,29 @@ func (bl *URLBlacklist) IsBlacklisted(targetURL string) (bool, string) {
 
 	// 检查主机名:端口
 	if port != "" {
-		for _, re := range bl.regexPatterns {
-			if re.MatchString(hostPort) {
-				return true, fmt.Sprintf("匹配端口黑名单规则: %s", re.String())
+		for _, rule := range bl.rulesOfType(RuleTypeRegex) {
+			if rule.regex.MatchString(hostPort) {
+				add(rule, hostPort, fmt.Sprintf("匹配端口黑名单规则: %s", rule.regex.String()))
+				if done() {
+					return exp
+				}
 			}
 		}
 	}
 
-	return false, ""
+	return exp
+}
+
+// rulesOfType 返回指定类型的规则，保持加载顺序
+func (bl *URLBlacklist) rulesOfType(ruleTypes ...string) []*BlacklistRule {
+	var rules []*BlacklistRule
+	for _, rule := range bl.rules {
+		for _, ruleType := range ruleTypes {
+			if rule.Type == ruleType {
+				rules = append(rules, rule)
+				break
+			}
+		}
+	}
+	return rules
 }
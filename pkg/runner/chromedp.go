@@ -2,30 +2,80 @@ package runner
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha1"
+	"encoding/base64"
 	"fmt"
-	"io/ioutil"
-	"path/filepath"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/dom"
 	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
 
+	"github.com/cyberspacesec/go-snir/pkg/har"
 	"github.com/cyberspacesec/go-snir/pkg/log"
 	"github.com/cyberspacesec/go-snir/pkg/models"
+	"github.com/cyberspacesec/go-snir/pkg/phash"
+	"github.com/cyberspacesec/go-snir/pkg/storage"
 )
 
 // ChromeDP implements the Driver interface using chromedp
 type ChromeDP struct {
-	ctx    context.Context
-	cancel context.CancelFunc
-	opts   *Options
+	ctx         context.Context
+	cancel      context.CancelFunc
+	opts        *Options
+	store       *storage.BlobStore
+	headers     []HeaderRule
+	credentials []Credential
+	proxy       string      // 整个浏览器使用的代理，不含凭据
+	proxyAuth   *Credential // 整个浏览器使用的代理的身份验证凭据
+
+	// 代理池，每个代理使用独立的浏览器上下文
+	proxies      *ProxyPool
+	startMu      sync.Mutex
+	started      bool
+	proxyMu      sync.Mutex
+	proxyCtxs    map[*Proxy]context.Context
+	proxyCancels []context.CancelFunc
 }
 
 // NewChromeDP creates a new ChromeDP driver
 func NewChromeDP(opts *Options) (*ChromeDP, error) {
+	// 打开截图存储
+	store, err := storage.OpenBlobStore(opts.Scan.Storage, opts.Scan.ScreenshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开截图存储失败: %v", err)
+	}
+
+	// 解析自定义请求头
+	headers, err := headerRules(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 加载身份验证凭据，代理地址中的凭据通过Fetch.authRequired响应
+	credentials, err := LoadCredentials(opts)
+	if err != nil {
+		return nil, err
+	}
+	proxy, proxyAuth, err := splitProxy(opts.Chrome.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	// 加载代理池，使用代理池时由每个浏览器上下文设置代理
+	proxies, err := LoadProxyPool(opts)
+	if err != nil {
+		return nil, err
+	}
+	if proxies != nil {
+		proxy, proxyAuth = "", nil
+	}
+
 	// 设置Chrome选项
 	chromedpOpts := []chromedp.ExecAllocatorOption{
 		chromedp.NoFirstRun,
@@ -47,8 +97,8 @@ func NewChromeDP(opts *Options) (*ChromeDP, error) {
 	}
 
 	// 设置代理
-	if opts.Chrome.Proxy != "" {
-		chromedpOpts = append(chromedpOpts, chromedp.ProxyServer(opts.Chrome.Proxy))
+	if proxy != "" {
+		chromedpOpts = append(chromedpOpts, chromedp.ProxyServer(proxy))
 	}
 
 	// 设置Chrome路径
@@ -56,6 +106,11 @@ func NewChromeDP(opts *Options) (*ChromeDP, error) {
 		chromedpOpts = append(chromedpOpts, chromedp.ExecPath(opts.Chrome.Path))
 	}
 
+	// 隐藏自动化控制标记，navigator.webdriver 由页面初始化脚本处理
+	if opts.Chrome.Stealth {
+		chromedpOpts = append(chromedpOpts, chromedp.Flag("disable-blink-features", "AutomationControlled"))
+	}
+
 	// 忽略证书错误
 	if opts.Chrome.IgnoreCertErrors {
 		chromedpOpts = append(chromedpOpts, chromedp.Flag("ignore-certificate-errors", true))
@@ -73,34 +128,165 @@ func NewChromeDP(opts *Options) (*ChromeDP, error) {
 	}
 
 	return &ChromeDP{
-		ctx:    ctx,
-		cancel: cancel,
-		opts:   opts,
+		ctx:         ctx,
+		cancel:      cancel,
+		opts:        opts,
+		store:       store,
+		headers:     headers,
+		credentials: credentials,
+		proxy:       proxy,
+		proxyAuth:   proxyAuth,
+		proxies:     proxies,
+		proxyCtxs:   make(map[*Proxy]context.Context),
 	}, nil
 }
 
 // Witness implements the Driver interface
-func (c *ChromeDP) Witness(target string, runner *Runner) (*models.Result, error) {
+func (c *ChromeDP) Witness(t Target, runner *Runner) (*models.Result, error) {
+	if c.proxies == nil {
+		ctx, cancel, err := c.newTab(t, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer cancel()
+		return c.witness(ctx, t, c.proxy, c.proxyAuth)
+	}
+
+	// 从代理池选择代理，代理连接失败时换一个代理重试
+	host := ""
+	if u, err := url.Parse(t.URL); err == nil {
+		host = u.Hostname()
+	}
+	var result *models.Result
+	var err error
+	for attempt := 0; attempt < c.proxies.Len(); attempt++ {
+		proxy := c.proxies.Next(host)
+		ctx, cancel, cerr := c.newTab(t, proxy)
+		if cerr != nil {
+			return nil, cerr
+		}
+		result, err = c.witness(ctx, t, proxy.Server, proxy.Auth)
+		cancel()
+		if !isProxyError(err) {
+			c.proxies.Succeeded(proxy)
+			return result, err
+		}
+		c.proxies.Failed(proxy)
+		log.Warn("代理连接失败，换一个代理重试", "url", t.URL, "proxy", proxy, "error", err)
+	}
+	return result, err
+}
+
+// newTab 为一次截图打开新的标签页，截图结束后调用返回的函数关闭标签页
+// 请求拦截、初始化脚本和设备模拟只作用于所在的标签页，并发的截图互不影响；
+// Cookie在浏览器上下文中共享，目标自带Cookie时使用独立的浏览器上下文，关闭标签页时一并销毁
+func (c *ChromeDP) newTab(t Target, proxy *Proxy) (context.Context, context.CancelFunc, error) {
+	if err := c.start(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(t.Cookies) > 0 {
+		ctx, cancel := chromedp.NewContext(c.ctx, chromedp.WithNewBrowserContext(
+			func(p *target.CreateBrowserContextParams) *target.CreateBrowserContextParams {
+				if proxy != nil {
+					p = p.WithProxyServer(proxy.Server)
+				}
+				return p
+			}))
+		return ctx, cancel, nil
+	}
+
+	parent := c.ctx
+	if proxy != nil {
+		var err error
+		if parent, err = c.proxyContext(proxy); err != nil {
+			return nil, nil, err
+		}
+	}
+	ctx, cancel := chromedp.NewContext(parent)
+	return ctx, cancel, nil
+}
+
+// start 启动浏览器，浏览器启动后才能打开新的标签页和浏览器上下文
+func (c *ChromeDP) start() error {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+	if c.started {
+		return nil
+	}
+	if err := chromedp.Run(c.ctx); err != nil {
+		return fmt.Errorf("启动浏览器失败: %v", err)
+	}
+	c.started = true
+	return nil
+}
+
+// proxyContext 返回使用代理的浏览器上下文，每个代理使用独立的浏览器上下文，Cookie和缓存互不影响
+func (c *ChromeDP) proxyContext(proxy *Proxy) (context.Context, error) {
+	c.proxyMu.Lock()
+	defer c.proxyMu.Unlock()
+	if ctx, ok := c.proxyCtxs[proxy]; ok {
+		return ctx, nil
+	}
+
+	ctx, cancel := chromedp.NewContext(c.ctx, chromedp.WithNewBrowserContext(
+		func(p *target.CreateBrowserContextParams) *target.CreateBrowserContextParams {
+			return p.WithProxyServer(proxy.Server)
+		}))
+	// 创建浏览器上下文，之后在其中打开的标签页都使用该代理
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return nil, fmt.Errorf("创建代理浏览器上下文失败: %v", err)
+	}
+	c.proxyCtxs[proxy] = ctx
+	c.proxyCancels = append(c.proxyCancels, cancel)
+	return ctx, nil
+}
+
+// witness 在标签页上下文中对目标截图，proxy 为页面使用的代理地址，proxyAuth 为代理身份验证凭据
+func (c *ChromeDP) witness(ctx context.Context, t Target, proxy string, proxyAuth *Credential) (*models.Result, error) {
+	target := t.URL
 	result := &models.Result{
 		URL:      target,
+		Device:   t.Device.name(),
+		Proxy:    proxy,
 		ProbedAt: time.Now(),
 	}
 
-	// 创建网络事件监听器
-	networkEvents := make(map[string]*models.NetworkLog)
-	chromedp.ListenTarget(c.ctx, func(ev interface{}) {
-		switch e := ev.(type) {
-		case *network.EventRequestWillBeSent:
-			networkEvents[e.RequestID.String()] = &models.NetworkLog{
-				Type:   models.HTTP,
-				URL:    e.Request.URL,
-				Method: e.Request.Method,
-			}
-		case *network.EventResponseReceived:
-			if nl, ok := networkEvents[e.RequestID.String()]; ok {
-				nl.StatusCode = int(e.Response.Status)
-				nl.ContentType = e.Response.MimeType
+	// 创建网络事件监听器，事件在chromedp的事件处理协程中到达
+	// 监听器在本次截图结束时移除，避免后续截图的事件写入本次的记录
+	listenCtx, stopListening := context.WithCancel(ctx)
+	defer stopListening()
+
+	// 合并自定义请求头和目标自带的请求头，HAR记录中隐藏这些请求头和Cookie的值
+	headers := c.headers
+	if len(t.Headers) > 0 {
+		headers = append(append([]HeaderRule{}, c.headers...), t.Headers...)
+	}
+	redact := make([]string, 0, len(headers)+1)
+	for _, rule := range headers {
+		redact = append(redact, rule.Name)
+	}
+	if len(c.opts.Scan.Cookies) > 0 || len(t.Cookies) > 0 {
+		redact = append(redact, "Cookie")
+	}
+
+	recorder := har.NewRecorder(redact...)
+	var tlsMu sync.Mutex
+	var tlsInfo models.TLS
+	var tlsOrigin string
+	chromedp.ListenTarget(listenCtx, func(ev interface{}) {
+		recorder.Handle(ev)
+
+		// 记录主文档的TLS证书信息
+		if e, ok := ev.(*network.EventResponseReceived); ok &&
+			e.Type == network.ResourceTypeDocument && e.Response.SecurityDetails != nil {
+			tlsMu.Lock()
+			if tlsOrigin == "" {
+				tlsInfo = tlsFromSecurityDetails(e.Response.SecurityDetails)
+				tlsOrigin = originOf(e.Response.URL)
 			}
+			tlsMu.Unlock()
 		}
 	})
 
@@ -109,6 +295,30 @@ func (c *ChromeDP) Witness(target string, runner *Runner) (*models.Result, error
 		network.Enable(),
 	}
 
+	// 发送自定义请求头和目标自带的请求头，响应HTTP身份验证和代理身份验证
+	if len(headers) > 0 || len(c.credentials) > 0 || proxyAuth != nil {
+		tasks = append(tasks, interceptRequests(listenCtx, headers, c.credentials, proxyAuth))
+	}
+
+	// 模拟设备的视口、像素比、触摸和User-Agent
+	acceptLanguage, stealth := c.opts.Chrome.AcceptLanguage, c.opts.Chrome.Stealth
+	if t.Device != nil {
+		tasks = append(tasks, emulateDevice(t.Device, acceptLanguage, stealth))
+	} else if acceptLanguage != "" || stealth {
+		tasks = append(tasks, overrideUserAgent(c.opts.Chrome.UserAgent, acceptLanguage, stealth))
+	}
+
+	// 页面初始化脚本在每个新文档和iframe中先于页面脚本执行，只作用于本次截图的标签页
+	if languages := parseLanguages(acceptLanguage); len(languages) > 0 {
+		tasks = append(tasks, addInitScript(languagesScript(languages)))
+	}
+	if stealth {
+		tasks = append(tasks, addInitScript(stealthScript))
+	}
+	if script := fingerprintScript(c.opts); script != "" {
+		tasks = append(tasks, addInitScript(script))
+	}
+
 	// 设置Cookie
 	if len(c.opts.Scan.Cookies) > 0 {
 		for _, cookie := range c.opts.Scan.Cookies {
@@ -125,106 +335,16 @@ func (c *ChromeDP) Witness(target string, runner *Runner) (*models.Result, error
 		}
 	}
 
+	// 目标自带的Cookie按目标URL设置，设置在本次截图独立的浏览器上下文中
+	for _, cookie := range t.Cookies {
+		tasks = append(tasks, network.SetCookie(cookie.Name, cookie.Value).WithURL(target))
+	}
+
 	// 加载前执行JavaScript
 	if c.opts.Scan.RunJSBefore && c.opts.Scan.JavaScript != "" {
 		tasks = append(tasks, chromedp.Evaluate(c.opts.Scan.JavaScript, nil))
 	}
 
-	// 添加指纹伪装脚本
-	if c.opts.Chrome.Platform != "" || c.opts.Chrome.Vendor != "" ||
-		len(c.opts.Chrome.Plugins) > 0 || c.opts.Chrome.WebGLVendor != "" ||
-		c.opts.Chrome.WebGLRenderer != "" || c.opts.Chrome.SpoofScreenSize {
-
-		// 构建指纹伪装脚本
-		fingerprintJS := "(() => {"
-
-		// 修改navigator.platform
-		if c.opts.Chrome.Platform != "" {
-			fingerprintJS += fmt.Sprintf(`
-				Object.defineProperty(navigator, 'platform', {
-					get: function() { return '%s'; }
-				});`, c.opts.Chrome.Platform)
-		}
-
-		// 修改vendor
-		if c.opts.Chrome.Vendor != "" {
-			fingerprintJS += fmt.Sprintf(`
-				Object.defineProperty(navigator, 'vendor', {
-					get: function() { return '%s'; }
-				});`, c.opts.Chrome.Vendor)
-		}
-
-		// 修改plugins
-		if len(c.opts.Chrome.Plugins) > 0 {
-			pluginsJSON, _ := json.Marshal(c.opts.Chrome.Plugins)
-			fingerprintJS += fmt.Sprintf(`
-				Object.defineProperty(navigator, 'plugins', {
-					get: function() { return %s; }
-				});`, string(pluginsJSON))
-		}
-
-		// WebGL相关
-		if c.opts.Chrome.WebGLVendor != "" || c.opts.Chrome.WebGLRenderer != "" {
-			fingerprintJS += `
-				const getParameter = WebGLRenderingContext.prototype.getParameter;
-				WebGLRenderingContext.prototype.getParameter = function(parameter) {
-			`
-
-			if c.opts.Chrome.WebGLVendor != "" {
-				fingerprintJS += fmt.Sprintf(`
-					if (parameter === 37445) {
-						return '%s';
-					}`, c.opts.Chrome.WebGLVendor)
-			}
-
-			if c.opts.Chrome.WebGLRenderer != "" {
-				fingerprintJS += fmt.Sprintf(`
-					if (parameter === 37446) {
-						return '%s';
-					}`, c.opts.Chrome.WebGLRenderer)
-			}
-
-			fingerprintJS += `
-					return getParameter.call(this, parameter);
-				};`
-		}
-
-		// 屏幕尺寸
-		if c.opts.Chrome.SpoofScreenSize && c.opts.Chrome.ScreenWidth > 0 && c.opts.Chrome.ScreenHeight > 0 {
-			fingerprintJS += fmt.Sprintf(`
-				Object.defineProperty(window, 'screen', {
-					get: function() {
-						return {
-							width: %d,
-							height: %d,
-							availWidth: %d,
-							availHeight: %d,
-							colorDepth: 24,
-							pixelDepth: 24
-						};
-					}
-				});`, c.opts.Chrome.ScreenWidth, c.opts.Chrome.ScreenHeight,
-				c.opts.Chrome.ScreenWidth, c.opts.Chrome.ScreenHeight)
-		}
-
-		// WebRTC
-		if c.opts.Chrome.DisableWebRTC {
-			fingerprintJS += `
-				// 禁用WebRTC
-				Object.defineProperty(window, 'RTCPeerConnection', {
-					value: undefined
-				});
-				Object.defineProperty(window, 'webkitRTCPeerConnection', {
-					value: undefined
-				});`
-		}
-
-		fingerprintJS += "})();"
-
-		// 执行指纹伪装脚本
-		tasks = append(tasks, chromedp.Evaluate(fingerprintJS, nil))
-	}
-
 	// 页面导航
 	tasks = append(tasks, chromedp.Navigate(target))
 
@@ -382,15 +502,8 @@ func (c *ChromeDP) Witness(target string, runner *Runner) (*models.Result, error
 
 	tasks = append(tasks,
 		chromedp.ActionFunc(func(ctx context.Context) error {
-			// 获取响应码
-			var statusCode int
-			for _, nl := range networkEvents {
-				if nl.URL == target || strings.HasSuffix(target, nl.URL) {
-					statusCode = nl.StatusCode
-					break
-				}
-			}
-			responseCode = statusCode
+			// 获取响应码，页面目标的ID就是主框架ID
+			responseCode = recorder.Status(cdp.FrameID(chromedp.FromContext(ctx).Target.TargetID))
 			return nil
 		}),
 		chromedp.Title(&title),
@@ -409,25 +522,75 @@ func (c *ChromeDP) Witness(target string, runner *Runner) (*models.Result, error
 			cookies, err = network.GetCookies().Do(ctx)
 			return err
 		}),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			// 获取证书并计算SHA1指纹，失败时不影响截图
+			tlsMu.Lock()
+			origin := tlsOrigin
+			tlsMu.Unlock()
+			if origin == "" {
+				return nil
+			}
+			certs, err := network.GetCertificate(origin).Do(ctx)
+			if err != nil || len(certs) == 0 {
+				return nil
+			}
+			if der, err := base64.StdEncoding.DecodeString(certs[0]); err == nil {
+				tlsMu.Lock()
+				tlsInfo.FingerprintSHA1 = fmt.Sprintf("%x", sha1.Sum(der))
+				tlsMu.Unlock()
+			}
+			return nil
+		}),
 	)
 
-	// 根据不同的选择方式截图
-	if c.opts.Scan.Selector != "" {
-		// 使用CSS选择器截图
-		tasks = append(tasks, chromedp.Screenshot(c.opts.Scan.Selector, &buf, chromedp.ByQuery))
-	} else if c.opts.Scan.XPath != "" {
-		// 使用XPath截图
-		tasks = append(tasks, chromedp.Screenshot(c.opts.Scan.XPath, &buf, chromedp.BySearch))
-	} else if c.opts.Scan.CaptureFullPage {
-		// 捕获完整页面（包括滚动部分）
-		tasks = append(tasks, chromedp.FullScreenshot(&buf, 100))
-	} else {
-		// 默认捕获可视区域
-		tasks = append(tasks, chromedp.CaptureScreenshot(&buf))
+	// 获取HAR中记录的响应体
+	if c.opts.Scan.SaveHAR {
+		tasks = append(tasks, recorder.FetchBodies(c.opts.Scan.HARBodyLimit))
+	}
+
+	// 根据不同的选择方式截图，只生成PDF时不截图
+	captureScreenshot, capturePDF := captureModes(c.opts.Scan.Capture)
+	if captureScreenshot {
+		if c.opts.Scan.Selector != "" {
+			// 使用CSS选择器截图
+			tasks = append(tasks, chromedp.Screenshot(c.opts.Scan.Selector, &buf, chromedp.ByQuery))
+		} else if c.opts.Scan.XPath != "" {
+			// 使用XPath截图
+			tasks = append(tasks, chromedp.Screenshot(c.opts.Scan.XPath, &buf, chromedp.BySearch))
+		} else if c.opts.Scan.CaptureFullPage {
+			// 捕获完整页面（包括滚动部分）
+			tasks = append(tasks, chromedp.FullScreenshot(&buf, 100))
+		} else {
+			// 默认捕获可视区域
+			tasks = append(tasks, chromedp.CaptureScreenshot(&buf))
+		}
+	}
+
+	// 在同一次页面加载中依次调整视口大小并截图
+	viewports, err := ParseViewports(c.opts.Scan.Viewports)
+	if err != nil {
+		return result, err
+	}
+	viewportBufs := make([][]byte, len(viewports))
+	if len(viewports) > 0 {
+		tasks = append(tasks, captureViewports(viewports, t.Device, c.opts.Scan.CaptureFullPage, viewportBufs))
+	}
+
+	// 打印为PDF，PDF中保留页面文本，可以检索
+	var pdfBuf []byte
+	if capturePDF {
+		tasks = append(tasks, printToPDF(c.opts.Scan.PDF, &pdfBuf))
+	}
+
+	// 保存MHTML页面存档，包含页面引用的图片、样式等资源
+	var archiveBuf []byte
+	var archivedAt time.Time
+	if c.opts.Scan.Archive {
+		tasks = append(tasks, captureArchive(&archiveBuf, &archivedAt))
 	}
 
 	// 执行任务
-	err := chromedp.Run(c.ctx, tasks...)
+	err = chromedp.Run(ctx, tasks...)
 	if err != nil {
 		result.Failed = true
 		result.FailedReason = err.Error()
@@ -438,21 +601,77 @@ func (c *ChromeDP) Witness(target string, runner *Runner) (*models.Result, error
 	result.Title = title
 	result.ResponseCode = responseCode
 	result.HTML = htmlContent
+	tlsMu.Lock()
+	result.TLS = tlsInfo
+	tlsMu.Unlock()
+
+	// 计算感知哈希，用于比较同一URL在不同扫描中的截图变化
+	if len(buf) > 0 {
+		if hash, err := phash.FromBytes(buf); err == nil {
+			result.PerceptionHash = hash
+		} else {
+			log.Debug("计算感知哈希失败", "url", target, "error", err)
+		}
+	}
 
-	// 保存截图
-	if !c.opts.Scan.ScreenshotSkipSave {
-		filename := fmt.Sprintf("%s_%s.%s",
-			strings.ReplaceAll(target, "/", "_"),
-			time.Now().Format("20060102150405"),
-			c.opts.Scan.ScreenshotFormat)
-		filepath := filepath.Join(c.opts.Scan.ScreenshotPath, filename)
-
-		err = ioutil.WriteFile(filepath, buf, 0644)
+	// 按内容哈希保存截图，相同内容的截图只保存一份
+	if captureScreenshot && !c.opts.Scan.ScreenshotSkipSave {
+		blob, err := c.store.Put(ctx, buf, "."+c.opts.Scan.ScreenshotFormat)
 		if err != nil {
 			log.Error("保存截图失败", "error", err)
 		} else {
-			result.Filename = filepath
-			result.Screenshot = filepath
+			if blob.Deduplicated {
+				log.Debug("截图内容已存在，复用已保存的文件", "url", target, "hash", blob.Hash)
+			}
+			result.Filename = blob.Location
+			result.Screenshot = blob.Location
+			result.ScreenshotHash = blob.Hash
+		}
+	}
+
+	// 保存多视口截图
+	for i, viewport := range viewports {
+		if len(viewportBufs[i]) == 0 || c.opts.Scan.ScreenshotSkipSave {
+			continue
+		}
+		blob, err := c.store.Put(ctx, viewportBufs[i], "."+c.opts.Scan.ScreenshotFormat)
+		if err != nil {
+			log.Error("保存视口截图失败", "viewport", viewport.String(), "error", err)
+			continue
+		}
+		capture := models.Viewport{
+			Width:      viewport.Width,
+			Height:     viewport.Height,
+			Screenshot: blob.Location,
+			Hash:       blob.Hash,
+		}
+		if hash, err := phash.FromBytes(viewportBufs[i]); err == nil {
+			capture.PerceptionHash = hash
+		}
+		result.Viewports = append(result.Viewports, capture)
+	}
+
+	// 保存PDF，与截图一样按内容哈希保存
+	if len(pdfBuf) > 0 {
+		blob, err := c.store.Put(ctx, pdfBuf, ".pdf")
+		if err != nil {
+			log.Error("保存PDF失败", "error", err)
+		} else {
+			result.IsPDF = true
+			result.PDF = blob.Location
+			result.PDFHash = blob.Hash
+		}
+	}
+
+	// 保存页面存档，记录内容哈希和采集时间用于证据保全
+	if len(archiveBuf) > 0 {
+		blob, err := c.store.Put(ctx, archiveBuf, ".mhtml")
+		if err != nil {
+			log.Error("保存页面存档失败", "error", err)
+		} else {
+			result.Archive = blob.Location
+			result.ArchiveHash = blob.Hash
+			result.ArchivedAt = &archivedAt
 		}
 	}
 
@@ -470,16 +689,63 @@ func (c *ChromeDP) Witness(target string, runner *Runner) (*models.Result, error
 
 	// 保存网络日志
 	if c.opts.Scan.SaveNetwork {
-		for _, nl := range networkEvents {
-			result.Network = append(result.Network, *nl)
+		result.Network = recorder.NetworkLogs()
+	}
+
+	// 保存HAR文件
+	if c.opts.Scan.SaveHAR {
+		data, err := har.Marshal(recorder.Log(title))
+		if err == nil {
+			var blob *storage.Blob
+			if blob, err = c.store.Put(ctx, data, ".har"); err == nil {
+				result.HAR = blob.Location
+				result.HARHash = blob.Hash
+			}
+		}
+		if err != nil {
+			log.Error("保存HAR文件失败", "error", err)
 		}
 	}
 
 	return result, nil
 }
 
+// tlsFromSecurityDetails 将CDP返回的安全信息转换为TLS记录
+func tlsFromSecurityDetails(details *network.SecurityDetails) models.TLS {
+	tls := models.TLS{
+		Version:     details.Protocol,
+		CipherSuite: details.Cipher,
+		Issuer:      details.Issuer,
+		Subject:     details.SubjectName,
+		SANs:        strings.Join(details.SanList, ","),
+	}
+	if details.ValidFrom != nil {
+		tls.NotBefore = details.ValidFrom.Time()
+	}
+	if details.ValidTo != nil {
+		tls.NotAfter = details.ValidTo.Time()
+	}
+	return tls
+}
+
+// originOf 返回URL的源（协议、主机和端口）
+func originOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
 // Close implements the Driver interface
 func (c *ChromeDP) Close() {
+	c.proxyMu.Lock()
+	for _, cancel := range c.proxyCancels {
+		cancel()
+	}
+	c.proxyCancels = nil
+	c.proxyMu.Unlock()
+
 	if c.cancel != nil {
 		c.cancel()
 	}
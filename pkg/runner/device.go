@@ -0,0 +1,275 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// Target 表示一次截图的目标
+type Target struct {
+	URL    string  // 目标URL
+	Device *Device // 模拟的设备，为nil时使用浏览器窗口大小和User-Agent
+
+	// 从代理记录中导入的请求头和Cookie，只发送给目标所在的主机
+	Headers []HeaderRule
+	Cookies []CustomCookie
+
+	// 产生目标主机的扩展来源，如 ct、wordlist，记录在结果中
+	Source string
+}
+
+// Device 表示设备模拟配置
+type Device struct {
+	Name              string       `json:"name"`
+	Width             int64        `json:"width"`
+	Height            int64        `json:"height"`
+	DeviceScaleFactor float64      `json:"device_scale_factor"`
+	Mobile            bool         `json:"mobile"`
+	Touch             bool         `json:"touch"`
+	UserAgent         string       `json:"user_agent"`
+	ClientHints       *ClientHints `json:"client_hints,omitempty"`
+}
+
+// ClientHints 表示设备的User-Agent客户端提示，对应 Sec-CH-UA 系列请求头和 navigator.userAgentData
+type ClientHints struct {
+	Brands          []Brand `json:"brands,omitempty"`
+	Platform        string  `json:"platform"`
+	PlatformVersion string  `json:"platform_version"`
+	Architecture    string  `json:"architecture"`
+	Bitness         string  `json:"bitness,omitempty"`
+	Model           string  `json:"model"`
+	Mobile          bool    `json:"mobile"`
+}
+
+// Brand 表示客户端提示中的浏览器品牌
+type Brand struct {
+	Brand   string `json:"brand"`
+	Version string `json:"version"`
+}
+
+// chromeBrands 是预置的Chrome设备使用的浏览器品牌
+var chromeBrands = []Brand{
+	{Brand: "Chromium", Version: "124"},
+	{Brand: "Google Chrome", Version: "124"},
+	{Brand: "Not-A.Brand", Version: "99"},
+}
+
+// devicePresets 是预置的设备配置，按名称查找时不区分大小写
+var devicePresets = map[string]*Device{
+	"desktop": {
+		Width: 1920, Height: 1080, DeviceScaleFactor: 1,
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+		ClientHints: &ClientHints{
+			Brands: chromeBrands, Platform: "Windows", PlatformVersion: "15.0.0", Architecture: "x86", Bitness: "64",
+		},
+	},
+	"laptop": {
+		Width: 1440, Height: 900, DeviceScaleFactor: 2,
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+		ClientHints: &ClientHints{
+			Brands: chromeBrands, Platform: "macOS", PlatformVersion: "14.4.0", Architecture: "arm", Bitness: "64",
+		},
+	},
+	"iphone": {
+		Width: 393, Height: 852, DeviceScaleFactor: 3, Mobile: true, Touch: true,
+		UserAgent:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+		ClientHints: &ClientHints{Platform: "iOS", PlatformVersion: "17.5", Model: "iPhone", Mobile: true},
+	},
+	"iphone-se": {
+		Width: 375, Height: 667, DeviceScaleFactor: 2, Mobile: true, Touch: true,
+		UserAgent:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+		ClientHints: &ClientHints{Platform: "iOS", PlatformVersion: "17.5", Model: "iPhone", Mobile: true},
+	},
+	"pixel": {
+		Width: 412, Height: 915, DeviceScaleFactor: 2.625, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+		ClientHints: &ClientHints{
+			Brands: chromeBrands, Platform: "Android", PlatformVersion: "14.0.0", Model: "Pixel 7", Mobile: true,
+		},
+	},
+	"ipad": {
+		Width: 820, Height: 1180, DeviceScaleFactor: 2, Mobile: true, Touch: true,
+		UserAgent:   "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+		ClientHints: &ClientHints{Platform: "iOS", PlatformVersion: "17.5", Model: "iPad", Mobile: true},
+	},
+	"ipad-pro": {
+		Width: 1024, Height: 1366, DeviceScaleFactor: 2, Mobile: true, Touch: true,
+		UserAgent:   "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+		ClientHints: &ClientHints{Platform: "iOS", PlatformVersion: "17.5", Model: "iPad", Mobile: true},
+	},
+}
+
+// DeviceNames 返回所有预置设备的名称
+func DeviceNames() []string {
+	names := make([]string, 0, len(devicePresets))
+	for name := range devicePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadDevices 按名称查找设备配置，file 为自定义设备配置文件（JSON数组）
+// 配置文件中的设备优先于同名的预置设备；未指定名称时使用配置文件中的所有设备
+func LoadDevices(names []string, file string) ([]*Device, error) {
+	custom := make(map[string]*Device)
+	var all []*Device
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取设备配置文件失败: %v", err)
+		}
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, fmt.Errorf("解析设备配置文件失败: %v", err)
+		}
+		for _, device := range all {
+			if err := device.validate(); err != nil {
+				return nil, err
+			}
+			custom[strings.ToLower(device.Name)] = device
+		}
+	}
+	if len(names) == 0 {
+		return all, nil
+	}
+
+	devices := make([]*Device, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		key := strings.ToLower(name)
+		if device, ok := custom[key]; ok {
+			devices = append(devices, device)
+			continue
+		}
+		preset, ok := devicePresets[key]
+		if !ok {
+			return nil, fmt.Errorf("未知的设备: %s (可选 %s，或使用设备配置文件)", name, strings.Join(DeviceNames(), "、"))
+		}
+		device := *preset
+		device.Name = key
+		devices = append(devices, &device)
+	}
+	return devices, nil
+}
+
+// name 返回设备名称，未模拟设备时返回空字符串
+func (d *Device) name() string {
+	if d == nil {
+		return ""
+	}
+	return d.Name
+}
+
+// validate 检查自定义设备配置
+func (d *Device) validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("设备配置缺少名称")
+	}
+	if d.Width <= 0 || d.Height <= 0 {
+		return fmt.Errorf("设备 %s 的宽度和高度必须大于0", d.Name)
+	}
+	if d.DeviceScaleFactor < 0 {
+		return fmt.Errorf("设备 %s 的像素比不能为负数", d.Name)
+	}
+	return nil
+}
+
+// setDeviceMetrics 设置设备的视口大小、像素比和屏幕方向
+func setDeviceMetrics(ctx context.Context, device *Device) error {
+	orientation := &emulation.ScreenOrientation{Type: emulation.OrientationTypePortraitPrimary}
+	if device.Width > device.Height {
+		orientation = &emulation.ScreenOrientation{Type: emulation.OrientationTypeLandscapePrimary, Angle: 90}
+	}
+	err := emulation.SetDeviceMetricsOverride(device.Width, device.Height, device.DeviceScaleFactor, device.Mobile).
+		WithScreenWidth(device.Width).
+		WithScreenHeight(device.Height).
+		WithScreenOrientation(orientation).
+		Do(ctx)
+	if err != nil {
+		return fmt.Errorf("设置设备 %s 的视口失败: %v", device.Name, err)
+	}
+	return nil
+}
+
+// emulateDevice 返回模拟设备的任务，设置视口大小、像素比、触摸、User-Agent和客户端提示
+// acceptLanguage 不为空时同时设置Accept-Language请求头，stealth 为true时去掉浏览器默认User-Agent中的无头标记
+func emulateDevice(device *Device, acceptLanguage string, stealth bool) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := setDeviceMetrics(ctx, device); err != nil {
+			return err
+		}
+
+		touch := emulation.SetTouchEmulationEnabled(device.Touch)
+		if device.Touch {
+			touch = touch.WithMaxTouchPoints(5)
+		}
+		if err := touch.Do(ctx); err != nil {
+			return fmt.Errorf("设置设备 %s 的触摸模拟失败: %v", device.Name, err)
+		}
+
+		if device.UserAgent == "" {
+			if acceptLanguage != "" || stealth {
+				return overrideUserAgent("", acceptLanguage, stealth).Do(ctx)
+			}
+			return nil
+		}
+		override := emulation.SetUserAgentOverride(device.UserAgent).WithAcceptLanguage(acceptLanguage)
+		if hints := device.ClientHints; hints != nil {
+			override = override.
+				WithPlatform(navigatorPlatform(hints)).
+				WithUserAgentMetadata(&emulation.UserAgentMetadata{
+					Brands:          userAgentBrands(hints.Brands),
+					Platform:        hints.Platform,
+					PlatformVersion: hints.PlatformVersion,
+					Architecture:    hints.Architecture,
+					Bitness:         hints.Bitness,
+					Model:           hints.Model,
+					Mobile:          hints.Mobile,
+				})
+		}
+		if err := override.Do(ctx); err != nil {
+			return fmt.Errorf("设置设备 %s 的User-Agent失败: %v", device.Name, err)
+		}
+		return nil
+	})
+}
+
+// userAgentBrands 转换客户端提示中的浏览器品牌
+func userAgentBrands(brands []Brand) []*emulation.UserAgentBrandVersion {
+	versions := make([]*emulation.UserAgentBrandVersion, 0, len(brands))
+	for _, brand := range brands {
+		versions = append(versions, &emulation.UserAgentBrandVersion{Brand: brand.Brand, Version: brand.Version})
+	}
+	return versions
+}
+
+// navigatorPlatform 返回客户端提示平台对应的 navigator.platform 值
+func navigatorPlatform(hints *ClientHints) string {
+	switch strings.ToLower(hints.Platform) {
+	case "windows":
+		return "Win32"
+	case "macos":
+		return "MacIntel"
+	case "ios":
+		if hints.Model != "" {
+			return hints.Model
+		}
+		return "iPhone"
+	case "android":
+		return "Linux armv8l"
+	case "linux":
+		return "Linux x86_64"
+	default:
+		return hints.Platform
+	}
+}
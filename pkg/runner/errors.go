@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrorClass 表示截图失败原因的分类，记录在结果中，用于决定是否重试
+type ErrorClass string
+
+// 截图失败原因的分类
+const (
+	ErrorNone              ErrorClass = ""
+	ErrorDNS               ErrorClass = "dns"                // 域名解析失败
+	ErrorConnectionRefused ErrorClass = "connection_refused" // 连接被拒绝
+	ErrorTLS               ErrorClass = "tls"                // 证书或TLS握手错误
+	ErrorTimeout           ErrorClass = "timeout"            // 连接或页面加载超时
+	ErrorNetwork           ErrorClass = "network"            // 连接重置、空响应等其他网络错误
+	ErrorProxy             ErrorClass = "proxy"              // 代理连接失败
+	ErrorHTTP5xx           ErrorClass = "http_5xx"           // 目标返回5xx状态码
+	ErrorBlacklisted       ErrorClass = "blacklisted"        // URL在黑名单中，未扫描
+	ErrorInvalidURL        ErrorClass = "invalid_url"        // URL无效，未扫描
+	ErrorBrowserCrash      ErrorClass = "browser_crash"      // 浏览器或标签页崩溃、连接断开
+	ErrorUnknown           ErrorClass = "unknown"            // 其他错误
+)
+
+// errorClasses 是可以在重试策略中指定的错误分类
+var errorClasses = []ErrorClass{
+	ErrorDNS, ErrorConnectionRefused, ErrorTLS, ErrorTimeout, ErrorNetwork,
+	ErrorProxy, ErrorHTTP5xx, ErrorBrowserCrash, ErrorUnknown,
+}
+
+// TargetError 表示带有错误分类的截图错误
+type TargetError struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *TargetError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TargetError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError 根据截图错误和目标的HTTP状态码判断错误分类
+// Chrome的网络错误只能从错误信息中的 net::ERR_* 代码判断
+func ClassifyError(err error, status int) ErrorClass {
+	if err == nil {
+		if status >= 500 {
+			return ErrorHTTP5xx
+		}
+		return ErrorNone
+	}
+
+	var targetErr *TargetError
+	if errors.As(err, &targetErr) {
+		return targetErr.Class
+	}
+	var chromeErr ChromeNotFoundError
+	if errors.As(err, &chromeErr) {
+		return ErrorBrowserCrash
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+
+	message := err.Error()
+	if isProxyError(err) {
+		return ErrorProxy
+	}
+	for _, rule := range []struct {
+		class ErrorClass
+		codes []string
+	}{
+		{ErrorDNS, []string{"net::ERR_NAME_NOT_RESOLVED", "net::ERR_NAME_RESOLUTION_FAILED", "net::ERR_DNS_"}},
+		{ErrorConnectionRefused, []string{"net::ERR_CONNECTION_REFUSED"}},
+		{ErrorTLS, []string{"net::ERR_CERT_", "net::ERR_SSL_", "net::ERR_BAD_SSL_", "net::ERR_CERTIFICATE_"}},
+		{ErrorTimeout, []string{"net::ERR_TIMED_OUT", "net::ERR_CONNECTION_TIMED_OUT", "deadline exceeded"}},
+		{ErrorBrowserCrash, []string{
+			"target crashed", "Target closed", "Inspector.detached", "websocket: close",
+			"use of closed network connection", "invalid context", "chrome failed to start",
+		}},
+		{ErrorNetwork, []string{"net::ERR_"}},
+	} {
+		for _, code := range rule.codes {
+			if strings.Contains(message, code) {
+				return rule.class
+			}
+		}
+	}
+	return ErrorUnknown
+}
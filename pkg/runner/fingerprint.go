@@ -0,0 +1,175 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	goruntime "runtime"
+	"strings"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// fingerprint 是传给指纹伪装脚本的配置，序列化为JSON后嵌入脚本，避免值中的引号破坏脚本
+type fingerprint struct {
+	Platform      string   `json:"platform,omitempty"`
+	Vendor        string   `json:"vendor,omitempty"`
+	Plugins       []string `json:"plugins,omitempty"`
+	WebGLVendor   string   `json:"webglVendor,omitempty"`
+	WebGLRenderer string   `json:"webglRenderer,omitempty"`
+	ScreenWidth   int      `json:"screenWidth,omitempty"`
+	ScreenHeight  int      `json:"screenHeight,omitempty"`
+	DisableWebRTC bool     `json:"disableWebRTC,omitempty"`
+}
+
+// fingerprintTemplate 是指纹伪装脚本，%s 处为JSON格式的配置
+// 属性定义在原型上，和浏览器原生属性的位置一致
+const fingerprintTemplate = `(() => {
+	const fp = %s;
+	const define = (target, name, value) =>
+		Object.defineProperty(target, name, { get: () => value, configurable: true });
+
+	if (fp.platform) define(Navigator.prototype, 'platform', fp.platform);
+	if (fp.vendor) define(Navigator.prototype, 'vendor', fp.vendor);
+	if (fp.plugins) define(Navigator.prototype, 'plugins', Object.freeze(fp.plugins));
+
+	// 37445 和 37446 分别是 UNMASKED_VENDOR_WEBGL 和 UNMASKED_RENDERER_WEBGL
+	if (fp.webglVendor || fp.webglRenderer) {
+		for (const context of [self.WebGLRenderingContext, self.WebGL2RenderingContext]) {
+			if (!context) continue;
+			const getParameter = context.prototype.getParameter;
+			context.prototype.getParameter = function (parameter) {
+				if (parameter === 37445 && fp.webglVendor) return fp.webglVendor;
+				if (parameter === 37446 && fp.webglRenderer) return fp.webglRenderer;
+				return getParameter.call(this, parameter);
+			};
+		}
+	}
+
+	if (fp.screenWidth && fp.screenHeight && self.Screen) {
+		define(Screen.prototype, 'width', fp.screenWidth);
+		define(Screen.prototype, 'height', fp.screenHeight);
+		define(Screen.prototype, 'availWidth', fp.screenWidth);
+		define(Screen.prototype, 'availHeight', fp.screenHeight);
+		define(Screen.prototype, 'colorDepth', 24);
+		define(Screen.prototype, 'pixelDepth', 24);
+	}
+
+	if (fp.disableWebRTC) {
+		for (const name of ['RTCPeerConnection', 'webkitRTCPeerConnection', 'RTCDataChannel']) {
+			Object.defineProperty(self, name, { value: undefined, configurable: true });
+		}
+	}
+})();`
+
+// fingerprintScript 返回指纹伪装脚本，没有需要伪装的指纹时返回空字符串
+func fingerprintScript(opts *Options) string {
+	fp := fingerprint{
+		Platform:      opts.Chrome.Platform,
+		Vendor:        opts.Chrome.Vendor,
+		Plugins:       opts.Chrome.Plugins,
+		WebGLVendor:   opts.Chrome.WebGLVendor,
+		WebGLRenderer: opts.Chrome.WebGLRenderer,
+		DisableWebRTC: opts.Chrome.DisableWebRTC,
+	}
+	if opts.Chrome.SpoofScreenSize && opts.Chrome.ScreenWidth > 0 && opts.Chrome.ScreenHeight > 0 {
+		fp.ScreenWidth, fp.ScreenHeight = opts.Chrome.ScreenWidth, opts.Chrome.ScreenHeight
+	}
+
+	data, _ := json.Marshal(fp)
+	if string(data) == "{}" {
+		return ""
+	}
+	return fmt.Sprintf(fingerprintTemplate, data)
+}
+
+// stealthScript 隐藏自动化控制的痕迹：navigator.webdriver、通知权限查询结果不一致和User-Agent客户端提示中的HeadlessChrome
+const stealthScript = `(() => {
+	Object.defineProperty(Navigator.prototype, 'webdriver', { get: () => false, configurable: true });
+
+	// 无头模式下通知权限查询返回 denied，而 Notification.permission 为 default
+	if (self.Permissions && self.Notification) {
+		const query = Permissions.prototype.query;
+		Permissions.prototype.query = function (descriptor) {
+			if (descriptor && descriptor.name === 'notifications') {
+				const state = Notification.permission === 'default' ? 'prompt' : Notification.permission;
+				return Promise.resolve(Object.setPrototypeOf({ state, onchange: null }, PermissionStatus.prototype));
+			}
+			return query.call(this, descriptor);
+		};
+	}
+
+	if (self.NavigatorUAData) {
+		const brands = Object.getOwnPropertyDescriptor(NavigatorUAData.prototype, 'brands');
+		if (brands && brands.get) {
+			Object.defineProperty(NavigatorUAData.prototype, 'brands', {
+				get() {
+					return brands.get.call(this).map(b =>
+						b.brand === 'HeadlessChrome' ? { brand: 'Google Chrome', version: b.version } : b);
+				},
+				configurable: true,
+			});
+		}
+	}
+})();`
+
+// overrideUserAgent 返回设置User-Agent覆盖的任务，userAgent为空时使用浏览器默认的User-Agent
+// acceptLanguage 不为空时同时设置Accept-Language请求头；stealth 为true时去掉User-Agent和客户端提示中的HeadlessChrome标记
+func overrideUserAgent(userAgent, acceptLanguage string, stealth bool) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		product := ""
+		if userAgent == "" {
+			var err error
+			if _, product, _, userAgent, _, err = browser.GetVersion().Do(ctx); err != nil {
+				return fmt.Errorf("获取浏览器User-Agent失败: %v", err)
+			}
+		}
+
+		override := emulation.SetUserAgentOverride(userAgent).WithAcceptLanguage(acceptLanguage)
+		if stealth && strings.Contains(userAgent, "HeadlessChrome") {
+			override.UserAgent = strings.ReplaceAll(userAgent, "HeadlessChrome", "Chrome")
+			override = override.WithUserAgentMetadata(stealthUserAgentMetadata(product))
+		}
+		return override.Do(ctx)
+	})
+}
+
+// stealthUserAgentMetadata 根据浏览器版本（如 HeadlessChrome/124.0.6367.91）生成不含无头标记的客户端提示
+func stealthUserAgentMetadata(product string) *emulation.UserAgentMetadata {
+	_, version, _ := strings.Cut(product, "/")
+	major, _, _ := strings.Cut(version, ".")
+	if major == "" {
+		major, version = "124", "124.0.0.0"
+	}
+
+	metadata := &emulation.UserAgentMetadata{
+		Brands: []*emulation.UserAgentBrandVersion{
+			{Brand: "Chromium", Version: major},
+			{Brand: "Google Chrome", Version: major},
+			{Brand: "Not-A.Brand", Version: "99"},
+		},
+		FullVersionList: []*emulation.UserAgentBrandVersion{
+			{Brand: "Chromium", Version: version},
+			{Brand: "Google Chrome", Version: version},
+			{Brand: "Not-A.Brand", Version: "99.0.0.0"},
+		},
+		Bitness: "64",
+	}
+	switch goruntime.GOOS {
+	case "windows":
+		metadata.Platform, metadata.PlatformVersion = "Windows", "15.0.0"
+	case "darwin":
+		metadata.Platform, metadata.PlatformVersion = "macOS", "14.0.0"
+	default:
+		metadata.Platform = "Linux"
+	}
+	switch goruntime.GOARCH {
+	case "arm64", "arm":
+		metadata.Architecture = "arm"
+	default:
+		metadata.Architecture = "x86"
+	}
+	return metadata
+}
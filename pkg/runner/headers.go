@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+)
+
+// HeaderRule 表示一条自定义请求头规则
+type HeaderRule struct {
+	Host  string // 主机模式，如 *.example.com，为空时对所有请求生效
+	Name  string // 请求头名称
+	Value string // 请求头的值
+}
+
+// ParseHeaderRules 解析 "名称: 值" 或 "主机模式=名称: 值" 格式的请求头
+// 请求头名称中不能包含等号，因此冒号前的第一个等号用于分隔主机模式
+func ParseHeaderRules(values []string) ([]HeaderRule, error) {
+	rules := make([]HeaderRule, 0, len(values))
+	for _, value := range values {
+		name, headerValue, ok := strings.Cut(value, ":")
+		if !ok {
+			return nil, fmt.Errorf("无效的请求头: %s (格式为 \"名称: 值\" 或 \"主机模式=名称: 值\")", value)
+		}
+		var host string
+		if pattern, headerName, found := strings.Cut(name, "="); found {
+			host, name = strings.ToLower(strings.TrimSpace(pattern)), headerName
+			if _, err := path.Match(host, ""); err != nil || host == "" {
+				return nil, fmt.Errorf("无效的主机模式: %s", pattern)
+			}
+		}
+		name = strings.TrimSpace(name)
+		if name == "" || strings.ContainsAny(name, " \t\r\n") {
+			return nil, fmt.Errorf("无效的请求头名称: %s", value)
+		}
+		rules = append(rules, HeaderRule{Host: host, Name: name, Value: strings.TrimSpace(headerValue)})
+	}
+	return rules, nil
+}
+
+// headerRules 合并Chrome.CustomHeaders和Chrome.Headers中的请求头规则
+func headerRules(opts *Options) ([]HeaderRule, error) {
+	rules, err := ParseHeaderRules(opts.Chrome.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	// map没有顺序，按名称排序保证每次发送的请求头一致
+	names := make([]string, 0, len(opts.Chrome.CustomHeaders))
+	for name := range opts.Chrome.CustomHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	global := make([]HeaderRule, 0, len(names))
+	for _, name := range names {
+		global = append(global, HeaderRule{Name: name, Value: opts.Chrome.CustomHeaders[name]})
+	}
+	return append(global, rules...), nil
+}
+
+// matchHost 检查主机名是否匹配模式，*.example.com 匹配 example.com 的所有子域名
+func matchHost(pattern, host string) bool {
+	host = strings.ToLower(host)
+	if strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]) {
+		return true
+	}
+	matched, _ := path.Match(pattern, host)
+	return matched
+}
+
+// requestHeaders 返回添加了匹配规则的请求头后的完整请求头
+func requestHeaders(rules []HeaderRule, request *network.Request) []*fetch.HeaderEntry {
+	host := ""
+	if u, err := url.Parse(request.URL); err == nil {
+		host = u.Hostname()
+	}
+
+	headers := make(map[string]string, len(request.Headers)+len(rules))
+	names := make(map[string]string, len(request.Headers)+len(rules))
+	set := func(name, value string) {
+		key := strings.ToLower(name)
+		if _, ok := names[key]; !ok {
+			names[key] = name
+		}
+		headers[key] = value
+	}
+	for name, value := range request.Headers {
+		set(name, fmt.Sprint(value))
+	}
+	for _, rule := range rules {
+		if rule.Host == "" || matchHost(rule.Host, host) {
+			set(rule.Name, rule.Value)
+		}
+	}
+
+	entries := make([]*fetch.HeaderEntry, 0, len(headers))
+	for key, value := range headers {
+		entries = append(entries, &fetch.HeaderEntry{Name: names[key], Value: value})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+// parseLanguages 解析Accept-Language中的语言列表，忽略权重
+func parseLanguages(acceptLanguage string) []string {
+	var languages []string
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		language, _, _ := strings.Cut(part, ";")
+		if language = strings.TrimSpace(language); language != "" && language != "*" {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// languagesScript 返回把 navigator.language 和 navigator.languages 设置为指定语言的脚本
+func languagesScript(languages []string) string {
+	data, _ := json.Marshal(languages)
+	return fmt.Sprintf(`(() => {
+	const languages = Object.freeze(%s);
+	Object.defineProperty(Navigator.prototype, 'languages', { get: () => languages, configurable: true });
+	Object.defineProperty(Navigator.prototype, 'language', { get: () => languages[0], configurable: true });
+})();`, data)
+}
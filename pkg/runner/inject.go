@@ -0,0 +1,17 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// addInitScript 返回添加页面初始化脚本的任务，脚本在每个新文档（包括iframe）中先于页面脚本执行
+// 每次截图使用新的标签页，脚本随标签页关闭，不需要移除
+func addInitScript(source string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		_, err := page.AddScriptToEvaluateOnNewDocument(source).Do(ctx)
+		return err
+	})
+}
@@ -0,0 +1,126 @@
+package runner
+
+import (
+	"context"
+	"net/url"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+
+	"github.com/cyberspacesec/go-snir/pkg/log"
+)
+
+// interceptor 通过Fetch域拦截请求，给匹配的主机添加请求头，并响应HTTP身份验证和代理身份验证
+type interceptor struct {
+	headers     []HeaderRule
+	hostHeaders bool
+	credentials []Credential
+	proxy       *Credential
+
+	mu       sync.Mutex
+	answered map[fetch.RequestID]bool // 已提供过凭据的请求，凭据错误时不再重复提供
+}
+
+// interceptRequests 返回发送自定义请求头和响应身份验证的任务
+// 只有全局请求头时使用 Network.setExtraHTTPHeaders；有按主机生效的请求头或凭据时拦截请求，
+// 只给匹配的主机添加请求头和凭据，避免泄露给第三方
+func interceptRequests(ctx context.Context, headers []HeaderRule, credentials []Credential, proxy *Credential) chromedp.Action {
+	i := &interceptor{
+		headers:     headers,
+		credentials: credentials,
+		proxy:       proxy,
+		answered:    make(map[fetch.RequestID]bool),
+	}
+	global := network.Headers{}
+	for _, rule := range headers {
+		if rule.Host != "" {
+			i.hostHeaders = true
+		} else {
+			global[rule.Name] = rule.Value
+		}
+	}
+
+	var tasks chromedp.Tasks
+	if !i.hostHeaders && len(global) > 0 {
+		tasks = append(tasks, network.SetExtraHTTPHeaders(global))
+	}
+	auth := len(credentials) > 0 || proxy != nil
+	if !i.hostHeaders && !auth {
+		return tasks
+	}
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch e := ev.(type) {
+		case *fetch.EventRequestPaused:
+			// 事件处理协程中不能执行CDP命令，需要在新的协程中继续请求
+			go i.continueRequest(ctx, e)
+		case *fetch.EventAuthRequired:
+			go i.continueWithAuth(ctx, e)
+		}
+	})
+	return append(tasks, fetch.Enable().
+		WithPatterns([]*fetch.RequestPattern{{URLPattern: "*"}}).
+		WithHandleAuthRequests(auth))
+}
+
+// continueRequest 继续被拦截的请求，有按主机生效的请求头时添加匹配的请求头
+func (i *interceptor) continueRequest(ctx context.Context, e *fetch.EventRequestPaused) {
+	continueRequest := fetch.ContinueRequest(e.RequestID)
+	if i.hostHeaders {
+		continueRequest = continueRequest.WithHeaders(requestHeaders(i.headers, e.Request))
+	}
+	err := continueRequest.Do(cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target))
+	if err != nil && ctx.Err() == nil {
+		log.Debug("继续请求失败", "url", e.Request.URL, "error", err)
+	}
+}
+
+// continueWithAuth 响应身份验证质询，没有匹配的凭据或凭据已被拒绝时取消验证，页面显示401或407响应
+func (i *interceptor) continueWithAuth(ctx context.Context, e *fetch.EventAuthRequired) {
+	response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+	if credential, ok := i.credential(e); ok {
+		response = &fetch.AuthChallengeResponse{
+			Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+			Username: credential.Username,
+			Password: credential.Password,
+		}
+	}
+
+	// 日志中只记录质询来源，不记录凭据
+	log.Debug("响应身份验证", "origin", e.AuthChallenge.Origin, "source", e.AuthChallenge.Source,
+		"scheme", e.AuthChallenge.Scheme, "response", response.Response)
+	err := fetch.ContinueWithAuth(e.RequestID, response).Do(cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target))
+	if err != nil && ctx.Err() == nil {
+		log.Debug("响应身份验证失败", "url", e.Request.URL, "error", err)
+	}
+}
+
+// credential 返回身份验证质询对应的凭据，同一请求只提供一次凭据
+func (i *interceptor) credential(e *fetch.EventAuthRequired) (Credential, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.answered[e.RequestID] {
+		return Credential{}, false
+	}
+
+	var credential Credential
+	var ok bool
+	if e.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+		if i.proxy != nil {
+			credential, ok = *i.proxy, true
+		}
+	} else {
+		host := ""
+		if u, err := url.Parse(e.AuthChallenge.Origin); err == nil {
+			host = u.Hostname()
+		}
+		credential, ok = findCredential(i.credentials, host)
+	}
+	if ok {
+		i.answered[e.RequestID] = true
+	}
+	return credential, ok
+}
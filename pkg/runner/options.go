@@ -1,5 +1,7 @@
 package runner
 
+import "time"
+
 // Options contains all the configuration options for the runner
 type Options struct {
 	// API server options
@@ -18,16 +20,19 @@ type Options struct {
 
 	// Chrome options
 	Chrome struct {
-		Path             string // Chrome可执行文件路径
-		UserAgent        string // 自定义User-Agent
-		Proxy            string // 代理服务器地址
-		Timeout          int    // 页面加载超时时间（秒）
-		Delay            int    // 截图前等待时间（秒）
-		WindowX          int    // 窗口宽度
-		WindowY          int    // 窗口高度
-		WSS              string // WebSocket服务器地址
-		Headless         bool   // 是否使用无头模式
-		IgnoreCertErrors bool   // 是否忽略证书错误
+		Path             string   // Chrome可执行文件路径
+		UserAgent        string   // 自定义User-Agent
+		Proxy            string   // 代理服务器地址
+		Proxies          []string // 代理池中的代理地址
+		ProxyFile        string   // 代理列表文件路径，每行一个代理
+		ProxyStrategy    string   // 代理选择策略：round-robin、random、sticky或failover
+		Timeout          int      // 页面加载超时时间（秒）
+		Delay            int      // 截图前等待时间（秒）
+		WindowX          int      // 窗口宽度
+		WindowY          int      // 窗口高度
+		WSS              string   // WebSocket服务器地址
+		Headless         bool     // 是否使用无头模式
+		IgnoreCertErrors bool     // 是否忽略证书错误
 
 		// 高级浏览器控制
 		AcceptLanguage  string            // 接受的语言
@@ -37,37 +42,60 @@ type Options struct {
 		WebGLVendor     string            // WebGL供应商
 		WebGLRenderer   string            // WebGL渲染器
 		CustomHeaders   map[string]string // 自定义HTTP头
+		Headers         []string          // 自定义HTTP头，格式为 "名称: 值" 或 "主机模式=名称: 值"
 		DisableWebRTC   bool              // 是否禁用WebRTC
 		SpoofScreenSize bool              // 是否欺骗屏幕尺寸
 		ScreenWidth     int               // 屏幕宽度
 		ScreenHeight    int               // 屏幕高度
+		Stealth         bool              // 是否隐藏navigator.webdriver、权限查询和无头User-Agent等自动化痕迹
+		Auth            []string          // HTTP身份验证凭据，格式为 "用户名:密码" 或 "主机模式=用户名:密码"
+		AuthFile        string            // 凭据文件路径，每行一条凭据
+		Credentials     []Credential      // API请求中的HTTP身份验证凭据
 	}
 
 	// Scan options
 	Scan struct {
-		Driver             string   // 使用的驱动（chromedp）
-		Threads            int      // 并发线程数
-		ScreenshotPath     string   // 截图保存路径
-		ScreenshotFormat   string   // 截图格式（jpeg或png）
-		ScreenshotQuality  int      // 截图质量（仅对JPEG有效）
-		ScreenshotSkipSave bool     // 是否跳过保存截图
-		SaveHTML           bool     // 是否保存HTML内容
-		SaveHeaders        bool     // 是否保存HTTP头
-		SaveConsole        bool     // 是否保存控制台日志
-		SaveCookies        bool     // 是否保存Cookie
-		SaveNetwork        bool     // 是否保存网络请求日志
-		HTTP               bool     // 是否使用HTTP协议
-		HTTPS              bool     // 是否使用HTTPS协议
-		Ports              []int    // 扫描的端口列表
-		Timeout            int      // 扫描超时时间（秒）
-		MaxRetries         int      // 最大重试次数
-		JavaScript         string   // 要在页面上执行的JavaScript代码
-		JavaScriptFile     string   // 包含JavaScript代码的文件路径
-		FilePath           string   // URL文件路径，用于批量扫描
-		EnableBlacklist    bool     // 是否启用URL黑名单
-		DefaultBlacklist   bool     // 是否使用默认黑名单
-		BlacklistPatterns  []string // 自定义黑名单规则（支持CIDR和正则表达式）
-		BlacklistFile      string   // 黑名单文件路径
+		Driver             string        // 使用的驱动（chromedp）
+		Threads            int           // 并发线程数
+		ScreenshotPath     string        // 截图保存路径
+		Storage            string        // 截图存储URI，如 s3://bucket/prefix，为空时保存到截图保存路径
+		ScreenshotFormat   string        // 截图格式（jpeg或png）
+		ScreenshotQuality  int           // 截图质量（仅对JPEG有效）
+		ScreenshotSkipSave bool          // 是否跳过保存截图
+		SaveHTML           bool          // 是否保存HTML内容
+		SaveHeaders        bool          // 是否保存HTTP头
+		SaveConsole        bool          // 是否保存控制台日志
+		SaveCookies        bool          // 是否保存Cookie
+		SaveNetwork        bool          // 是否保存网络请求日志
+		HTTP               bool          // 是否使用HTTP协议
+		HTTPS              bool          // 是否使用HTTPS协议
+		Ports              []int         // 扫描的端口列表
+		Timeout            int           // 扫描超时时间（秒）
+		MaxRetries         int           // 最大重试次数
+		RetryBackoff       time.Duration // 第一次重试前的等待时间，之后每次加倍
+		RetryOn            []string      // 需要重试的错误分类，为空时使用 DefaultRetryOn
+		JavaScript         string        // 要在页面上执行的JavaScript代码
+		JavaScriptFile     string        // 包含JavaScript代码的文件路径
+		FilePath           string        // URL文件路径，用于批量扫描
+		GetOnly            bool          // 从代理记录导入时只导入GET请求
+		EnableBlacklist    bool          // 是否启用URL黑名单
+		DefaultBlacklist   bool          // 是否使用默认黑名单
+		BlacklistPatterns  []string      // 自定义黑名单规则（支持CIDR和正则表达式）
+		BlacklistFile      string        // 黑名单文件路径
+
+		// 速率限制
+		RateLimit       float64       // 全局每秒最大请求数，为0时不限制
+		HostRateLimit   float64       // 同一主机每秒最大请求数，为0时不限制
+		HostConcurrency int           // 同一主机的最大并发数，为0时不限制
+		RateLimitBy     string        // 按主机（host）或注册域名（domain）限速
+		Jitter          time.Duration // 每次请求前额外等待的随机时间上限
+
+		// 子域名扩展
+		CTFiles        []string      // 证书透明度记录文件（crt.sh 导出的JSON、JSON日志或名称列表）
+		Wordlists      []string      // 子域名字典文件
+		Permute        bool          // 是否把字典与证书透明度记录中的子域名组合
+		ResolveThreads int           // 并发解析数
+		ResolveTimeout time.Duration // 单次解析的超时时间
 
 		// 高级功能
 		RunJSBefore     bool                // 在页面加载前执行JS
@@ -76,6 +104,14 @@ type Options struct {
 		Selector        string              // CSS选择器，用于元素截图
 		XPath           string              // XPath，用于元素截图
 		CaptureFullPage bool                // 是否捕获整个页面
+		Capture         string              // 采集模式（screenshot、pdf或both），为空时只截图
+		PDF             PDFOptions          // PDF选项
+		Archive         bool                // 是否保存MHTML页面存档
+		SaveHAR         bool                // 是否保存HAR文件
+		HARBodyLimit    int64               // HAR中保存的单个响应体大小上限（字节），为0时不保存响应体
+		Devices         []string            // 模拟的设备名称，每个设备截图一次
+		DeviceFile      string              // 自定义设备配置文件（JSON）
+		Viewports       []string            // 页面加载后依次截图的视口大小，如 1920x1080
 		Actions         []InteractionAction // 交互操作列表
 		Form            Form                // 表单配置
 	}
@@ -94,8 +130,13 @@ type Options struct {
 
 	// Database options
 	DB struct {
-		Enable bool   // 是否启用数据库
-		Path   string // 数据库文件路径
+		Enable          bool          // 是否启用数据库
+		Path            string        // 数据库文件路径
+		SessionName     string        // 扫描会话名称
+		Operator        string        // 扫描操作者
+		MaxOpenConns    int           // 最大打开连接数
+		MaxIdleConns    int           // 最大空闲连接数
+		ConnMaxLifetime time.Duration // 连接最大存活时间
 	}
 
 	// Report options
@@ -136,6 +177,16 @@ type FormField struct {
 	Type     string // input, select, checkbox, radio
 }
 
+// PDFOptions 表示PDF采集选项
+type PDFOptions struct {
+	Paper           string // 纸张大小，如 A4、Letter，为空时使用A4
+	Landscape       bool   // 是否横向打印
+	Margin          string // 页边距，如 1cm 或 10mm,5mm,10mm,5mm（上、右、下、左）
+	PrintBackground bool   // 是否打印背景图形
+	HeaderTemplate  string // 页眉HTML模板
+	FooterTemplate  string // 页脚HTML模板
+}
+
 // Form 表示表单配置
 type Form struct {
 	Fields          []FormField // 表单字段
@@ -0,0 +1,177 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// 页面采集模式
+const (
+	CaptureScreenshot = "screenshot" // 只截图
+	CapturePDF        = "pdf"        // 只生成PDF
+	CaptureBoth       = "both"       // 同时截图和生成PDF
+)
+
+// paperSizes 是支持的纸张大小，单位为英寸（宽, 高）
+var paperSizes = map[string][2]float64{
+	"a3":      {11.69, 16.54},
+	"a4":      {8.27, 11.69},
+	"a5":      {5.83, 8.27},
+	"letter":  {8.5, 11},
+	"legal":   {8.5, 14},
+	"tabloid": {11, 17},
+}
+
+// marginUnits 是页边距支持的单位及其对应的英寸数
+var marginUnits = []struct {
+	suffix string
+	inches float64
+}{
+	{"mm", 1 / 25.4},
+	{"cm", 1 / 2.54},
+	{"in", 1},
+	{"px", 1.0 / 96},
+}
+
+// captureModes 返回是否需要截图和生成PDF，mode为空时只截图
+func captureModes(mode string) (screenshot bool, pdf bool) {
+	switch strings.ToLower(mode) {
+	case CapturePDF:
+		return false, true
+	case CaptureBoth:
+		return true, true
+	default:
+		return true, false
+	}
+}
+
+// ValidateCapture 检查采集模式和PDF选项
+func ValidateCapture(opts *Options) error {
+	switch strings.ToLower(opts.Scan.Capture) {
+	case "", CaptureScreenshot, CapturePDF, CaptureBoth:
+	default:
+		return fmt.Errorf("无效的采集模式: %s (可选 screenshot、pdf、both)", opts.Scan.Capture)
+	}
+	if _, pdf := captureModes(opts.Scan.Capture); !pdf {
+		return nil
+	}
+	if _, err := paperSize(opts.Scan.PDF.Paper); err != nil {
+		return err
+	}
+	if _, err := ParseMargins(opts.Scan.PDF.Margin); err != nil {
+		return err
+	}
+	return nil
+}
+
+// paperSize 返回纸张大小，为空时使用A4
+func paperSize(name string) ([2]float64, error) {
+	if name == "" {
+		return paperSizes["a4"], nil
+	}
+	size, ok := paperSizes[strings.ToLower(name)]
+	if !ok {
+		return size, fmt.Errorf("不支持的纸张大小: %s (可选 A3、A4、A5、Letter、Legal、Tabloid)", name)
+	}
+	return size, nil
+}
+
+// ParseMargins 解析页边距，返回以英寸为单位的上、右、下、左边距
+// 支持一个值（四边相同）、两个值（上下, 左右）或四个值（上, 右, 下, 左），单位为 mm、cm、in 或 px，不带单位时为英寸
+func ParseMargins(value string) ([4]float64, error) {
+	var margins [4]float64
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return margins, nil
+	}
+
+	parts := strings.Split(value, ",")
+	values := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		margin, err := parseLength(strings.TrimSpace(part))
+		if err != nil {
+			return margins, fmt.Errorf("无效的页边距: %s", value)
+		}
+		values = append(values, margin)
+	}
+
+	switch len(values) {
+	case 1:
+		margins = [4]float64{values[0], values[0], values[0], values[0]}
+	case 2:
+		margins = [4]float64{values[0], values[1], values[0], values[1]}
+	case 4:
+		copy(margins[:], values)
+	default:
+		return margins, fmt.Errorf("无效的页边距: %s", value)
+	}
+	return margins, nil
+}
+
+// parseLength 解析带单位的长度并转换为英寸
+func parseLength(value string) (float64, error) {
+	value = strings.ToLower(value)
+	scale := 1.0
+	for _, unit := range marginUnits {
+		if number, found := strings.CutSuffix(value, unit.suffix); found {
+			value, scale = number, unit.inches
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("无效的长度: %s", value)
+	}
+	return n * scale, nil
+}
+
+// printToPDF 返回把当前页面打印为PDF的任务，打印结果写入buf
+func printToPDF(opts PDFOptions, buf *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		size, err := paperSize(opts.Paper)
+		if err != nil {
+			return err
+		}
+		margins, err := ParseMargins(opts.Margin)
+		if err != nil {
+			return err
+		}
+
+		params := page.PrintToPDF().
+			WithPaperWidth(size[0]).
+			WithPaperHeight(size[1]).
+			WithLandscape(opts.Landscape).
+			WithPrintBackground(opts.PrintBackground).
+			WithMarginTop(margins[0]).
+			WithMarginRight(margins[1]).
+			WithMarginBottom(margins[2]).
+			WithMarginLeft(margins[3]).
+			WithGenerateTaggedPDF(true)
+
+		// 只设置了页眉或页脚时，另一个使用空模板，避免Chrome显示默认的日期和标题
+		if opts.HeaderTemplate != "" || opts.FooterTemplate != "" {
+			header, footer := opts.HeaderTemplate, opts.FooterTemplate
+			if header == "" {
+				header = "<span></span>"
+			}
+			if footer == "" {
+				footer = "<span></span>"
+			}
+			params = params.
+				WithDisplayHeaderFooter(true).
+				WithHeaderTemplate(header).
+				WithFooterTemplate(footer)
+		}
+
+		*buf, _, err = params.Do(ctx)
+		if err != nil {
+			return fmt.Errorf("生成PDF失败: %v", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,244 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 代理选择策略
+const (
+	ProxyRoundRobin = "round-robin" // 依次轮换
+	ProxyRandom     = "random"      // 随机选择
+	ProxySticky     = "sticky"      // 同一主机始终使用同一个代理
+	ProxyFailover   = "failover"    // 按顺序使用第一个可用的代理
+)
+
+// proxyCooldown 是代理连接失败后暂停使用的时间
+const proxyCooldown = time.Minute
+
+// Proxy 表示代理池中的一个代理
+type Proxy struct {
+	Server string      // 不含凭据的代理地址，如 socks5://10.0.0.1:1080
+	Auth   *Credential // 代理身份验证凭据，只支持HTTP和HTTPS代理
+}
+
+// String 返回不含凭据的代理地址
+func (p *Proxy) String() string {
+	return p.Server
+}
+
+// ParseProxy 解析代理地址，支持 http、https、socks4 和 socks5，未指定协议时使用 http
+func ParseProxy(value string) (*Proxy, error) {
+	value = strings.TrimSpace(value)
+	if !strings.Contains(value, "://") {
+		value = "http://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("无效的代理地址: %s (格式为 协议://[用户名:密码@]主机:端口)", RedactProxy(value))
+	}
+
+	proxy := &Proxy{}
+	switch u.Scheme {
+	case "http", "https", "socks4", "socks5":
+	default:
+		return nil, fmt.Errorf("不支持的代理协议: %s (可选 http、https、socks4、socks5)", u.Scheme)
+	}
+	if u.User != nil {
+		if strings.HasPrefix(u.Scheme, "socks") {
+			return nil, fmt.Errorf("Chrome不支持SOCKS代理身份验证，请使用不需要身份验证的SOCKS代理或HTTP代理: %s", RedactProxy(value))
+		}
+		password, _ := u.User.Password()
+		proxy.Auth = &Credential{Username: u.User.Username(), Password: password}
+		u.User = nil
+	}
+	proxy.Server = u.Scheme + "://" + u.Host
+	return proxy, nil
+}
+
+// ProxyPool 是按策略为每个目标选择代理的代理池
+type ProxyPool struct {
+	proxies  []*Proxy
+	strategy string
+
+	mu       sync.Mutex
+	next     int               // 轮换策略的下一个代理
+	sticky   map[string]int    // 固定策略下主机使用的代理
+	failedAt map[int]time.Time // 代理最近一次连接失败的时间
+	rand     *rand.Rand
+}
+
+// NewProxyPool 创建代理池，strategy 为空时依次轮换
+func NewProxyPool(values []string, strategy string) (*ProxyPool, error) {
+	switch strategy {
+	case "":
+		strategy = ProxyRoundRobin
+	case ProxyRoundRobin, ProxyRandom, ProxySticky, ProxyFailover:
+	default:
+		return nil, fmt.Errorf("无效的代理选择策略: %s (可选 %s、%s、%s、%s)",
+			strategy, ProxyRoundRobin, ProxyRandom, ProxySticky, ProxyFailover)
+	}
+
+	pool := &ProxyPool{
+		strategy: strategy,
+		sticky:   make(map[string]int),
+		failedAt: make(map[int]time.Time),
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		proxy, err := ParseProxy(value)
+		if err != nil {
+			return nil, err
+		}
+		pool.proxies = append(pool.proxies, proxy)
+	}
+	return pool, nil
+}
+
+// LoadProxyPool 根据Chrome.Proxies和代理列表文件创建代理池，同时指定了Chrome.Proxy时作为代理池的第一个代理
+// 没有配置代理列表时返回nil，此时整个浏览器使用Chrome.Proxy
+func LoadProxyPool(opts *Options) (*ProxyPool, error) {
+	if len(opts.Chrome.Proxies) == 0 && opts.Chrome.ProxyFile == "" {
+		return nil, nil
+	}
+
+	var values []string
+	if opts.Chrome.Proxy != "" {
+		values = append(values, opts.Chrome.Proxy)
+	}
+	values = append(values, opts.Chrome.Proxies...)
+
+	if opts.Chrome.ProxyFile != "" {
+		file, err := os.Open(opts.Chrome.ProxyFile)
+		if err != nil {
+			return nil, fmt.Errorf("打开代理列表文件失败: %v", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				values = append(values, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("读取代理列表文件失败: %v", err)
+		}
+	}
+
+	pool, err := NewProxyPool(values, opts.Chrome.ProxyStrategy)
+	if err != nil {
+		return nil, err
+	}
+	if len(pool.proxies) == 0 {
+		return nil, fmt.Errorf("代理列表为空")
+	}
+	return pool, nil
+}
+
+// Len 返回代理池中的代理数量
+func (p *ProxyPool) Len() int {
+	return len(p.proxies)
+}
+
+// Next 为主机选择一个代理，跳过最近连接失败的代理；所有代理都不可用时忽略失败记录
+func (p *ProxyPool) Next(host string) *Proxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	available := make([]int, 0, len(p.proxies))
+	for i := range p.proxies {
+		if failedAt, ok := p.failedAt[i]; !ok || time.Since(failedAt) > proxyCooldown {
+			available = append(available, i)
+		}
+	}
+	if len(available) == 0 {
+		for i := range p.proxies {
+			available = append(available, i)
+		}
+	}
+	usable := func(i int) bool {
+		for _, index := range available {
+			if index == i {
+				return true
+			}
+		}
+		return false
+	}
+
+	var index int
+	switch p.strategy {
+	case ProxyRandom:
+		index = available[p.rand.Intn(len(available))]
+	case ProxyFailover:
+		index = available[0]
+	case ProxySticky:
+		if i, ok := p.sticky[host]; ok && usable(i) {
+			return p.proxies[i]
+		}
+		index = available[p.rand.Intn(len(available))]
+		p.sticky[host] = index
+	default:
+		index = available[0]
+		for _, i := range available {
+			if i >= p.next {
+				index = i
+				break
+			}
+		}
+		p.next = index + 1
+	}
+	return p.proxies[index]
+}
+
+// Failed 记录代理连接失败，代理在一段时间内不再被选择
+func (p *ProxyPool) Failed(proxy *Proxy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, candidate := range p.proxies {
+		if candidate == proxy {
+			p.failedAt[i] = time.Now()
+		}
+	}
+}
+
+// Succeeded 清除代理的失败记录
+func (p *ProxyPool) Succeeded(proxy *Proxy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, candidate := range p.proxies {
+		if candidate == proxy {
+			delete(p.failedAt, i)
+		}
+	}
+}
+
+// isProxyError 检查页面加载错误是否由代理连接失败引起，这类错误换一个代理重试
+func isProxyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	for _, code := range []string{
+		"net::ERR_PROXY_",
+		"net::ERR_SOCKS_",
+		"net::ERR_TUNNEL_CONNECTION_FAILED",
+		"net::ERR_MANDATORY_PROXY_CONFIGURATION_FAILED",
+		"net::ERR_NO_SUPPORTED_PROXIES",
+	} {
+		if strings.Contains(message, code) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cyberspacesec/go-snir/pkg/models"
+)
+
+// maxRetryBackoff 是两次重试之间的最长等待时间
+const maxRetryBackoff = time.Minute
+
+// DefaultRetryOn 是默认重试的错误分类，域名解析失败、连接被拒绝和证书错误重试通常也不会成功
+var DefaultRetryOn = []string{
+	string(ErrorTimeout), string(ErrorNetwork), string(ErrorProxy),
+	string(ErrorHTTP5xx), string(ErrorBrowserCrash), string(ErrorUnknown),
+}
+
+// RetryPolicy 表示截图失败时的重试策略
+type RetryPolicy struct {
+	MaxRetries int                 // 最大重试次数
+	Backoff    time.Duration       // 第一次重试前的等待时间，之后每次加倍
+	RetryOn    map[ErrorClass]bool // 需要重试的错误分类
+}
+
+// NewRetryPolicy 根据扫描选项创建重试策略，未指定重试的错误分类时使用 DefaultRetryOn
+func NewRetryPolicy(opts *Options) (*RetryPolicy, error) {
+	if opts.Scan.MaxRetries < 0 || opts.Scan.RetryBackoff < 0 {
+		return nil, fmt.Errorf("重试次数和重试等待时间不能为负数")
+	}
+
+	retryOn := opts.Scan.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = DefaultRetryOn
+	}
+	policy := &RetryPolicy{
+		MaxRetries: opts.Scan.MaxRetries,
+		Backoff:    opts.Scan.RetryBackoff,
+		RetryOn:    make(map[ErrorClass]bool, len(retryOn)),
+	}
+	for _, name := range retryOn {
+		class := ErrorClass(strings.TrimSpace(name))
+		if !isErrorClass(class) {
+			names := make([]string, 0, len(errorClasses))
+			for _, c := range errorClasses {
+				names = append(names, string(c))
+			}
+			return nil, fmt.Errorf("无效的重试错误分类: %s (可选 %s)", name, strings.Join(names, "、"))
+		}
+		policy.RetryOn[class] = true
+	}
+	return policy, nil
+}
+
+// isErrorClass 检查错误分类是否可以在重试策略中指定
+func isErrorClass(class ErrorClass) bool {
+	for _, c := range errorClasses {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable 检查第attempt次尝试（从0开始）失败后是否重试
+func (p *RetryPolicy) retryable(class ErrorClass, attempt int) bool {
+	return class != ErrorNone && attempt < p.MaxRetries && p.RetryOn[class]
+}
+
+// delay 返回第attempt次重试（从1开始）前的等待时间
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
+
+// Witness 对目标截图，按重试策略重试失败的截图，每次尝试前等待速率限制
+// 无论成功与否都返回一个最终结果，失败时结果中记录错误分类，返回的错误为 *TargetError
+// 最后一次尝试返回5xx状态码时同样视为失败
+func (run *Runner) Witness(t Target) (*models.Result, error) {
+	var result *models.Result
+	var err error
+	class := ErrorNone
+	attempts := 0
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			delay := run.retry.delay(attempt)
+			run.log.Info(fmt.Sprintf("第 %d 次重试扫描", attempt), "url", t.URL, "error_class", class, "delay", delay)
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-run.ctx.Done():
+				timer.Stop()
+				err = run.ctx.Err()
+			}
+			if run.ctx.Err() != nil {
+				break
+			}
+		}
+
+		// 等待速率限制和同一主机的并发限制
+		release, aerr := run.scheduler.Acquire(run.ctx, t.URL)
+		if aerr != nil {
+			err = aerr
+			break
+		}
+		result, err = run.Driver.Witness(t, run)
+		attempts++
+		status := 0
+		if result != nil {
+			status = result.ResponseCode
+		}
+		release(status)
+
+		class = ClassifyError(err, status)
+		if !run.retry.retryable(class, attempt) {
+			break
+		}
+	}
+
+	if result == nil {
+		result = &models.Result{
+			URL:      t.URL,
+			Device:   t.Device.name(),
+			ProbedAt: time.Now(),
+		}
+	}
+	if err == nil && class == ErrorHTTP5xx {
+		// 页面已截图，但目标在重试后仍返回5xx，与其他失败一样记录为失败
+		err = &TargetError{Class: ErrorHTTP5xx, Err: fmt.Errorf("目标返回状态码 %d", result.ResponseCode)}
+	}
+	if err != nil {
+		class = ClassifyError(err, 0)
+		result.Failed = true
+		if result.FailedReason == "" {
+			result.FailedReason = err.Error()
+		}
+		if _, ok := err.(*TargetError); !ok {
+			err = &TargetError{Class: class, Err: err}
+		}
+	}
+	result.ErrorClass = string(class)
+	result.Attempts = attempts
+	result.Source = t.Source
+	return result, err
+}
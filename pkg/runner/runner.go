@@ -8,10 +8,12 @@ import (
 	"net/url"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cyberspacesec/go-snir/pkg/islazy"
 	"github.com/cyberspacesec/go-snir/pkg/models"
+	"github.com/cyberspacesec/go-snir/pkg/storage"
 )
 
 // Runner is a runner that probes web targets using a driver
@@ -26,7 +28,7 @@ type Runner struct {
 	log *slog.Logger
 
 	// Targets to scan.
-	Targets chan string
+	Targets chan Target
 
 	// in case we need to bail
 	ctx    context.Context
@@ -34,10 +36,20 @@ type Runner struct {
 
 	// Results channel
 	Results chan *models.Result
+	// closed once every result sent to Results has been written
+	writeDone chan struct{}
 
 	// Blacklist for URL filtering
 	blacklist *URLBlacklist
 
+	// devices to emulate for every target, empty for the default window
+	devices []*Device
+
+	// scheduler limits request rate and per-host concurrency and backs off on 429/503
+	scheduler *Scheduler
+	// retry decides which failed targets are loaded again
+	retry *RetryPolicy
+
 	// Done flag and timestamp
 	done   bool
 	doneAt time.Time
@@ -54,13 +66,15 @@ type Writer interface {
 // NewRunner gets a new Runner ready for probing.
 // It's up to the caller to call Close() on the runner
 func NewRunner(logger *slog.Logger, driver Driver, opts Options, writers []Writer) (*Runner, error) {
-	if !opts.Scan.ScreenshotSkipSave {
+	if !opts.Scan.ScreenshotSkipSave && opts.Scan.Storage == "" {
 		screenshotPath, err := islazy.CreateDir(opts.Scan.ScreenshotPath)
 		if err != nil {
 			return nil, err
 		}
 		opts.Scan.ScreenshotPath = screenshotPath
 		logger.Debug("最终截图路径", "screenshot-path", opts.Scan.ScreenshotPath)
+	} else if opts.Scan.Storage != "" {
+		logger.Debug("截图存储", "storage", storage.RedactURI(opts.Scan.Storage))
 	} else {
 		logger.Debug("不保存截图到磁盘")
 	}
@@ -70,6 +84,11 @@ func NewRunner(logger *slog.Logger, driver Driver, opts Options, writers []Write
 		return nil, errors.New("无效的截图格式")
 	}
 
+	// 检查采集模式和PDF选项
+	if err := ValidateCapture(&opts); err != nil {
+		return nil, err
+	}
+
 	// 包含JavaScript代码的文件
 	// 读取文件内容并设置到Scan.JavaScript
 	if opts.Scan.JavaScriptFile != "" {
@@ -81,6 +100,29 @@ func NewRunner(logger *slog.Logger, driver Driver, opts Options, writers []Write
 		opts.Scan.JavaScript = string(javascript)
 	}
 
+	// 检查多视口截图的视口大小
+	if _, err := ParseViewports(opts.Scan.Viewports); err != nil {
+		return nil, err
+	}
+
+	// 加载设备模拟配置
+	devices, err := LoadDevices(opts.Scan.Devices, opts.Scan.DeviceFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// 创建速率限制调度器
+	scheduler, err := NewScheduler(&opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 创建重试策略
+	retry, err := NewRetryPolicy(&opts)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize blacklist
 	blacklist, err := NewURLBlacklist(&opts)
 	if err != nil {
@@ -93,12 +135,15 @@ func NewRunner(logger *slog.Logger, driver Driver, opts Options, writers []Write
 		Driver:    driver,
 		options:   opts,
 		writers:   writers,
-		Targets:   make(chan string, 1000),
+		Targets:   make(chan Target, 1000),
 		log:       logger,
 		ctx:       ctx,
 		cancel:    cancel,
 		Results:   make(chan *models.Result, 1000),
 		blacklist: blacklist,
+		devices:   devices,
+		scheduler: scheduler,
+		retry:     retry,
 	}, nil
 }
 
@@ -119,10 +164,24 @@ func (run *Runner) checkUrl(target string) error {
 	return err
 }
 
+// DeviceTargets 返回目标在每个设备配置下的截图目标，未配置设备时只返回目标本身
+func (run *Runner) DeviceTargets(target Target) []Target {
+	if len(run.devices) == 0 {
+		return []Target{target}
+	}
+	targets := make([]Target, 0, len(run.devices))
+	for _, device := range run.devices {
+		t := target
+		t.Device = device
+		targets = append(targets, t)
+	}
+	return targets
+}
+
 // Run starts the runner, processing targets from the Targets channel
 // Screenshot 执行单次截图操作
 func Screenshot(target string) (*models.Result, error) {
-	return defaultRunner.Driver.Witness(target, defaultRunner)
+	return defaultRunner.Driver.Witness(Target{URL: target}, defaultRunner)
 }
 
 func (run *Runner) Run() error {
@@ -131,6 +190,7 @@ func (run *Runner) Run() error {
 	}
 
 	var wg sync.WaitGroup
+	var targetCount int64
 
 	// 创建工作线程池
 	for i := 0; i < run.options.Scan.Threads; i++ {
@@ -144,37 +204,60 @@ func (run *Runner) Run() error {
 					if !ok {
 						return
 					}
+					atomic.AddInt64(&targetCount, 1)
 
 					// 检查URL是否在黑名单中
-					if isBlacklisted, reason := run.blacklist.IsBlacklisted(target); isBlacklisted {
-						run.log.Warn("跳过黑名单URL", "url", target, "reason", reason)
+					if isBlacklisted, reason := run.blacklist.IsBlacklisted(target.URL); isBlacklisted {
+						run.log.Warn("跳过黑名单URL", "url", target.URL, "reason", reason)
 
 						// 创建失败结果
 						result := &models.Result{
-							URL:          target,
+							URL:          target.URL,
+							Source:       target.Source,
 							ProbedAt:     time.Now(),
 							Failed:       true,
 							FailedReason: fmt.Sprintf("URL在黑名单中: %s", reason),
+							ErrorClass:   string(ErrorBlacklisted),
 						}
 
-						// 发送到结果通道
-						run.Results <- result
+						// 与截图结果一样同步写入，保证每个目标都有一个最终结果
+						if err := run.runWriters(result); err != nil {
+							run.log.Error("写入结果失败", "url", target.URL, "error", err)
+						}
 						continue
 					}
 
-					if err := run.checkUrl(target); err != nil {
-						run.log.Error("无效的URL", "url", target, "error", err)
+					if err := run.checkUrl(target.URL); err != nil {
+						run.log.Error("无效的URL", "url", target.URL, "error", err)
+						result := &models.Result{
+							URL:          target.URL,
+							Source:       target.Source,
+							ProbedAt:     time.Now(),
+							Failed:       true,
+							FailedReason: fmt.Sprintf("无效的URL: %v", err),
+							ErrorClass:   string(ErrorInvalidURL),
+						}
+						if err := run.runWriters(result); err != nil {
+							run.log.Error("写入结果失败", "url", target.URL, "error", err)
+						}
 						continue
 					}
 
-					result, err := run.Driver.Witness(target, run)
-					if err != nil {
-						run.log.Error("截图失败", "url", target, "error", err)
-						continue
-					}
+					// 配置了多个设备时，每个设备截图一次，便于比较响应式布局
+					// 每个截图目标都写入一个最终结果，失败的结果中记录错误分类
+					for _, t := range run.DeviceTargets(target) {
+						result, err := run.Witness(t)
+						if run.ctx.Err() != nil {
+							return
+						}
+						if err != nil {
+							run.log.Error("截图失败", "url", target.URL, "device", t.Device.name(),
+								"error_class", result.ErrorClass, "attempts", result.Attempts, "error", err)
+						}
 
-					if err := run.runWriters(result); err != nil {
-						run.log.Error("写入结果失败", "url", target, "error", err)
+						if err := run.runWriters(result); err != nil {
+							run.log.Error("写入结果失败", "url", target.URL, "error", err)
+						}
 					}
 				case <-run.ctx.Done():
 					return
@@ -183,14 +266,28 @@ func (run *Runner) Run() error {
 		}()
 	}
 
+	run.writeDone = make(chan struct{})
 	go run.write()
 
 	wg.Wait()
+	run.SetTargetCount(int(atomic.LoadInt64(&targetCount)))
 	return nil
 }
 
+// SetTargetCount 将目标数量通知给需要记录目标数量的写入器
+func (run *Runner) SetTargetCount(count int) {
+	for _, writer := range run.writers {
+		if counter, ok := writer.(TargetCounter); ok {
+			if err := counter.SetTargetCount(count); err != nil {
+				run.log.Error("记录目标数量失败", "error", err)
+			}
+		}
+	}
+}
+
 // write writes results to the configured writers
 func (r *Runner) write() {
+	defer close(r.writeDone)
 	for result := range r.Results {
 		if result == nil {
 			continue
@@ -212,6 +309,12 @@ func (r *Runner) write() {
 func (run *Runner) Close() error {
 	run.cancel()
 
+	// 关闭结果通道，等待通道中剩余的结果写入后再关闭写入器
+	close(run.Results)
+	if run.writeDone != nil {
+		<-run.writeDone
+	}
+
 	// 关闭所有写入器
 	for _, writer := range run.writers {
 		if err := writer.Close(); err != nil {
@@ -219,9 +322,6 @@ func (run *Runner) Close() error {
 		}
 	}
 
-	// 关闭结果通道
-	close(run.Results)
-
 	run.done = true
 	run.doneAt = time.Now()
 
@@ -0,0 +1,203 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/cyberspacesec/go-snir/pkg/log"
+)
+
+// 限速的分组方式
+const (
+	RateLimitByHost   = "host"   // 按主机名限速
+	RateLimitByDomain = "domain" // 按注册域名限速，如 a.example.com 和 b.example.com 共用限额
+)
+
+// 目标返回429或503时的退避时间
+const (
+	minBackoff = 5 * time.Second
+	maxBackoff = 2 * time.Minute
+)
+
+// Scheduler 控制发往目标的请求速率和并发数，保护同一主机不被大量请求压垮
+type Scheduler struct {
+	interval        time.Duration // 全局请求间隔，为0时不限制
+	hostInterval    time.Duration // 同一主机的请求间隔，为0时不限制
+	hostConcurrency int           // 同一主机的最大并发数，为0时不限制
+	jitter          time.Duration // 每次请求前额外等待的随机时间上限
+	by              string
+
+	mu    sync.Mutex
+	next  time.Time // 全局下一个可用的请求时间
+	hosts map[string]*hostSchedule
+	rand  *rand.Rand
+}
+
+// hostSchedule 记录一个主机或注册域名的请求状态
+type hostSchedule struct {
+	slots        chan struct{} // 并发槽，为nil时不限制并发
+	refs         int           // 正在等待或执行的请求数
+	next         time.Time     // 下一个可用的请求时间
+	backoff      time.Duration // 当前退避时间
+	backoffUntil time.Time     // 退避结束时间
+}
+
+// NewScheduler 根据扫描选项创建调度器
+// 没有配置速率、并发和随机延迟限制时只按429和503响应退避
+func NewScheduler(opts *Options) (*Scheduler, error) {
+	scan := opts.Scan
+	if scan.RateLimit < 0 || scan.HostRateLimit < 0 || scan.HostConcurrency < 0 || scan.Jitter < 0 {
+		return nil, fmt.Errorf("速率限制、并发数和随机延迟不能为负数")
+	}
+	by := scan.RateLimitBy
+	switch by {
+	case "":
+		by = RateLimitByHost
+	case RateLimitByHost, RateLimitByDomain:
+	default:
+		return nil, fmt.Errorf("无效的限速分组方式: %s (可选 %s、%s)", by, RateLimitByHost, RateLimitByDomain)
+	}
+	s := &Scheduler{
+		hostConcurrency: scan.HostConcurrency,
+		jitter:          scan.Jitter,
+		by:              by,
+		hosts:           make(map[string]*hostSchedule),
+		rand:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if scan.RateLimit > 0 {
+		s.interval = time.Duration(float64(time.Second) / scan.RateLimit)
+	}
+	if scan.HostRateLimit > 0 {
+		s.hostInterval = time.Duration(float64(time.Second) / scan.HostRateLimit)
+	}
+	return s, nil
+}
+
+// key 返回目标URL的限速分组
+func (s *Scheduler) key(target string) string {
+	host := target
+	if u, err := url.Parse(target); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	host = strings.ToLower(host)
+	if s.by == RateLimitByDomain {
+		// IP地址和内网主机名没有注册域名，按主机名限速
+		if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+			return domain
+		}
+	}
+	return host
+}
+
+// Acquire 等待目标可以发送请求，返回请求完成后调用的函数，参数为目标的HTTP状态码
+// 调度器为nil时立即返回
+func (s *Scheduler) Acquire(ctx context.Context, target string) (func(status int), error) {
+	if s == nil {
+		return func(int) {}, nil
+	}
+
+	key := s.key(target)
+	s.mu.Lock()
+	host, ok := s.hosts[key]
+	if !ok {
+		host = &hostSchedule{}
+		if s.hostConcurrency > 0 {
+			host.slots = make(chan struct{}, s.hostConcurrency)
+		}
+		s.hosts[key] = host
+	}
+	host.refs++
+	s.mu.Unlock()
+
+	// 先占用并发槽，再预约请求时间，避免等待并发槽时占用速率限额
+	if host.slots != nil {
+		select {
+		case host.slots <- struct{}{}:
+		case <-ctx.Done():
+			s.release(key, host, false)
+			return nil, ctx.Err()
+		}
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	start := now
+	for _, t := range []time.Time{s.next, host.next, host.backoffUntil} {
+		if t.After(start) {
+			start = t
+		}
+	}
+	if s.interval > 0 {
+		s.next = start.Add(s.interval)
+	}
+	if s.hostInterval > 0 {
+		host.next = start.Add(s.hostInterval)
+	}
+	if s.jitter > 0 {
+		start = start.Add(time.Duration(s.rand.Int63n(int64(s.jitter))))
+	}
+	s.mu.Unlock()
+
+	if wait := start.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			s.release(key, host, true)
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	return func(status int) {
+		once.Do(func() {
+			s.backoff(key, host, status)
+			s.release(key, host, true)
+		})
+	}, nil
+}
+
+// backoff 根据状态码调整退避时间，429和503时退避时间加倍，其他成功响应时清除退避
+func (s *Scheduler) backoff(key string, host *hostSchedule, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable:
+		host.backoff *= 2
+		if host.backoff < minBackoff {
+			host.backoff = minBackoff
+		}
+		if host.backoff > maxBackoff {
+			host.backoff = maxBackoff
+		}
+		host.backoffUntil = time.Now().Add(host.backoff)
+		log.Warn("目标请求过多，暂停发送请求", "host", key, "status", status, "backoff", host.backoff)
+	case status > 0 && status < 400:
+		host.backoff = 0
+	}
+}
+
+// release 释放并发槽，主机没有等待中的请求且不需要等待时删除主机状态
+func (s *Scheduler) release(key string, host *hostSchedule, acquired bool) {
+	if acquired && host.slots != nil {
+		<-host.slots
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	host.refs--
+	now := time.Now()
+	if host.refs == 0 && host.backoff == 0 && !host.next.After(now) && !host.backoffUntil.After(now) {
+		delete(s.hosts, key)
+	}
+}
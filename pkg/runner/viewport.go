@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// Viewport 表示多视口截图中的一个视口大小
+type Viewport struct {
+	Width  int64
+	Height int64
+}
+
+// String 返回 宽x高 格式的视口大小
+func (v Viewport) String() string {
+	return fmt.Sprintf("%dx%d", v.Width, v.Height)
+}
+
+// ParseViewports 解析 宽x高 格式的视口列表，如 1920x1080
+func ParseViewports(values []string) ([]Viewport, error) {
+	viewports := make([]Viewport, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		width, height, ok := strings.Cut(strings.ToLower(value), "x")
+		w, errW := strconv.ParseInt(strings.TrimSpace(width), 10, 64)
+		h, errH := strconv.ParseInt(strings.TrimSpace(height), 10, 64)
+		if !ok || errW != nil || errH != nil || w <= 0 || h <= 0 {
+			return nil, fmt.Errorf("无效的视口大小: %s (格式为 宽x高，如 1920x1080)", value)
+		}
+		viewports = append(viewports, Viewport{Width: w, Height: h})
+	}
+	return viewports, nil
+}
+
+// viewportSettleScript 等待两帧，确保调整视口后页面已重新布局和绘制
+const viewportSettleScript = `new Promise(resolve => requestAnimationFrame(() => requestAnimationFrame(resolve)))`
+
+// captureViewports 返回依次调整视口大小并截图的任务，截图结果按顺序写入bufs
+// 截图完成后恢复原来的视口，设备模拟时保留设备的像素比和移动端设置
+func captureViewports(viewports []Viewport, device *Device, fullPage bool, bufs [][]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		scale, mobile := 1.0, false
+		if device != nil {
+			scale, mobile = device.DeviceScaleFactor, device.Mobile
+		}
+
+		for i, viewport := range viewports {
+			err := emulation.SetDeviceMetricsOverride(viewport.Width, viewport.Height, scale, mobile).Do(ctx)
+			if err != nil {
+				return fmt.Errorf("设置视口 %s 失败: %v", viewport, err)
+			}
+			err = chromedp.Evaluate(viewportSettleScript, nil, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+				return p.WithAwaitPromise(true)
+			}).Do(ctx)
+			if err != nil {
+				return fmt.Errorf("等待视口 %s 重新布局失败: %v", viewport, err)
+			}
+
+			capture := chromedp.CaptureScreenshot(&bufs[i])
+			if fullPage {
+				capture = chromedp.FullScreenshot(&bufs[i], 100)
+			}
+			if err := capture.Do(ctx); err != nil {
+				return fmt.Errorf("视口 %s 截图失败: %v", viewport, err)
+			}
+		}
+
+		if device != nil {
+			return setDeviceMetrics(ctx, device)
+		}
+		return emulation.ClearDeviceMetricsOverride().Do(ctx)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/user"
 	"path/filepath"
 	"time"
 
@@ -166,7 +167,7 @@ func (w *StdoutWriter) Write(result *models.Result) error {
 	}
 
 	if result.Failed {
-		log.Error("扫描失败", "reason", result.FailedReason)
+		log.Error("扫描失败", "reason", result.FailedReason, "error_class", result.ErrorClass)
 	}
 
 	return nil
@@ -177,15 +178,63 @@ func (w *StdoutWriter) Close() error {
 	return nil
 }
 
+// TargetCounter is implemented by writers that record how many targets a scan had
+type TargetCounter interface {
+	SetTargetCount(count int) error
+}
+
+// SessionOptions 将扫描相关的选项序列化为JSON，用于记录到扫描会话
+func SessionOptions(opts *Options) string {
+	// 代理地址和身份验证凭据中的密码不写入会话记录
+	redacted := redactCredentials(opts)
+	data, err := json.Marshal(struct {
+		Chrome interface{}
+		Scan   interface{}
+	}{
+		Chrome: redacted.Chrome,
+		Scan:   redacted.Scan,
+	})
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// CurrentOperator 返回当前系统用户名，作为默认的扫描操作者
+func CurrentOperator() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// DatabaseOptions 根据扫描选项生成数据库连接选项
+func DatabaseOptions(opts *Options) database.Options {
+	return database.Options{
+		Path:            opts.DB.Path,
+		URI:             opts.Writer.DbURI,
+		MaxOpenConns:    opts.DB.MaxOpenConns,
+		MaxIdleConns:    opts.DB.MaxIdleConns,
+		ConnMaxLifetime: opts.DB.ConnMaxLifetime,
+		Debug:           opts.Writer.DbDebug,
+	}
+}
+
+// databaseLocation 返回用于日志输出的数据库位置，隐藏连接URI中的密码
+func databaseLocation(options database.Options) string {
+	if options.URI != "" {
+		return database.RedactURI(options.URI)
+	}
+	return options.Path
+}
+
 // CreateWriters creates writers based on options
 func CreateWriters(opts *Options) ([]Writer, error) {
 	var writers []Writer
 
 	// 创建数据库写入器
 	if opts.DB.Enable {
-		dbOptions := database.Options{
-			Path: opts.DB.Path,
-		}
+		dbOptions := DatabaseOptions(opts)
 
 		// 创建数据库连接
 		db, err := database.NewDB(dbOptions)
@@ -193,10 +242,25 @@ func CreateWriters(opts *Options) ([]Writer, error) {
 			return nil, fmt.Errorf("创建数据库连接失败: %v", err)
 		}
 
-		// 创建数据库写入器
+		// 创建数据库写入器，并为本次扫描打开一个会话
 		dbWriter := database.NewDBWriter(db)
+		session := &database.ScanSession{
+			Name:        opts.DB.SessionName,
+			CommandLine: RedactCommandLine(os.Args),
+			Options:     SessionOptions(opts),
+			Operator:    opts.DB.Operator,
+		}
+		if session.Name == "" {
+			session.Name = "scan " + time.Now().Format("2006-01-02 15:04:05")
+		}
+		if session.Operator == "" {
+			session.Operator = CurrentOperator()
+		}
+		if err := dbWriter.StartSession(session); err != nil {
+			return nil, err
+		}
 		writers = append(writers, dbWriter)
-		log.Debug("已创建数据库写入器", "path", dbOptions.Path)
+		log.Debug("已创建数据库写入器", "database", databaseLocation(dbOptions), "session", session.ID)
 	}
 
 	// 创建JSONL写入器
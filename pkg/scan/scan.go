@@ -1,14 +1,17 @@
 package scan
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net"
 	"net/url"
 	"strings"
-	"time"
 
 	"github.com/cyberspacesec/go-snir/pkg/log"
 	"github.com/cyberspacesec/go-snir/pkg/models"
 	"github.com/cyberspacesec/go-snir/pkg/runner"
+	"github.com/cyberspacesec/go-snir/pkg/targets"
 )
 
 // Config 表示扫描配置
@@ -66,20 +69,10 @@ func createWriters(options *runner.Options) ([]runner.Writer, error) {
 	return runner.CreateWriters(options)
 }
 
-// ScanSingle 扫描单个URL
-func (s *Scanner) ScanSingle(target string) (*models.Result, error) {
+// ScanSingle 扫描单个URL，配置了多个设备时每个设备返回一个结果
+func (s *Scanner) ScanSingle(target string) ([]*models.Result, error) {
 	// 确保URL格式正确
-	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
-		// 根据配置添加协议前缀
-		if s.Config.Options.Scan.HTTPS {
-			target = "https://" + target
-		} else if s.Config.Options.Scan.HTTP {
-			target = "http://" + target
-		} else {
-			// 默认使用HTTPS
-			target = "https://" + target
-		}
-	}
+	target = s.normalize(target)
 
 	// 验证URL格式
 	_, err := url.Parse(target)
@@ -98,47 +91,116 @@ func (s *Scanner) ScanSingle(target string) (*models.Result, error) {
 		s.Runner = runner
 	}
 
-	// 尝试执行扫描，最多重试指定次数
-	var result *models.Result
-	var lastErr error
-	maxRetries := s.Config.Options.Scan.MaxRetries
+	s.Runner.SetTargetCount(1)
 
-	// 至少尝试一次
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		if attempt > 0 {
-			log.Info(fmt.Sprintf("第 %d 次重试扫描", attempt), "url", target)
-			// 重试前等待一小段时间
-			time.Sleep(time.Duration(2*attempt) * time.Second)
-		}
+	// 失败的截图也写入结果，结果中记录错误分类
+	var results []*models.Result
+	var scanErr error
+	for _, t := range s.Runner.DeviceTargets(runner.Target{URL: target}) {
+		result, err := s.Runner.Witness(t)
 
-		// 执行扫描
-		result, lastErr = s.Driver.Witness(target, s.Runner)
-
-		// 如果成功或者是特定类型的错误不应重试，则跳出循环
-		if lastErr == nil ||
-			(strings.Contains(lastErr.Error(), "net::ERR_NAME_NOT_RESOLVED") ||
-				strings.Contains(lastErr.Error(), "net::ERR_CONNECTION_REFUSED")) {
-			break
+		// 运行写入器
+		for _, writer := range s.Writers {
+			if err := writer.Write(result); err != nil {
+				log.Error("写入结果失败", "error", err)
+			}
+		}
+		results = append(results, result)
+		if err != nil && scanErr == nil {
+			scanErr = fmt.Errorf("扫描失败 (%s): %w", result.ErrorClass, err)
 		}
 	}
 
-	// 如果所有尝试都失败，返回最后一个错误
-	if lastErr != nil {
-		return nil, fmt.Errorf("扫描失败: %v", lastErr)
-	}
+	return results, scanErr
+}
 
-	// 运行写入器
-	for _, writer := range s.Writers {
-		if err := writer.Write(result); err != nil {
-			log.Error("写入结果失败", "error", err)
+// ScanMulti 扫描多个URL
+func (s *Scanner) ScanMulti(targets []string) error {
+	return s.scanStream(func(send func(runner.Target) bool) error {
+		for _, target := range targets {
+			if !send(runner.Target{URL: target}) {
+				break
+			}
 		}
+		return nil
+	})
+}
+
+// ScanReader 从r中流式读取目标并扫描，每行可以包含多个URL、主机名或CIDR网段
+// 目标通道满时暂停读取，不会把全部目标读入内存；返回读取的目标数量
+func (s *Scanner) ScanReader(r io.Reader) (int, error) {
+	var count int
+	err := s.scanStream(func(send func(runner.Target) bool) error {
+		var err error
+		count, err = targets.Read(r, func(target string) bool {
+			return send(runner.Target{URL: target})
+		})
+		return err
+	})
+	return count, err
+}
+
+// ScanImport 从r中读取端口扫描结果（nmap、masscan、naabu或 主机:端口 列表），
+// 把Web服务和 Scan.Ports 中的端口转换为URL后扫描；返回导入的URL数量
+func (s *Scanner) ScanImport(r io.Reader, format string) (int, error) {
+	var count int
+	err := s.scanStream(func(send func(runner.Target) bool) error {
+		var err error
+		count, err = targets.Import(r, format, s.Config.Options.Scan.Ports, func(url string) bool {
+			return send(runner.Target{URL: url})
+		})
+		return err
+	})
+	return count, err
+}
+
+// ScanRequests 从r中读取代理记录（Burp、ZAP或HAR），按规范化的URL去重后扫描
+// 请求中的请求头和Cookie只发送给请求所在的主机；返回导入的请求数量
+func (s *Scanner) ScanRequests(r io.Reader, format string) (int, error) {
+	var count int
+	err := s.scanStream(func(send func(runner.Target) bool) error {
+		var err error
+		count, err = targets.ImportRequests(r, format, s.Config.Options.Scan.GetOnly, func(req targets.Request) bool {
+			return send(requestTarget(req))
+		})
+		return err
+	})
+	return count, err
+}
+
+// requestTarget 把导入的请求转换为截图目标，请求头限定为只发送给请求所在的主机
+func requestTarget(req targets.Request) runner.Target {
+	t := runner.Target{URL: req.URL}
+	host := ""
+	if u, err := url.Parse(req.URL); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+	for _, header := range req.Headers {
+		t.Headers = append(t.Headers, runner.HeaderRule{Host: host, Name: header.Name, Value: header.Value})
 	}
+	for _, cookie := range req.Cookies {
+		t.Cookies = append(t.Cookies, runner.CustomCookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	return t
+}
 
-	return result, nil
+// ScanExpansion 用系统解析器解析扩展器生成的候选主机，能解析的主机边解析边扫描
+// 结果中记录产生主机的扩展来源；返回能解析的主机数量
+func (s *Scanner) ScanExpansion(e *targets.Expander) (int, error) {
+	var count int
+	err := s.scanStream(func(send func(runner.Target) bool) error {
+		var err error
+		count, err = e.Resolve(context.Background(), func(c targets.Candidate) bool {
+			log.Debug("发现主机", "host", c.Host, "source", c.Source)
+			return send(runner.Target{URL: c.Host, Source: c.Source})
+		})
+		return err
+	})
+	return count, err
 }
 
-// ScanMulti 扫描多个URL
-func (s *Scanner) ScanMulti(targets []string) error {
+// scanStream 在后台把feed生成的目标发送到Runner，同时执行扫描
+func (s *Scanner) scanStream(feed func(send func(runner.Target) bool) error) error {
 	// 创建Runner（如果尚未创建）
 	if s.Runner == nil {
 		runner, err := runner.NewRunner(log.GetLogger(), s.Driver, *s.Config.Options, s.Writers)
@@ -148,28 +210,48 @@ func (s *Scanner) ScanMulti(targets []string) error {
 		s.Runner = runner
 	}
 
-	// 启动扫描
+	// 启动扫描，Targets通道满时发送会阻塞，从而暂停读取目标
+	done := make(chan struct{})
+	feedErr := make(chan error, 1)
 	go func() {
-		for _, target := range targets {
-			// 确保URL格式正确
-			if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
-				// 根据配置添加协议前缀
-				if s.Config.Options.Scan.HTTPS {
-					target = "https://" + target
-				} else if s.Config.Options.Scan.HTTP {
-					target = "http://" + target
-				} else {
-					// 默认使用HTTPS
-					target = "https://" + target
-				}
+		defer close(s.Runner.Targets)
+		feedErr <- feed(func(target runner.Target) bool {
+			target.URL = s.normalize(target.URL)
+			select {
+			case s.Runner.Targets <- target:
+				return true
+			case <-done:
+				return false
 			}
-			s.Runner.Targets <- target
-		}
-		close(s.Runner.Targets)
+		})
 	}()
 
 	// 执行扫描
-	return s.Runner.Run()
+	err := s.Runner.Run()
+	close(done)
+	if ferr := <-feedErr; ferr != nil && err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// normalize 确保URL格式正确，没有协议时根据配置添加协议前缀
+func (s *Scanner) normalize(target string) string {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return target
+	}
+
+	// IPv6地址需要加上方括号
+	if ip := net.ParseIP(target); ip != nil && ip.To4() == nil {
+		target = "[" + target + "]"
+	}
+	if s.Config.Options.Scan.HTTPS {
+		return "https://" + target
+	} else if s.Config.Options.Scan.HTTP {
+		return "http://" + target
+	}
+	// 默认使用HTTPS
+	return "https://" + target
 }
 
 // Close 关闭扫描器
@@ -0,0 +1,161 @@
+// Package storage 提供截图文件的存储后端，并按内容哈希保存截图，相同内容的截图只保存一份
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// hashPattern 匹配SHA-256十六进制哈希
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Blob 表示一个按内容寻址保存的文件
+type Blob struct {
+	Hash         string `json:"hash"`         // 内容的SHA-256哈希
+	Key          string `json:"key"`          // 存储中的key，如 ab/cd/abcd....png
+	Location     string `json:"location"`     // 保存在扫描结果中的位置，本地存储为文件路径
+	Size         int64  `json:"size"`         // 文件大小
+	Deduplicated bool   `json:"deduplicated"` // 是否已存在相同内容的文件
+}
+
+// BlobStore 把文件按SHA-256哈希保存在存储后端的分片目录中，key为 <哈希前2位>/<哈希3-4位>/<哈希>.<扩展名>
+type BlobStore struct {
+	backend Storage
+}
+
+// NewBlobStore 创建使用backend保存文件的存储
+func NewBlobStore(backend Storage) *BlobStore {
+	return &BlobStore{backend: backend}
+}
+
+// OpenBlobStore 根据URI打开存储后端，uri为空时使用本地目录root，见 Open
+func OpenBlobStore(uri, root string) (*BlobStore, error) {
+	backend, err := Open(uri, root)
+	if err != nil {
+		return nil, err
+	}
+	return NewBlobStore(backend), nil
+}
+
+// Backend 返回存储后端
+func (s *BlobStore) Backend() Storage {
+	return s.backend
+}
+
+// IsLocal 判断是否使用本地目录存储
+func (s *BlobStore) IsLocal() bool {
+	_, ok := s.backend.(*Local)
+	return ok
+}
+
+// Hash 计算内容的SHA-256哈希
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// IsHash 判断字符串是否为SHA-256十六进制哈希
+func IsHash(value string) bool {
+	return hashPattern.MatchString(value)
+}
+
+// BlobKey 返回哈希对应的分片路径，ext为带点的扩展名
+func BlobKey(hash, ext string) string {
+	return fmt.Sprintf("%s/%s/%s%s", hash[0:2], hash[2:4], hash, strings.ToLower(ext))
+}
+
+// Put 保存内容并返回对应的Blob，已存在相同内容的文件时不再重复写入
+func (s *BlobStore) Put(ctx context.Context, data []byte, ext string) (*Blob, error) {
+	hash := Hash(data)
+	key := BlobKey(hash, ext)
+	blob := &Blob{
+		Hash:     hash,
+		Key:      key,
+		Location: s.backend.Location(key),
+		Size:     int64(len(data)),
+	}
+
+	existing, err := s.backend.List(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range existing {
+		if object.Key == key && object.Size == blob.Size {
+			blob.Deduplicated = true
+			return blob, nil
+		}
+	}
+
+	if err := s.backend.Put(ctx, key, data, ContentType(key)); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// Get 读取key对应的文件
+func (s *BlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.backend.Get(ctx, key)
+}
+
+// Location 返回key对应的位置
+func (s *BlobStore) Location(key string) string {
+	return s.backend.Location(key)
+}
+
+// Key 返回扫描结果中保存的位置对应的key，按哈希命名的文件直接由文件名得到分片路径
+func (s *BlobStore) Key(location string) string {
+	if location == "" {
+		return ""
+	}
+	base := path.Base(strings.ReplaceAll(location, "\\", "/"))
+	ext := path.Ext(base)
+	if hash := strings.TrimSuffix(base, ext); IsHash(hash) {
+		return BlobKey(hash, ext)
+	}
+	return s.backend.Key(location)
+}
+
+// ResolveKey 把请求中的名称解析为key
+// 支持分片路径、<哈希>.<扩展名> 形式的文件名，以及旧版本保存在根目录下的文件名
+func (s *BlobStore) ResolveKey(name string) (string, error) {
+	clean, err := cleanKey(name)
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(clean, "/") {
+		ext := path.Ext(clean)
+		if hash := strings.TrimSuffix(clean, ext); IsHash(hash) {
+			return BlobKey(hash, ext), nil
+		}
+	}
+	return clean, nil
+}
+
+// ServeHTTP 以请求路径为文件名从存储中读取并返回文件
+func (s *BlobStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, err := s.ResolveKey(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := s.backend.Get(r.Context(), key)
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType(key))
+	w.Write(data)
+}
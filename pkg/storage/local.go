@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Local 把对象保存在本地目录中
+type Local struct {
+	root string
+}
+
+// NewLocal 创建以root为根目录的本地存储
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+// Root 返回本地存储根目录
+func (l *Local) Root() string {
+	return l.root
+}
+
+// path 返回key对应的本地文件路径
+func (l *Local) path(key string) (string, error) {
+	clean, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.root, filepath.FromSlash(clean)), nil
+}
+
+// Put 写入文件，先写入临时文件再重命名，避免并发写入同一文件时读到不完整的内容
+func (l *Local) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("保存文件失败: %v", err)
+	}
+	return nil
+}
+
+// Get 读取文件
+func (l *Local) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Delete 删除文件
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List 列出目录中以prefix开头的文件，忽略写入中的临时文件
+func (l *Local) List(ctx context.Context, prefix string) ([]Object, error) {
+	// 只遍历前缀所在的子目录
+	start := l.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir, err := l.path(prefix[:i])
+		if err != nil {
+			return nil, err
+		}
+		start = dir
+	}
+	if _, err := os.Stat(start); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var objects []Object
+	err := filepath.Walk(start, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(l.root, path)
+		if err != nil {
+			return nil
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, Object{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects, nil
+}
+
+// SignedURL 返回文件的file://链接，本地存储不需要签名
+func (l *Local) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(), nil
+}
+
+// Location 返回key对应的本地文件路径
+func (l *Local) Location(key string) string {
+	return filepath.Join(l.root, filepath.FromSlash(key))
+}
+
+// Key 返回文件路径相对于根目录的key，不在根目录下的文件返回文件名
+func (l *Local) Key(location string) string {
+	if location == "" {
+		return ""
+	}
+	if rel, err := filepath.Rel(l.root, location); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	if abs, err := filepath.Abs(location); err == nil {
+		if root, err := filepath.Abs(l.root); err == nil {
+			if rel, err := filepath.Rel(root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	return filepath.Base(location)
+}
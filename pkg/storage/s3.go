@@ -0,0 +1,457 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 签名相关常量
+const (
+	s3Algorithm      = "AWS4-HMAC-SHA256"
+	s3UnsignedBody   = "UNSIGNED-PAYLOAD"
+	s3DefaultRegion  = "us-east-1"
+	s3MaxPresignTime = 7 * 24 * time.Hour
+)
+
+// S3Config S3兼容存储的配置
+type S3Config struct {
+	Endpoint     string // 服务地址，如 http://127.0.0.1:9000，为空时使用AWS
+	Region       string // 区域
+	Bucket       string // 存储桶
+	Prefix       string // 对象key的前缀
+	AccessKey    string // 访问密钥ID
+	SecretKey    string // 访问密钥
+	SessionToken string // 临时凭证的会话令牌
+	PathStyle    bool   // 使用 endpoint/bucket/key 形式的地址，MinIO等自建服务通常需要
+}
+
+// S3 把对象保存在S3兼容的对象存储中，使用AWS Signature V4签名请求
+type S3 struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3 创建S3兼容存储
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("未指定S3存储桶")
+	}
+	if cfg.Region == "" {
+		cfg.Region = s3DefaultRegion
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("无效的S3服务地址: %s", cfg.Endpoint)
+	}
+	if cfg.Prefix != "" {
+		cfg.Prefix = strings.Trim(cfg.Prefix, "/") + "/"
+	}
+
+	return &S3{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 60 * time.Second},
+		now:      time.Now,
+	}, nil
+}
+
+// parseS3URI 解析 s3://[access:secret@]bucket/prefix?endpoint=&region=&path_style= 形式的URI
+// 未在URI中指定的凭证和区域从 AWS_ACCESS_KEY_ID、AWS_SECRET_ACCESS_KEY、AWS_SESSION_TOKEN、AWS_REGION 环境变量读取
+func parseS3URI(u *url.URL) (S3Config, error) {
+	query := u.Query()
+	cfg := S3Config{
+		Bucket:       u.Host,
+		Prefix:       strings.Trim(u.Path, "/"),
+		Endpoint:     query.Get("endpoint"),
+		Region:       query.Get("region"),
+		AccessKey:    envFirst("AWS_ACCESS_KEY_ID"),
+		SecretKey:    envFirst("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: envFirst("AWS_SESSION_TOKEN"),
+	}
+	if u.User != nil {
+		cfg.AccessKey = u.User.Username()
+		cfg.SecretKey, _ = u.User.Password()
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = envFirst("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL")
+	}
+	if cfg.Region == "" {
+		cfg.Region = envFirst("AWS_REGION", "AWS_DEFAULT_REGION")
+	}
+
+	// 自建服务默认使用路径形式的地址
+	cfg.PathStyle = cfg.Endpoint != ""
+	if value := query.Get("path_style"); value != "" {
+		pathStyle, err := strconv.ParseBool(value)
+		if err != nil {
+			return cfg, fmt.Errorf("无效的path_style参数: %s", value)
+		}
+		cfg.PathStyle = pathStyle
+	}
+
+	if cfg.Bucket == "" {
+		return cfg, fmt.Errorf("S3存储URI中缺少存储桶: %s", u.Redacted())
+	}
+	return cfg, nil
+}
+
+// objectURL 返回对象的访问地址，key为空时返回存储桶地址
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	u.RawQuery = ""
+	base := strings.TrimSuffix(u.Path, "/")
+	if s.cfg.PathStyle {
+		u.Path = base + "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = base + "/" + key
+	}
+	return &u
+}
+
+// fullKey 返回加上前缀后的对象key
+func (s *S3) fullKey(key string) (string, error) {
+	clean, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return s.cfg.Prefix + clean, nil
+}
+
+// do 签名并发送请求
+func (s *S3) do(ctx context.Context, method string, u *url.URL, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.ContentLength = int64(len(body))
+
+	sum := sha256.Sum256(body)
+	s.sign(req, hex.EncodeToString(sum[:]), s.now().UTC())
+	return s.client.Do(req)
+}
+
+// Put 上传对象
+func (s *S3) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	full, err := s.fullKey(key)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(full), data, header)
+	if err != nil {
+		return fmt.Errorf("上传对象失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return s3Error("上传对象失败", resp)
+	}
+	return nil
+}
+
+// Get 下载对象
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	full, err := s.fullKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(full), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("下载对象失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, s3Error("下载对象失败", resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Delete 删除对象
+func (s *S3) Delete(ctx context.Context, key string) error {
+	full, err := s.fullKey(key)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(full), nil, nil)
+	if err != nil {
+		return fmt.Errorf("删除对象失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		if err := s3Error("删除对象失败", resp); !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// listBucketResult 是ListObjectsV2的响应
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List 分页列出以prefix开头的对象
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		u := s.objectURL("")
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", s.cfg.Prefix+prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u.RawQuery = query.Encode()
+
+		resp, err := s.do(ctx, http.MethodGet, u, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("列出对象失败: %v", err)
+		}
+		if resp.StatusCode/100 != 2 {
+			err := s3Error("列出对象失败", resp)
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("解析对象列表失败: %v", err)
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, Object{
+				Key:     strings.TrimPrefix(content.Key, s.cfg.Prefix),
+				Size:    content.Size,
+				ModTime: content.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// SignedURL 返回预签名的下载链接，有效期最长7天
+func (s *S3) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	full, err := s.fullKey(key)
+	if err != nil {
+		return "", err
+	}
+	if expires <= 0 || expires > s3MaxPresignTime {
+		expires = s3MaxPresignTime
+	}
+	return s.presign(http.MethodGet, s.objectURL(full), expires, s.now().UTC()), nil
+}
+
+// Location 返回对象的 s3://bucket/key 地址
+func (s *S3) Location(key string) string {
+	return "s3://" + s.cfg.Bucket + "/" + s.cfg.Prefix + key
+}
+
+// Key 从 s3://bucket/key 地址中取出key
+func (s *S3) Key(location string) string {
+	return strings.TrimPrefix(location, "s3://"+s.cfg.Bucket+"/"+s.cfg.Prefix)
+}
+
+// s3Error 根据响应生成错误信息，对象不存在时返回ErrNotFound
+// 存储桶不存在等其他404响应不是ErrNotFound，避免配置错误被当作对象不存在
+func s3Error(msg string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	parsed := xml.Unmarshal(body, &e) == nil && e.Code != ""
+	if resp.StatusCode == http.StatusNotFound && (!parsed || e.Code == "NoSuchKey") {
+		return ErrNotFound
+	}
+	if parsed {
+		return fmt.Errorf("%s: %s %s: %s", msg, resp.Status, e.Code, e.Message)
+	}
+	return fmt.Errorf("%s: %s", msg, resp.Status)
+}
+
+// sign 使用AWS Signature V4为请求添加Authorization头
+func (s *S3) sign(req *http.Request, payloadHash string, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+	}
+
+	canonical, signedHeaders := canonicalRequest(req, payloadHash)
+	scope := s.scope(t)
+	signature := s.signature(t, stringToSign(amzDate, scope, canonical))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+// canonicalRequest 返回请求的规范请求和签名的请求头列表，请求的所有请求头都参与签名
+func canonicalRequest(req *http.Request, payloadHash string) (string, string) {
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	return strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n"), signedHeaders
+}
+
+// presign 生成查询参数形式签名的链接
+func (s *S3) presign(method string, u *url.URL, expires time.Duration, t time.Time) string {
+	amzDate := t.Format("20060102T150405Z")
+	scope := s.scope(t)
+
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.cfg.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if s.cfg.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		canonicalPath(u),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedBody,
+	}, "\n")
+
+	query.Set("X-Amz-Signature", s.signature(t, stringToSign(amzDate, scope, canonicalRequest)))
+	signed := *u
+	signed.RawQuery = canonicalQuery(query)
+	return signed.String()
+}
+
+// scope 返回签名的凭证范围
+func (s *S3) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+// signature 计算签名
+func (s *S3) signature(t time.Time, toSign string) string {
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+// stringToSign 返回待签名字符串
+func stringToSign(amzDate, scope, canonicalRequest string) string {
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	return strings.Join([]string{s3Algorithm, amzDate, scope, hex.EncodeToString(sum[:])}, "\n")
+}
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalPath 返回按S3规则编码的路径，除/以外的保留字符都需要编码
+func canonicalPath(u *url.URL) string {
+	path := u.Path
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery 返回按key排序并编码的查询字符串
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, uriEncode(key)+"="+uriEncode(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode 按RFC 3986编码，只保留 A-Z a-z 0-9 - _ . ~ 不编码
+func uriEncode(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound 表示对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// Object 表示存储中的一个对象
+type Object struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Storage 是截图文件的存储后端，key使用/分隔
+type Storage interface {
+	// Put 写入对象，已存在时覆盖
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get 读取对象，不存在时返回ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+	// List 列出以prefix开头的所有对象
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// SignedURL 返回在expires时间内有效的对象访问链接
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+	// Location 返回对象保存在扫描结果中的位置，本地存储为文件路径，S3为 s3://bucket/key
+	Location(key string) string
+	// Key 把扫描结果中保存的位置转换为key
+	Key(location string) string
+}
+
+// Open 根据URI打开存储后端，uri为空时使用本地目录root
+// 支持的URI:
+//
+//	file:///data/screenshots 或 /data/screenshots  本地目录
+//	s3://bucket/prefix?endpoint=http://127.0.0.1:9000&region=us-east-1&path_style=true  S3兼容存储
+func Open(uri, root string) (Storage, error) {
+	if uri == "" {
+		return NewLocal(root), nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		// 不带协议的URI（包括Windows盘符路径）作为本地目录
+		return NewLocal(uri), nil
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "file":
+		return NewLocal(u.Host + u.Path), nil
+	case "s3":
+		cfg, err := parseS3URI(u)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3(cfg)
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: %s", u.Scheme)
+	}
+}
+
+// RedactURI 隐藏URI中的访问密钥，用于日志输出
+func RedactURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.User == nil {
+		return uri
+	}
+	return u.Redacted()
+}
+
+// contentTypes 是系统MIME类型表中可能缺少的扩展名
+var contentTypes = map[string]string{
+	".mhtml": "multipart/related",
+	".har":   "application/json",
+}
+
+// ContentType 根据key的扩展名返回内容类型
+func ContentType(key string) string {
+	ext := strings.ToLower(path.Ext(key))
+	if ct, ok := contentTypes[ext]; ok {
+		return ct
+	}
+	if ct := mime.TypeByExtension(ext); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// cleanKey 规范化key并拒绝跳出存储根目录的路径
+func cleanKey(key string) (string, error) {
+	clean := strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(key, "\\", "/")), "/")
+	if clean == "" || clean == "." || strings.Contains(key, "..") {
+		return "", fmt.Errorf("无效的文件名: %s", key)
+	}
+	return clean, nil
+}
+
+// envFirst 返回第一个非空的环境变量
+func envFirst(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
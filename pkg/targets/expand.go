@@ -0,0 +1,377 @@
+package targets
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// 扩展来源，记录在结果中
+const (
+	SourceInput       = "input"       // 命令行中指定的域名
+	SourceCT          = "ct"          // 证书透明度记录
+	SourceWordlist    = "wordlist"    // 字典中的子域名
+	SourcePermutation = "permutation" // 字典与证书透明度记录中子域名的组合
+)
+
+// Candidate 表示一个待解析的主机名及其扩展来源
+type Candidate struct {
+	Host   string
+	Source string
+}
+
+// Expander 把根域名扩展为子域名，用系统解析器解析后只保留能解析的主机
+type Expander struct {
+	Threads int           // 并发解析数
+	Timeout time.Duration // 单次解析的超时时间
+	Permute bool          // 是否把字典与证书透明度记录中的子域名组合
+
+	resolver   *net.Resolver
+	domains    []string
+	words      []string
+	candidates []Candidate
+	seen       map[string]bool
+	ctLabels   map[string]bool // 证书透明度记录中子域名的第一级标签
+	permuted   bool
+}
+
+// NewExpander 创建扩展器，domains 为要扩展的根域名，它们本身也会被解析
+func NewExpander(domains []string) (*Expander, error) {
+	e := &Expander{
+		Threads:  20,
+		Timeout:  5 * time.Second,
+		resolver: net.DefaultResolver,
+		seen:     make(map[string]bool),
+		ctLabels: make(map[string]bool),
+	}
+	for _, domain := range domains {
+		host, ok := normalizeHost(domain)
+		if !ok || !strings.Contains(host, ".") || net.ParseIP(host) != nil {
+			return nil, fmt.Errorf("无效的域名: %s", domain)
+		}
+		e.domains = append(e.domains, host)
+		e.add(host, SourceInput)
+	}
+	if len(e.domains) == 0 {
+		return nil, fmt.Errorf("请指定要扩展的域名")
+	}
+	return e, nil
+}
+
+// normalizeHost 规范化主机名：小写，去掉通配符前缀、末尾的点和URL协议
+func normalizeHost(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if u, ok := strings.CutPrefix(name, "https://"); ok {
+		name = u
+	} else if u, ok := strings.CutPrefix(name, "http://"); ok {
+		name = u
+	}
+	name, _, _ = strings.Cut(name, "/")
+	name = strings.TrimPrefix(name, "*.")
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || strings.ContainsAny(name, " *:@") {
+		return "", false
+	}
+	return name, true
+}
+
+// add 添加候选主机，同一主机只保留第一个来源
+func (e *Expander) add(host, source string) {
+	if e.seen[host] {
+		return
+	}
+	e.seen[host] = true
+	e.candidates = append(e.candidates, Candidate{Host: host, Source: source})
+}
+
+// domainOf 返回主机所属的根域名，不属于任何根域名时返回空字符串
+func (e *Expander) domainOf(host string) string {
+	for _, domain := range e.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return domain
+		}
+	}
+	return ""
+}
+
+// addName 添加证书中的名称，只保留属于根域名的主机
+func (e *Expander) addName(name string) {
+	for _, part := range strings.Split(name, "\n") {
+		host, ok := normalizeHost(part)
+		if !ok {
+			continue
+		}
+		domain := e.domainOf(host)
+		if domain == "" {
+			continue
+		}
+		if host != domain {
+			label, _, _ := strings.Cut(strings.TrimSuffix(host, "."+domain), ".")
+			e.ctLabels[label] = true
+		}
+		e.add(host, SourceCT)
+	}
+}
+
+// ctRecord 是证书透明度记录中需要的字段，兼容 crt.sh 的JSON和 certstream 的消息
+type ctRecord struct {
+	NameValue  string   `json:"name_value"`
+	CommonName string   `json:"common_name"`
+	DNSNames   []string `json:"dns_names"`
+	Data       *struct {
+		LeafCert struct {
+			AllDomains []string `json:"all_domains"`
+		} `json:"leaf_cert"`
+	} `json:"data"`
+}
+
+func (e *Expander) addRecord(record ctRecord) {
+	e.addName(record.NameValue)
+	e.addName(record.CommonName)
+	for _, name := range record.DNSNames {
+		e.addName(name)
+	}
+	if record.Data != nil {
+		for _, name := range record.Data.LeafCert.AllDomains {
+			e.addName(name)
+		}
+	}
+}
+
+// AddCT 读取证书透明度记录中属于根域名的主机名，支持以下格式：
+// crt.sh 导出的JSON数组、每行一条JSON记录的日志（crt.sh 或 certstream 格式）、每行一个名称的列表
+func (e *Expander) AddCT(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	first, err := peekNonSpace(reader)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取证书透明度记录失败: %v", err)
+	}
+
+	// crt.sh 导出的JSON数组
+	if first == '[' {
+		decoder := json.NewDecoder(reader)
+		if _, err := decoder.Token(); err != nil {
+			return fmt.Errorf("解析证书透明度记录失败: %v", err)
+		}
+		for decoder.More() {
+			var record ctRecord
+			if err := decoder.Decode(&record); err != nil {
+				return fmt.Errorf("解析证书透明度记录失败: %v", err)
+			}
+			e.addRecord(record)
+		}
+		return nil
+	}
+
+	return scanLines(reader, func(line string) (bool, error) {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			return true, nil
+		}
+		if line[0] != '{' {
+			e.addName(line)
+			return true, nil
+		}
+		var record ctRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return false, fmt.Errorf("无效的证书透明度记录: %v", err)
+		}
+		e.addRecord(record)
+		return true, nil
+	})
+}
+
+// peekNonSpace 跳过开头的空白，返回第一个非空白字符但不读取它
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b[0])) {
+			return b[0], nil
+		}
+		if _, err := reader.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// AddWordlist 读取子域名字典，每行一个子域名标签，# 之后为注释
+// 每个标签与每个根域名组合为候选主机
+func (e *Expander) AddWordlist(r io.Reader) error {
+	return scanLines(r, func(line string) (bool, error) {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		word := strings.Trim(strings.ToLower(strings.TrimSpace(line)), ".")
+		if word == "" {
+			return true, nil
+		}
+		if strings.ContainsAny(word, " \t/:*@") {
+			return false, fmt.Errorf("无效的子域名: %s", word)
+		}
+		e.words = append(e.words, word)
+		for _, domain := range e.domains {
+			e.add(word+"."+domain, SourceWordlist)
+		}
+		return true, nil
+	})
+}
+
+// permutations 把字典中的标签与证书透明度记录中子域名的第一级标签组合，如 api-dev、dev-api
+func (e *Expander) permutations() {
+	if !e.Permute || e.permuted || len(e.words) == 0 {
+		return
+	}
+	e.permuted = true
+	labels := make([]string, 0, len(e.ctLabels))
+	for label := range e.ctLabels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		for _, word := range e.words {
+			if word == label || strings.Contains(word, ".") {
+				continue
+			}
+			for _, domain := range e.domains {
+				e.add(label+"-"+word+"."+domain, SourcePermutation)
+				e.add(word+"-"+label+"."+domain, SourcePermutation)
+			}
+		}
+	}
+}
+
+// Candidates 返回候选主机数量，包括组合生成的子域名
+func (e *Expander) Candidates() int {
+	e.permutations()
+	return len(e.candidates)
+}
+
+// Resolve 并发解析候选主机，每个能解析的主机调用一次fn，fn返回false时停止
+// 根域名配置了泛解析时，解析结果与泛解析相同的主机会被丢弃；返回能解析的主机数量
+func (e *Expander) Resolve(ctx context.Context, fn func(Candidate) bool) (int, error) {
+	e.permutations()
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 检测泛解析，用随机子域名的解析结果过滤候选主机
+	wildcards := make(map[string]map[string]bool)
+	for _, domain := range e.domains {
+		if addrs := e.wildcard(ctx, domain); len(addrs) > 0 {
+			wildcards[domain] = addrs
+		}
+	}
+
+	threads := e.Threads
+	if threads <= 0 {
+		threads = 1
+	}
+
+	jobs := make(chan Candidate)
+	live := make(chan Candidate)
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for candidate := range jobs {
+				addrs, err := e.lookup(ctx, candidate.Host)
+				if err != nil || len(addrs) == 0 {
+					continue
+				}
+				if wildcard := wildcards[e.domainOf(candidate.Host)]; wildcard != nil &&
+					candidate.Source != SourceInput && subset(addrs, wildcard) {
+					continue
+				}
+				select {
+				case live <- candidate:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, candidate := range e.candidates {
+			select {
+			case jobs <- candidate:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(live)
+	}()
+
+	count := 0
+	for candidate := range live {
+		count++
+		if !fn(candidate) {
+			cancel()
+			break
+		}
+	}
+	// 等待解析协程退出
+	for range live {
+	}
+	return count, parent.Err()
+}
+
+// lookup 用系统解析器解析主机名
+func (e *Expander) lookup(ctx context.Context, host string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.Timeout)
+	defer cancel()
+	return e.resolver.LookupHost(ctx, host)
+}
+
+// wildcard 解析根域名下的随机子域名，能解析时返回泛解析的地址
+func (e *Expander) wildcard(ctx context.Context, domain string) map[string]bool {
+	// 公共后缀（如 com.cn）下不检测
+	if suffix, _ := publicsuffix.PublicSuffix(domain); suffix == domain {
+		return nil
+	}
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return nil
+	}
+	addrs, err := e.lookup(ctx, "snir-"+hex.EncodeToString(random)+"."+domain)
+	if err != nil || len(addrs) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		set[addr] = true
+	}
+	return set
+}
+
+// subset 检查addrs中的地址是否都在set中
+func subset(addrs []string, set map[string]bool) bool {
+	for _, addr := range addrs {
+		if !set[addr] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,321 @@
+package targets
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 支持导入的端口扫描结果格式
+const (
+	FormatNmap     = "nmap"     // nmap XML输出 (-oX)
+	FormatMasscan  = "masscan"  // masscan JSON (-oJ) 或列表 (-oL) 输出
+	FormatNaabu    = "naabu"    // naabu JSON (-json) 或 主机:端口 输出
+	FormatHostPort = "hostport" // 每行一个 主机:端口
+)
+
+// Service 表示端口扫描结果中的一个开放端口
+type Service struct {
+	Host   string // 主机名或IP地址
+	Port   int    // 端口
+	Name   string // 识别出的服务名称，如 http、https、ssl、x509(masscan)，未识别时为空
+	Tunnel string // 服务外层的隧道，nmap 识别出 TLS 时为 ssl
+	TLS    bool   // 端口使用TLS
+}
+
+// Parser 解析端口扫描结果，每个开放端口调用一次fn，fn返回false时停止解析
+type Parser func(r io.Reader, fn func(Service) bool) error
+
+var parsers = map[string]Parser{
+	FormatNmap:     ParseNmap,
+	FormatMasscan:  ParseMasscan,
+	FormatNaabu:    ParseHostPorts,
+	FormatHostPort: ParseHostPorts,
+}
+
+// Formats 返回支持导入的格式名称
+func Formats() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tlsPorts 是没有服务信息时默认使用HTTPS的端口
+var tlsPorts = map[int]bool{443: true, 4443: true, 6443: true, 8443: true, 8843: true, 9443: true, 10443: true}
+
+// Import 从r中读取端口扫描结果并转换为URL，每个URL调用一次fn，同一主机端口只生成一次
+// 识别为 http/https/ssl 的服务总会被导入；其他端口在ports不为空时只导入ports中的端口，
+// 为空时只导入没有服务信息的端口
+// 返回生成的URL数量
+func Import(r io.Reader, format string, ports []int, fn func(url string) bool) (int, error) {
+	parse, ok := parsers[format]
+	if !ok {
+		return 0, fmt.Errorf("不支持的导入格式: %s (可选 %s)", format, strings.Join(Formats(), "、"))
+	}
+
+	allowed := make(map[int]bool, len(ports))
+	for _, port := range ports {
+		allowed[port] = true
+	}
+
+	count := 0
+	seen := make(map[string]bool)
+	err := parse(r, func(s Service) bool {
+		url, ok := s.URL(allowed)
+		if !ok {
+			return true
+		}
+		key := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+		if seen[key] {
+			return true
+		}
+		seen[key] = true
+		count++
+		return fn(url)
+	})
+	return count, err
+}
+
+// URL 根据服务信息生成URL，ports 不为空时只导入其中的非Web服务端口
+// 返回false表示该端口不需要扫描
+func (s Service) URL(ports map[int]bool) (string, bool) {
+	if s.Host == "" || s.Port <= 0 || s.Port > 65535 {
+		return "", false
+	}
+
+	name := strings.ToLower(s.Name)
+	var scheme string
+	switch {
+	case s.TLS || strings.EqualFold(s.Tunnel, "ssl") ||
+		strings.Contains(name, "https") || strings.HasPrefix(name, "ssl") || name == "tls" || name == "x509":
+		scheme = "https"
+	case strings.Contains(name, "http"):
+		scheme = "http"
+	case len(ports) > 0 && !ports[s.Port]:
+		return "", false
+	case len(ports) == 0 && name != "":
+		// 识别出的其他服务（如 ssh）不扫描
+		return "", false
+	case tlsPorts[s.Port]:
+		scheme = "https"
+	default:
+		scheme = "http"
+	}
+
+	host := s.Host
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if (scheme == "http" && s.Port == 80) || (scheme == "https" && s.Port == 443) {
+		return scheme + "://" + host, true
+	}
+	return scheme + "://" + host + ":" + strconv.Itoa(s.Port), true
+}
+
+// nmapHost 对应nmap XML输出中的 host 元素
+type nmapHost struct {
+	Addresses []struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+	} `xml:"address"`
+	Hostnames []struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"hostnames>hostname"`
+	Ports []struct {
+		Protocol string `xml:"protocol,attr"`
+		PortID   int    `xml:"portid,attr"`
+		State    struct {
+			State string `xml:"state,attr"`
+		} `xml:"state"`
+		Service struct {
+			Name   string `xml:"name,attr"`
+			Tunnel string `xml:"tunnel,attr"`
+		} `xml:"service"`
+	} `xml:"ports>port"`
+}
+
+// ParseNmap 解析nmap XML输出 (-oX)，逐个读取 host 元素
+// 优先使用扫描时指定的主机名（type="user"），以便访问基于名称的虚拟主机
+func ParseNmap(r io.Reader, fn func(Service) bool) error {
+	decoder := xml.NewDecoder(r)
+	// nmap 的XML带有DOCTYPE声明，不需要严格校验
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("解析nmap XML失败: %v", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "host" {
+			continue
+		}
+
+		var host nmapHost
+		if err := decoder.DecodeElement(&host, &start); err != nil {
+			return fmt.Errorf("解析nmap XML失败: %v", err)
+		}
+
+		name := ""
+		for _, hostname := range host.Hostnames {
+			if hostname.Type == "user" {
+				name = hostname.Name
+				break
+			}
+		}
+		if name == "" {
+			for _, addr := range host.Addresses {
+				if addr.AddrType == "ipv4" || addr.AddrType == "ipv6" {
+					name = addr.Addr
+					break
+				}
+			}
+		}
+
+		for _, port := range host.Ports {
+			if port.Protocol != "tcp" || port.State.State != "open" {
+				continue
+			}
+			if !fn(Service{Host: name, Port: port.PortID, Name: port.Service.Name, Tunnel: port.Service.Tunnel}) {
+				return nil
+			}
+		}
+	}
+}
+
+// masscanRecord 对应masscan JSON输出中的一条记录
+type masscanRecord struct {
+	IP    string `json:"ip"`
+	Ports []struct {
+		Port    int    `json:"port"`
+		Proto   string `json:"proto"`
+		Status  string `json:"status"`
+		Service struct {
+			Name string `json:"name"`
+		} `json:"service"`
+	} `json:"ports"`
+}
+
+// ParseMasscan 解析masscan的JSON (-oJ) 或列表 (-oL) 输出
+// masscan 的JSON是每行一条记录的数组，末尾可能带有逗号，因此逐行解析
+func ParseMasscan(r io.Reader, fn func(Service) bool) error {
+	return scanLines(r, func(line string) (bool, error) {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line == "[" || line == "]" {
+			return true, nil
+		}
+
+		// JSON记录
+		if line[0] == '{' {
+			var record masscanRecord
+			if err := json.Unmarshal([]byte(strings.TrimSuffix(line, ",")), &record); err != nil {
+				return false, fmt.Errorf("无效的masscan JSON: %v", err)
+			}
+			for _, port := range record.Ports {
+				if port.Proto != "" && port.Proto != "tcp" {
+					continue
+				}
+				if port.Status != "" && port.Status != "open" {
+					continue
+				}
+				if !fn(Service{Host: record.IP, Port: port.Port, Name: port.Service.Name}) {
+					return false, nil
+				}
+			}
+			return true, nil
+		}
+
+		// 列表格式: open tcp 80 1.2.3.4 1390000000
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			return false, fmt.Errorf("无效的masscan记录: %s", line)
+		}
+		if fields[0] != "open" || fields[1] != "tcp" {
+			return true, nil
+		}
+		port, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return false, fmt.Errorf("无效的端口: %s", fields[2])
+		}
+		return fn(Service{Host: fields[3], Port: port}), nil
+	})
+}
+
+// naabuRecord 对应naabu JSON输出中的一条记录
+type naabuRecord struct {
+	Host     string `json:"host"`
+	IP       string `json:"ip"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	TLS      bool   `json:"tls"`
+}
+
+// ParseHostPorts 解析每行一个 主机:端口 的列表，naabu 的默认输出就是这种格式
+// 以 { 开头的行按naabu的JSON输出 (-json) 解析，# 之后为注释
+func ParseHostPorts(r io.Reader, fn func(Service) bool) error {
+	return scanLines(r, func(line string) (bool, error) {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return true, nil
+		}
+
+		if line[0] == '{' {
+			var record naabuRecord
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				return false, fmt.Errorf("无效的naabu JSON: %v", err)
+			}
+			if record.Protocol != "" && record.Protocol != "tcp" {
+				return true, nil
+			}
+			host := record.Host
+			if host == "" {
+				host = record.IP
+			}
+			return fn(Service{Host: host, Port: record.Port, TLS: record.TLS}), nil
+		}
+
+		host, port, err := net.SplitHostPort(line)
+		if err != nil {
+			return false, fmt.Errorf("无效的 主机:端口: %s", line)
+		}
+		portNum, err := strconv.Atoi(port)
+		if err != nil {
+			return false, fmt.Errorf("无效的端口: %s", port)
+		}
+		return fn(Service{Host: host, Port: portNum}), nil
+	})
+}
+
+// scanLines 逐行调用fn，fn返回false时停止，错误信息中包含行号
+func scanLines(r io.Reader, fn func(line string) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		more, err := fn(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("第%d行: %v", line, err)
+		}
+		if !more {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取目标失败: %v", err)
+	}
+	return nil
+}
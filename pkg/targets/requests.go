@@ -0,0 +1,388 @@
+package targets
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 支持导入的代理记录格式
+const (
+	FormatBurp = "burp" // Burp Suite 导出的XML (Save items / Site map)
+	FormatZAP  = "zap"  // ZAP 导出的消息 (Export Messages to File)
+	FormatHAR  = "har"  // HAR 文件，浏览器开发者工具、Burp 和 ZAP 都可以导出
+)
+
+// Header 表示请求头或Cookie
+type Header struct {
+	Name  string
+	Value string
+}
+
+// Request 表示代理记录中的一个请求
+type Request struct {
+	Method  string
+	URL     string
+	Headers []Header // 请求头，不包含Cookie和由浏览器决定的请求头
+	Cookies []Header // 请求中的Cookie
+}
+
+// RequestParser 解析代理记录，每个请求调用一次fn，fn返回false时停止解析
+type RequestParser func(r io.Reader, fn func(Request) bool) error
+
+var requestParsers = map[string]RequestParser{
+	FormatBurp: ParseBurp,
+	FormatZAP:  ParseZAP,
+	FormatHAR:  ParseHAR,
+}
+
+// RequestFormats 返回支持导入的代理记录格式名称
+func RequestFormats() []string {
+	names := make([]string, 0, len(requestParsers))
+	for name := range requestParsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// skipHeaders 是导入时丢弃的请求头，它们由浏览器根据连接决定，或者会导致缓存的304响应
+var skipHeaders = map[string]bool{
+	"host": true, "content-length": true, "content-type": true, "connection": true, "keep-alive": true,
+	"transfer-encoding": true, "upgrade": true, "te": true, "trailer": true, "proxy-connection": true,
+	"proxy-authorization": true, "accept-encoding": true, "if-none-match": true,
+	"if-modified-since": true, "cookie": true,
+}
+
+// ImportRequests 从r中读取代理记录，按规范化的URL去重后每个请求调用一次fn
+// 截图总是以GET方式加载页面，同一URL优先使用GET请求的请求头和Cookie：
+// GET请求读取后立即导入，其他方法的请求在读取完成后导入，已有同一URL的GET请求时跳过
+// getOnly 为true时只导入GET请求；返回导入的请求数量
+func ImportRequests(r io.Reader, format string, getOnly bool, fn func(Request) bool) (int, error) {
+	parse, ok := requestParsers[format]
+	if !ok {
+		return 0, fmt.Errorf("不支持的导入格式: %s (可选 %s)", format, strings.Join(RequestFormats(), "、"))
+	}
+
+	count := 0
+	seen := make(map[string]bool)
+	var pending []Request // 等待读取完成的其他方法的请求，每个URL一个
+	pendingURLs := make(map[string]bool)
+	stopped := false
+	err := parse(r, func(req Request) bool {
+		canonical, err := Canonical(req.URL)
+		if err != nil || seen[canonical] {
+			return true
+		}
+		req.URL = canonical
+		if !strings.EqualFold(req.Method, "GET") {
+			if !getOnly && !pendingURLs[canonical] {
+				pendingURLs[canonical] = true
+				pending = append(pending, req)
+			}
+			return true
+		}
+		seen[canonical] = true
+		count++
+		stopped = !fn(req)
+		return !stopped
+	})
+	if err != nil || stopped {
+		return count, err
+	}
+
+	for _, req := range pending {
+		if seen[req.URL] {
+			continue
+		}
+		count++
+		if !fn(req) {
+			break
+		}
+	}
+	return count, nil
+}
+
+// Canonical 返回规范化的URL：协议和主机名小写，去掉默认端口和片段，
+// 路径为空时使用 /，查询参数按名称排序
+func Canonical(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("不支持的协议: %s", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("缺少主机名: %s", rawURL)
+	}
+
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = sortQuery(u.RawQuery)
+	}
+	return u.String(), nil
+}
+
+// sortQuery 按名称排序查询参数，保持参数值的原始编码
+func sortQuery(query string) string {
+	params := strings.Split(query, "&")
+	sort.SliceStable(params, func(i, j int) bool {
+		a, _, _ := strings.Cut(params[i], "=")
+		b, _, _ := strings.Cut(params[j], "=")
+		return a < b
+	})
+	return strings.Join(params, "&")
+}
+
+// newRequest 根据请求头创建请求，Cookie请求头拆分为Cookie
+func newRequest(method, rawURL string, headers []Header) Request {
+	req := Request{Method: strings.ToUpper(method), URL: rawURL}
+	for _, header := range headers {
+		name := strings.TrimSpace(header.Name)
+		key := strings.ToLower(name)
+		switch {
+		case key == "cookie":
+			req.Cookies = append(req.Cookies, parseCookies(header.Value)...)
+		case name == "" || strings.HasPrefix(name, ":") || skipHeaders[key]:
+			// HTTP/2 伪头部和由浏览器决定的请求头
+		default:
+			req.Headers = append(req.Headers, Header{Name: name, Value: strings.TrimSpace(header.Value)})
+		}
+	}
+	return req
+}
+
+// parseCookies 解析Cookie请求头中的 名称=值 列表
+func parseCookies(value string) []Header {
+	var cookies []Header
+	for _, part := range strings.Split(value, ";") {
+		name, cookieValue, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			continue
+		}
+		cookies = append(cookies, Header{Name: strings.TrimSpace(name), Value: strings.TrimSpace(cookieValue)})
+	}
+	return cookies
+}
+
+// parseRawRequest 解析原始HTTP请求的请求行和请求头，请求行中的路径与base拼接为完整URL
+func parseRawRequest(raw string, base string) (Request, error) {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	head, _, _ := strings.Cut(raw, "\n\n")
+	lines := strings.Split(head, "\n")
+
+	fields := strings.Fields(lines[0])
+	if len(fields) < 2 {
+		return Request{}, fmt.Errorf("无效的请求行: %s", lines[0])
+	}
+
+	var headers []Header
+	host := ""
+	for _, line := range lines[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		headers = append(headers, Header{Name: name, Value: value})
+		if strings.EqualFold(strings.TrimSpace(name), "host") {
+			host = strings.TrimSpace(value)
+		}
+	}
+
+	// 请求行中通常是路径，通过代理发送的请求中是完整URL
+	target := fields[1]
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		if base == "" {
+			if host == "" {
+				return Request{}, fmt.Errorf("无法确定请求的URL: %s", lines[0])
+			}
+			base = "https://" + host
+		}
+		baseURL, err := url.Parse(base)
+		if err != nil {
+			return Request{}, err
+		}
+		ref, err := url.Parse(target)
+		if err != nil {
+			return Request{}, err
+		}
+		target = baseURL.ResolveReference(ref).String()
+	}
+	return newRequest(fields[0], target, headers), nil
+}
+
+// burpItem 对应Burp导出的XML中的 item 元素
+type burpItem struct {
+	URL      string `xml:"url"`
+	Host     string `xml:"host"`
+	Port     string `xml:"port"`
+	Protocol string `xml:"protocol"`
+	Method   string `xml:"method"`
+	Request  struct {
+		Base64 bool   `xml:"base64,attr"`
+		Value  string `xml:",chardata"`
+	} `xml:"request"`
+}
+
+// ParseBurp 解析Burp Suite导出的XML，逐个读取 item 元素
+// 请求内容可能经过base64编码，没有请求内容时只使用 url 和 method
+func ParseBurp(r io.Reader, fn func(Request) bool) error {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("解析Burp XML失败: %v", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "item" {
+			continue
+		}
+
+		var item burpItem
+		if err := decoder.DecodeElement(&item, &start); err != nil {
+			return fmt.Errorf("解析Burp XML失败: %v", err)
+		}
+
+		base := strings.TrimSpace(item.URL)
+		if base == "" && item.Host != "" {
+			base = strings.TrimSpace(item.Protocol) + "://" + strings.TrimSpace(item.Host)
+			if port := strings.TrimSpace(item.Port); port != "" {
+				base += ":" + port
+			}
+		}
+		req := newRequest(item.Method, base, nil)
+		raw := item.Request.Value
+		if item.Request.Base64 {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(raw))
+			if err != nil {
+				return fmt.Errorf("解析Burp请求失败: %s: %v", item.URL, err)
+			}
+			raw = string(decoded)
+		}
+		if strings.TrimSpace(raw) != "" {
+			parsed, err := parseRawRequest(raw, base)
+			if err != nil {
+				return fmt.Errorf("解析Burp请求失败: %s: %v", item.URL, err)
+			}
+			// item 中的URL是完整的，没有时使用请求行中的路径补全
+			if item.URL != "" {
+				parsed.URL = base
+			}
+			req = parsed
+		}
+		if !fn(req) {
+			return nil
+		}
+	}
+}
+
+// zapMessage 匹配ZAP导出的消息文件中每条消息的分隔行，如 ==== 12 ==========
+var zapMessage = regexp.MustCompile(`^==== \d+ =+$`)
+
+// ParseZAP 解析ZAP导出的消息文件，每条消息以分隔行开始，之后是请求头、请求体和响应
+// ZAP导出的请求行中是完整URL
+func ParseZAP(r io.Reader, fn func(Request) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	var head []string
+	inHead := false
+	emit := func() (bool, error) {
+		if len(head) == 0 {
+			return true, nil
+		}
+		req, err := parseRawRequest(strings.Join(head, "\n"), "")
+		head = nil
+		if err != nil {
+			return false, err
+		}
+		return fn(req), nil
+	}
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case zapMessage.MatchString(text):
+			inHead = true
+		case inHead && text == "" && len(head) > 0:
+			// 请求头结束，忽略请求体和响应，直到下一条消息
+			inHead = false
+			more, err := emit()
+			if err != nil {
+				return fmt.Errorf("第%d行: %v", line, err)
+			}
+			if !more {
+				return nil
+			}
+		case inHead && text != "":
+			head = append(head, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取目标失败: %v", err)
+	}
+	_, err := emit()
+	return err
+}
+
+// harFile 是HAR文件中导入时需要的部分
+type harFile struct {
+	Log *struct {
+		Entries []struct {
+			Request struct {
+				Method  string   `json:"method"`
+				URL     string   `json:"url"`
+				Headers []Header `json:"headers"`
+				Cookies []Header `json:"cookies"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// ParseHAR 解析HAR文件中的请求，只读取请求的方法、URL、请求头和Cookie
+func ParseHAR(r io.Reader, fn func(Request) bool) error {
+	var file harFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return fmt.Errorf("解析HAR文件失败: %v", err)
+	}
+	if file.Log == nil {
+		return fmt.Errorf("无效的HAR文件: 缺少log字段")
+	}
+
+	for _, entry := range file.Log.Entries {
+		req := newRequest(entry.Request.Method, entry.Request.URL, entry.Request.Headers)
+		// HAR中的Cookie同时出现在cookies字段和Cookie请求头中，优先使用cookies字段
+		if len(entry.Request.Cookies) > 0 {
+			req.Cookies = entry.Request.Cookies
+		}
+		if !fn(req) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package targets
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// harEntries 生成只包含请求方法、URL和一个请求头的HAR文件
+func harEntries(requests ...[3]string) string {
+	var entries []string
+	for _, r := range requests {
+		entries = append(entries, fmt.Sprintf(
+			`{"request": {"method": %q, "url": %q, "headers": [{"name": "X-Request", "value": %q}]}}`, r[0], r[1], r[2]))
+	}
+	return `{"log": {"entries": [` + strings.Join(entries, ",") + `]}}`
+}
+
+func TestImportRequestsPrefersGet(t *testing.T) {
+	input := harEntries(
+		[3]string{"POST", "https://example.com/login", "post-login"},
+		[3]string{"GET", "https://EXAMPLE.com:443/login#form", "get-login"},
+		[3]string{"POST", "https://example.com/api?b=2&a=1", "post-api"},
+		[3]string{"PUT", "https://example.com/api?a=1&b=2", "put-api"},
+		[3]string{"GET", "https://example.com/", "get-home"},
+		[3]string{"GET", "https://example.com", "get-home-again"},
+	)
+
+	tests := []struct {
+		getOnly bool
+		want    []string
+	}{
+		// 同一URL优先使用GET请求，只有其他方法的URL使用第一个请求
+		{false, []string{
+			"GET https://example.com/login get-login",
+			"GET https://example.com/ get-home",
+			"POST https://example.com/api?a=1&b=2 post-api",
+		}},
+		{true, []string{
+			"GET https://example.com/login get-login",
+			"GET https://example.com/ get-home",
+		}},
+	}
+	for _, tt := range tests {
+		var got []string
+		count, err := ImportRequests(strings.NewReader(input), FormatHAR, tt.getOnly, func(req Request) bool {
+			got = append(got, fmt.Sprintf("%s %s %s", req.Method, req.URL, req.Headers[0].Value))
+			return true
+		})
+		if err != nil || count != len(tt.want) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("getOnly=%v 导入 %q (%d个, %v)，应为 %q", tt.getOnly, got, count, err, tt.want)
+		}
+	}
+}
+
+func TestImportRequestsStop(t *testing.T) {
+	input := harEntries(
+		[3]string{"POST", "https://example.com/submit", "post-submit"},
+		[3]string{"GET", "https://example.com/", "get-home"},
+		[3]string{"GET", "https://example.com/about", "get-about"},
+	)
+
+	// fn 返回false时停止导入，尚未导入的其他方法的请求不再导入
+	var got []string
+	count, err := ImportRequests(strings.NewReader(input), FormatHAR, false, func(req Request) bool {
+		got = append(got, req.URL)
+		return len(got) < 1
+	})
+	if err != nil || count != 1 || !reflect.DeepEqual(got, []string{"https://example.com/"}) {
+		t.Errorf("停止后导入 %q (%d个, %v)，应只导入第一个GET请求", got, count, err)
+	}
+}
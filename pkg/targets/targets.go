@@ -0,0 +1,124 @@
+// Package targets 逐行读取扫描目标，按需展开CIDR网段，不会把全部目标读入内存
+package targets
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// maxLineSize 是一行目标的最大长度
+const maxLineSize = 1024 * 1024
+
+// Split 拆分一行中用空白分隔的多个目标，行首或空白之后的 # 开始注释
+// URL中可能包含逗号、分号和 #，如 https://example.com/#/login，因此只按空白拆分
+func Split(line string) []string {
+	fields := strings.Fields(line)
+	for i, field := range fields {
+		if strings.HasPrefix(field, "#") {
+			return fields[:i]
+		}
+	}
+	return fields
+}
+
+// Expand 展开一个目标，CIDR网段依次生成其中的每个IP地址，主机名和URL原样返回
+// fn 返回false时停止展开
+func Expand(entry string, fn func(target string) bool) (bool, error) {
+	if !isCIDR(entry) {
+		return fn(entry), nil
+	}
+	return ExpandCIDR(entry, fn)
+}
+
+// isCIDR 检查目标是否为CIDR网段，URL的路径中也可能有斜杠，因此只检查 地址/前缀长度 的形式
+func isCIDR(entry string) bool {
+	addr, prefix, ok := strings.Cut(entry, "/")
+	if !ok || prefix == "" || strings.Contains(prefix, "/") {
+		return false
+	}
+	return net.ParseIP(addr) != nil
+}
+
+// ExpandCIDR 依次生成网段中的IP地址，IPv4网段跳过网络地址和广播地址（/31和/32除外）
+// fn 返回false时停止，返回值表示是否已生成全部地址
+func ExpandCIDR(cidr string, fn func(ip string) bool) (bool, error) {
+	ip, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false, fmt.Errorf("无效的CIDR格式: %v", err)
+	}
+
+	start := ip.Mask(ipnet.Mask)
+	if v4 := start.To4(); v4 != nil {
+		start = v4
+	}
+	ones, bits := ipnet.Mask.Size()
+	skipEdges := bits == 32 && bits-ones > 1
+
+	current := make(net.IP, len(start))
+	copy(current, start)
+	if skipEdges {
+		increment(current)
+	}
+	for ipnet.Contains(current) {
+		next := make(net.IP, len(current))
+		copy(next, current)
+		if !increment(next) {
+			// 已到地址空间末尾，current 是最后一个地址
+			if skipEdges {
+				return true, nil
+			}
+			return fn(current.String()), nil
+		}
+		if skipEdges && !ipnet.Contains(next) {
+			// current 是广播地址
+			break
+		}
+		if !fn(current.String()) {
+			return false, nil
+		}
+		current = next
+	}
+	return true, nil
+}
+
+// increment 把IP地址加1，溢出时返回false
+func increment(ip net.IP) bool {
+	for i := len(ip) - 1; i >= 0; i-- {
+		ip[i]++
+		if ip[i] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Read 从r中逐行读取目标并展开，每个目标调用一次fn，fn返回false时停止读取
+// 返回已生成的目标数量
+func Read(r io.Reader, fn func(target string) bool) (int, error) {
+	count := 0
+	emit := func(target string) bool {
+		count++
+		return fn(target)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		for _, entry := range Split(scanner.Text()) {
+			more, err := Expand(entry, emit)
+			if err != nil {
+				return count, fmt.Errorf("第%d行: %v", line, err)
+			}
+			if !more {
+				return count, nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("读取目标失败: %v", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,116 @@
+package targets
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"   \t", nil},
+		{"# 注释", nil},
+		{"example.com", []string{"example.com"}},
+		{"  https://example.com\thttp://intranet.local:8080 \r", []string{"https://example.com", "http://intranet.local:8080"}},
+		{"10.0.0.0/30 2001:db8::1   # 注释", []string{"10.0.0.0/30", "2001:db8::1"}},
+		{"example.com #注释 other.com", []string{"example.com"}},
+		{"https://app.example.com/#/login", []string{"https://app.example.com/#/login"}},
+		{"https://example.com/app;jsessionid=X", []string{"https://example.com/app;jsessionid=X"}},
+		{"https://example.com/?ids=1,2", []string{"https://example.com/?ids=1,2"}},
+		{"https://example.com/#top # 注释", []string{"https://example.com/#top"}},
+	}
+	for _, tt := range tests {
+		got := Split(tt.line)
+		if len(got) != len(tt.want) || len(got) > 0 && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Split(%q) = %q，应为 %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+// collect 展开目标并返回生成的全部目标，limit 大于0时最多生成limit个
+func collect(t *testing.T, expand func(string, func(string) bool) (bool, error), entry string, limit int) ([]string, bool) {
+	t.Helper()
+	var got []string
+	done, err := expand(entry, func(target string) bool {
+		got = append(got, target)
+		return limit <= 0 || len(got) < limit
+	})
+	if err != nil {
+		t.Fatalf("展开 %s 失败: %v", entry, err)
+	}
+	return got, done
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  []string
+	}{
+		{"example.com", []string{"example.com"}},
+		{"https://example.com/a/b", []string{"https://example.com/a/b"}},
+		{"https://10.0.0.1/24", []string{"https://10.0.0.1/24"}},
+		{"10.0.0.1/api/v1", []string{"10.0.0.1/api/v1"}},
+		{"10.0.0.1", []string{"10.0.0.1"}},
+		{"10.0.0.0/30", []string{"10.0.0.1", "10.0.0.2"}},
+		{"2001:db8::/127", []string{"2001:db8::", "2001:db8::1"}},
+	}
+	for _, tt := range tests {
+		got, done := collect(t, Expand, tt.entry, 0)
+		if !done || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Expand(%q) = %q (完成: %v)，应为 %q", tt.entry, got, done, tt.want)
+		}
+	}
+
+	if _, err := Expand("10.0.0.0/33", func(string) bool { return true }); err == nil {
+		t.Error("Expand(10.0.0.0/33) 应返回错误")
+	}
+}
+
+func TestExpandCIDR(t *testing.T) {
+	tests := []struct {
+		cidr  string
+		limit int
+		want  []string
+		done  bool
+	}{
+		{"192.168.1.5/32", 0, []string{"192.168.1.5"}, true},
+		{"192.168.1.4/31", 0, []string{"192.168.1.4", "192.168.1.5"}, true},
+		{"192.168.1.5/30", 0, []string{"192.168.1.5", "192.168.1.6"}, true},
+		{"10.0.0.0/29", 0, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"}, true},
+		{"10.0.0.0/8", 3, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, false},
+		{"255.255.255.252/30", 0, []string{"255.255.255.253", "255.255.255.254"}, true},
+		{"255.255.255.254/31", 0, []string{"255.255.255.254", "255.255.255.255"}, true},
+		{"2001:db8::/126", 0, []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"}, true},
+		{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe/127", 0,
+			[]string{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"}, true},
+	}
+	for _, tt := range tests {
+		got, done := collect(t, ExpandCIDR, tt.cidr, tt.limit)
+		if done != tt.done || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExpandCIDR(%q) = %q (完成: %v)，应为 %q (完成: %v)", tt.cidr, got, done, tt.want, tt.done)
+		}
+	}
+
+	for _, cidr := range []string{"10.0.0.0", "10.0.0.0/33", "example.com/24"} {
+		if _, err := ExpandCIDR(cidr, func(string) bool { return true }); err == nil {
+			t.Errorf("ExpandCIDR(%q) 应返回错误", cidr)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	input := "# 目标列表\nhttps://app.example.com/#/login https://example.com/?ids=1,2\n\n10.0.0.0/30   # 内网\n"
+	var got []string
+	count, err := Read(strings.NewReader(input), func(target string) bool {
+		got = append(got, target)
+		return true
+	})
+	want := []string{"https://app.example.com/#/login", "https://example.com/?ids=1,2", "10.0.0.1", "10.0.0.2"}
+	if err != nil || count != len(want) || !reflect.DeepEqual(got, want) {
+		t.Errorf("Read 生成 %q (%d个, %v)，应为 %q", got, count, err, want)
+	}
+}